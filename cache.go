@@ -0,0 +1,210 @@
+package meteomatics
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRelativeTimeCacheTTL is the default maximum time for which responses
+// to requests with relative times are cached.
+const DefaultRelativeTimeCacheTTL = time.Minute
+
+// relativeTimeRx matches a lowercase time that starts with a relative time
+// token, optionally followed by an offset, a time of day, a period, or a step.
+var relativeTimeRx = regexp.MustCompile(`^(now|today|tomorrow|yesterday)([+\-t:p]|$)`)
+
+// A Cache caches responses. Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value for key, if it is present and has not expired.
+	Get(key string) ([]byte, bool)
+	// Set sets the value for key, expiring after ttl.
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// A MemoryCache is an in-memory least-recently-used Cache.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	lru        *list.List
+	elements   map[string]*list.Element
+	now        func() time.Time
+}
+
+type memoryCacheEntry struct {
+	key    string
+	value  []byte
+	expiry time.Time
+}
+
+// A FileCache is a Cache that stores responses in files in a directory.
+type FileCache struct {
+	dir string
+	now func() time.Time
+}
+
+// WithCache sets the cache used for responses, and the time for which
+// responses are cached. Responses are keyed by request URL, excluding
+// credentials. Requests with RequestOptions.BypassCache set are always sent to
+// the API, and their responses update the cache.
+func WithCache(cache Cache, ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.cache = cache
+		c.cacheTTL = ttl
+	}
+}
+
+// WithRelativeTimeCacheTTL sets the maximum time for which responses to
+// requests with times relative to now, for example TimeNow and NowOffset, are
+// cached. If ttl is zero then such responses are not cached.
+func WithRelativeTimeCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.relativeTTL = ttl
+	}
+}
+
+// NewMemoryCache returns a new MemoryCache containing at most maxEntries
+// entries. If maxEntries is zero then the number of entries is not limited.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		elements:   make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+// Get implements Cache.Get.
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.elements[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*memoryCacheEntry)
+	if !c.now().Before(entry.expiry) {
+		c.lru.Remove(element)
+		delete(c.elements, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	return append([]byte(nil), entry.value...), true
+}
+
+// Len returns the number of entries in c, including expired entries that
+// have not yet been evicted.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Set implements Cache.Set.
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &memoryCacheEntry{
+		key:    key,
+		value:  append([]byte(nil), value...),
+		expiry: c.now().Add(ttl),
+	}
+	if element, ok := c.elements[key]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.elements[key] = c.lru.PushFront(entry)
+	if c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.elements, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// NewFileCache returns a new FileCache that stores responses in dir. If dir
+// does not exist, it is created when the first response is stored.
+func NewFileCache(dir string) *FileCache {
+	return &FileCache{
+		dir: dir,
+		now: time.Now,
+	}
+}
+
+// Get implements Cache.Get. Errors reading the cache are treated as cache
+// misses.
+func (c *FileCache) Get(key string) ([]byte, bool) {
+	filename := c.filename(key)
+	data, err := ioutil.ReadFile(filename)
+	if err != nil || len(data) < 8 {
+		return nil, false
+	}
+	expiry := time.Unix(0, int64(binary.BigEndian.Uint64(data[:8])))
+	if !c.now().Before(expiry) {
+		_ = os.Remove(filename)
+		return nil, false
+	}
+	return data[8:], true
+}
+
+// Set implements Cache.Set. Errors writing the cache are ignored.
+func (c *FileCache) Set(key string, value []byte, ttl time.Duration) {
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return
+	}
+	f, err := ioutil.TempFile(c.dir, ".tmp-")
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+	var header [8]byte
+	binary.BigEndian.PutUint64(header[:], uint64(c.now().Add(ttl).UnixNano()))
+	_, err = f.Write(header[:])
+	if err == nil {
+		_, err = f.Write(value)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	_ = os.Rename(f.Name(), c.filename(key))
+}
+
+// filename returns the filename for key.
+func (c *FileCache) filename(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// cacheTTLFor returns the time for which the response to a request at ts should
+// be cached.
+func (c *Client) cacheTTLFor(ts TimeString) time.Duration {
+	ttl := c.cacheTTL
+	if isRelativeTime(ts) && ttl > c.relativeTTL {
+		ttl = c.relativeTTL
+	}
+	return ttl
+}
+
+// isRelativeTime returns whether any of the times in ts, which are separated by
+// commas and double dashes, is relative to now.
+func isRelativeTime(ts TimeString) bool {
+	for _, s := range strings.Split(strings.ToLower(string(ts)), ",") {
+		for _, t := range strings.Split(s, "--") {
+			if relativeTimeRx.MatchString(t) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package meteomatics
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache(t *testing.T) {
+	now := time.Date(2019, 6, 10, 0, 0, 0, 0, time.UTC)
+	c := NewMemoryCache(2)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", []byte("1"), time.Minute)
+	c.Set("b", []byte("2"), time.Hour)
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	c.Set("c", []byte("3"), time.Hour)
+	assert.Equal(t, 2, c.Len())
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	value, ok = c.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("3"), value)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestFileCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-meteomatics-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2019, 6, 10, 0, 0, 0, 0, time.UTC)
+	c := NewFileCache(dir)
+	c.now = func() time.Time { return now }
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+
+	c.Set("a", []byte("1"), time.Minute)
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	c2 := NewFileCache(dir)
+	c2.now = c.now
+	value, ok = c2.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, infos, 0)
+}
+
+func TestClientRequestCache(t *testing.T) {
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		_, _ = w.Write([]byte(strconv.Itoa(int(n))))
+	}))
+	defer s.Close()
+
+	for _, tc := range []struct {
+		name             string
+		ts               TimeStringer
+		clientOptions    []ClientOption
+		requestOptions   []*RequestOptions
+		expectedData     []string
+		expectedRequests int32
+	}{
+		{
+			name:             "absolute_time",
+			ts:               Time(time.Date(2019, 6, 10, 0, 0, 0, 0, time.UTC)),
+			requestOptions:   []*RequestOptions{nil, nil, {}},
+			expectedData:     []string{"1", "1", "1"},
+			expectedRequests: 1,
+		},
+		{
+			name:             "bypass_cache",
+			ts:               Time(time.Date(2019, 6, 10, 0, 0, 0, 0, time.UTC)),
+			requestOptions:   []*RequestOptions{nil, {BypassCache: true}, nil},
+			expectedData:     []string{"1", "2", "2"},
+			expectedRequests: 2,
+		},
+		{
+			name:             "relative_time",
+			ts:               NowOffset(time.Hour),
+			requestOptions:   []*RequestOptions{nil, nil},
+			expectedData:     []string{"1", "1"},
+			expectedRequests: 1,
+		},
+		{
+			name: "relative_time_uncached",
+			ts: TimeSlice{
+				Time(time.Date(2019, 6, 10, 0, 0, 0, 0, time.UTC)),
+				TimeNow,
+			},
+			clientOptions: []ClientOption{
+				WithRelativeTimeCacheTTL(0),
+			},
+			requestOptions:   []*RequestOptions{nil, nil},
+			expectedData:     []string{"1", "2"},
+			expectedRequests: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+			c := NewClient(append([]ClientOption{
+				WithBaseURL(s.URL),
+				WithCache(NewMemoryCache(0), time.Hour),
+			}, tc.clientOptions...)...)
+			for i, options := range tc.requestOptions {
+				data, err := c.Request(context.Background(), tc.ts, ParameterString("t_2m:C"), Point{}, FormatCSV, options)
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedData[i], string(data))
+			}
+			assert.Equal(t, tc.expectedRequests, atomic.LoadInt32(&requests))
+		})
+	}
+}
+
+func TestIsRelativeTime(t *testing.T) {
+	assert.True(t, isRelativeTime(TimeNow))
+	assert.True(t, isRelativeTime(NowOffset(-time.Hour).TimeString()))
+	assert.True(t, isRelativeTime(TimeTomorrow))
+	assert.True(t, isRelativeTime(TimeSlice{TimeYesterday, TimeNow}.TimeString()))
+	assert.False(t, isRelativeTime(Time(time.Date(2019, 6, 10, 0, 0, 0, 0, time.UTC)).TimeString()))
+	assert.True(t, isRelativeTime("2019-06-10T00:00:00Z--now+1H:PT1H"))
+	assert.True(t, isRelativeTime("todayT12:00:00Z"))
+	assert.False(t, isRelativeTime("snowfall"))
+	assert.False(t, isRelativeTime("2019-06-10T00:00:00Z,knownow"))
+}
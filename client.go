@@ -5,10 +5,12 @@ package meteomatics
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 )
 
 // DefaultBaseURL is the default base URL.
@@ -16,22 +18,36 @@ const DefaultBaseURL = "https://api.meteomatics.com"
 
 // A Client is a Client.
 type Client struct {
-	httpClient      *http.Client
-	baseURL         string
-	preRequestFuncs []func(*http.Request)
+	httpClient        *http.Client
+	baseURL           string
+	tokenURL          string
+	preRequestFuncs   []func(*http.Request) error
+	unauthorizedFuncs []func(*http.Request)
+	retryPolicy       *RetryPolicy
+	rateLimiter       *rateLimiter
+	semaphore         chan struct{}
+	cache             Cache
+	cacheTTL          time.Duration
+	relativeTTL       time.Duration
+	missingValues     missingValues
 }
 
 // A ClientOption sets an option on a Client.
 type ClientOption func(*Client)
 
-// RequestOptions are per-request options.
+// RequestOptions are per-request options. Source selects the model or
+// observation source, for example ModelECMWFIFS or ModelMixObs, and is sent as
+// the source query parameter.
 type RequestOptions struct {
-	Source                string
+	Source                Model
 	TemporalInterpolation string
 	EnsembleSelect        string
 	ClusterSelect         string
 	Timeout               int
 	Route                 bool
+	FillWithInvalid       bool
+	InitDate              time.Time
+	BypassCache           bool
 }
 
 // WithBaseURL sets the base URL.
@@ -51,8 +67,9 @@ func WithHTTPClient(httpClient *http.Client) ClientOption {
 // WithBasicAuth sets the username and password for basic authentication.
 func WithBasicAuth(username, password string) ClientOption {
 	return func(c *Client) {
-		c.preRequestFuncs = append(c.preRequestFuncs, func(req *http.Request) {
+		c.preRequestFuncs = append(c.preRequestFuncs, func(req *http.Request) error {
 			req.SetBasicAuth(username, password)
+			return nil
 		})
 	}
 }
@@ -60,8 +77,10 @@ func WithBasicAuth(username, password string) ClientOption {
 // NewClient returns a new Client with options set.
 func NewClient(options ...ClientOption) *Client {
 	c := &Client{
-		httpClient: http.DefaultClient,
-		baseURL:    DefaultBaseURL,
+		httpClient:  http.DefaultClient,
+		baseURL:     DefaultBaseURL,
+		tokenURL:    DefaultTokenURL,
+		relativeTTL: DefaultRelativeTimeCacheTTL,
 	}
 	for _, o := range options {
 		o(c)
@@ -72,37 +91,141 @@ func NewClient(options ...ClientOption) *Client {
 // Request performs a raw request. It is the caller's responsibility to
 // interpret the []byte returned.
 func (c *Client) Request(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, fs FormatStringer, options *RequestOptions) ([]byte, error) {
-	urlStr := fmt.Sprintf("%s/%s/%s/%s/%s", c.baseURL, ts.TimeString(), ps.ParameterString(), ls.LocationString(), fs.FormatString())
+	timeString := ts.TimeString()
+	urlStr := c.requestURL(timeString, ps, ls, fs, options)
+
+	var ttl time.Duration
+	if c.cache != nil {
+		ttl = c.cacheTTLFor(timeString)
+	}
+	if ttl > 0 && (options == nil || !options.BypassCache) {
+		if data, ok := c.cache.Get(urlStr); ok {
+			return data, nil
+		}
+	}
+
+	data, err := c.get(ctx, urlStr, fs.ContentType())
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl > 0 {
+		c.cache.Set(urlStr, data, ttl)
+	}
+	return data, nil
+}
+
+// requestStream performs a raw request and returns the response body without
+// reading it, so that large responses can be decoded as they arrive. Responses
+// are not cached. It is the caller's responsibility to close the returned body.
+func (c *Client) requestStream(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, fs FormatStringer, options *RequestOptions) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, c.requestURL(ts.TimeString(), ps, ls, fs, options), fs.ContentType())
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// requestURL returns the URL of a request.
+func (c *Client) requestURL(timeString TimeString, ps ParameterStringer, ls LocationStringer, fs FormatStringer, options *RequestOptions) string {
+	urlStr := fmt.Sprintf("%s/%s/%s/%s/%s", c.baseURL, timeString, ps.ParameterString(), ls.LocationString(), fs.FormatString())
 	if values := options.Values(); values != nil {
 		urlStr += "?" + values.Encode()
 	}
+	return urlStr
+}
 
+// authorizedURLLength returns the length of urlStr after c's pre-request funcs,
+// which may add query parameters such as access tokens, have been applied.
+func (c *Client) authorizedURLLength(ctx context.Context, urlStr string) (int, error) {
 	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	req = req.WithContext(ctx)
-	req.Header.Set("Accept", fs.ContentType())
 	for _, f := range c.preRequestFuncs {
-		f(req)
+		if err := f(req); err != nil {
+			return 0, err
+		}
 	}
+	return len(req.URL.String()), nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// get performs a GET request to urlStr, retrying according to c's retry
+// policy, and returns the response body.
+func (c *Client) get(ctx context.Context, urlStr, accept string) ([]byte, error) {
+	resp, err := c.do(ctx, urlStr, accept)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// do performs a GET request to urlStr, retrying according to c's retry
+// policy. On success, it is the caller's responsibility to close the
+// returned response's body.
+func (c *Client) do(ctx context.Context, urlStr, accept string) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.doOnce(ctx, urlStr, accept)
+		if err == nil {
+			return resp, nil
+		}
+		if e, ok := err.(*permanentError); ok {
+			return nil, e.err
+		}
+		p := c.retryPolicy
+		if p == nil || attempt >= p.MaxAttempts || !p.retryable(ctx, err) {
+			return nil, err
+		}
+		if !sleep(ctx, p.delay(attempt, err)) {
+			return nil, err
+		}
+	}
+}
+
+// doOnce performs a single GET request to urlStr.
+func (c *Client) doOnce(ctx context.Context, urlStr, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", accept)
+	for _, f := range c.preRequestFuncs {
+		if err := f(req); err != nil {
+			return nil, &permanentError{err: err}
+		}
+	}
+
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		release()
+		return nil, redactError(err)
+	}
 
 	if resp.StatusCode < http.StatusOK || http.StatusMultipleChoices <= resp.StatusCode {
+		defer release()
+		defer resp.Body.Close()
 		respBody, _ := ioutil.ReadAll(resp.Body)
-		return nil, &Error{
-			Request:      req,
-			Response:     resp,
-			ResponseBody: respBody,
+		if resp.StatusCode == http.StatusUnauthorized {
+			for _, f := range c.unauthorizedFuncs {
+				f(req)
+			}
 		}
+		return nil, newError(req, resp, respBody)
 	}
 
-	return ioutil.ReadAll(resp.Body)
+	resp.Body = &releaseOnCloseReadCloser{
+		ReadCloser: resp.Body,
+		release:    release,
+	}
+	return resp, nil
 }
 
 // Values returns the url.Values that set the request options defined by o.
@@ -112,7 +235,7 @@ func (o *RequestOptions) Values() url.Values {
 	}
 	v := url.Values{}
 	if o.Source != "" {
-		v.Set("source", o.Source)
+		v.Set("source", string(o.Source))
 	}
 	if o.TemporalInterpolation != "" {
 		v.Set("temporal_interpolation", o.TemporalInterpolation)
@@ -129,6 +252,12 @@ func (o *RequestOptions) Values() url.Values {
 	if o.Route {
 		v.Set("route", "true")
 	}
+	if o.FillWithInvalid {
+		v.Set("on_invalid", "fill_with_invalid")
+	}
+	if !o.InitDate.IsZero() {
+		v.Set("init_date", formatTime(o.InitDate))
+	}
 	if len(v) == 0 {
 		return nil
 	}
@@ -5,11 +5,16 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
+	"io/ioutil"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// maxCSVLineLength is the maximum length of a line in a CSV response.
+const maxCSVLineLength = 16 * 1024 * 1024
+
 var errCSVParse = errors.New("csv parse error")
 
 // A CSVRow is a CSV row.
@@ -47,6 +52,78 @@ type CSVRouteResponse struct {
 	Rows       []CSVRouteRow
 }
 
+// A CSVLocationRow is a row of a CSV response for multiple locations. Lat and
+// Lon are set for coordinate locations and StationID is set for station
+// locations.
+type CSVLocationRow struct {
+	Lat       float64
+	Lon       float64
+	StationID string
+	ValidDate time.Time
+	Values    []float64
+}
+
+// A CSVLocationsResponse is a response to a CSV request for multiple
+// locations.
+type CSVLocationsResponse struct {
+	Parameters []ParameterString
+	Rows       []CSVLocationRow
+}
+
+// A CSVRegionRow is a row of a CSV region response. Lons is shared by all the
+// rows in the same region and must not be modified.
+type CSVRegionRow struct {
+	ValidDate time.Time
+	Parameter ParameterString
+	Lat       float64
+	Lons      []float64
+	Values    []float64
+}
+
+// A CSVRowReader reads the rows of a CSV response.
+type CSVRowReader struct {
+	rc            io.ReadCloser
+	s             *bufio.Scanner
+	missingValues missingValues
+	parameters    []ParameterString
+	row           CSVRow
+	err           error
+}
+
+// A CSVRegionRowReader reads the rows of a CSV region response.
+type CSVRegionRowReader struct {
+	rc            io.ReadCloser
+	s             *bufio.Scanner
+	missingValues missingValues
+	header        *CSVRegionResponse
+	onHeader      func(*CSVRegionResponse)
+	row           CSVRegionRow
+	err           error
+}
+
+// A CSVLocationRowReader reads the rows of a CSV response for multiple
+// locations.
+type CSVLocationRowReader struct {
+	rc            io.ReadCloser
+	s             *bufio.Scanner
+	missingValues missingValues
+	stations      bool
+	columns       int
+	parameters    []ParameterString
+	row           CSVLocationRow
+	err           error
+}
+
+// A CSVRouteRowReader reads the rows of a CSV route response.
+type CSVRouteRowReader struct {
+	rc            io.ReadCloser
+	s             *bufio.Scanner
+	missingValues missingValues
+	parameters    []ParameterString
+	row           CSVRouteRow
+	err           error
+}
+
 // RequestCSV requests a forecast in CSV format.
 func (c *Client) RequestCSV(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*CSVResponse, error) {
 	data, err := c.Request(ctx, ts, ps, ls, FormatCSV, options)
@@ -54,175 +131,571 @@ func (c *Client) RequestCSV(ctx context.Context, ts TimeStringer, ps ParameterSt
 		return nil, err
 	}
 
-	cr := &CSVResponse{}
-
-	s := bufio.NewScanner(bytes.NewReader(data))
-	if !s.Scan() {
-		return nil, errCSVParse
+	r, err := newCSVRowReader(ioutil.NopCloser(bytes.NewReader(data)), c.missingValues)
+	if err != nil {
+		return nil, err
 	}
-	record := strings.Split(s.Text(), ";")
-	if len(record) < 1 {
-		return nil, errCSVParse
+	cr := &CSVResponse{
+		Parameters: r.Parameters(),
 	}
-	if record[0] != "validdate" {
-		return nil, errCSVParse
+	for r.Next() {
+		cr.Rows = append(cr.Rows, r.Row())
 	}
-	cols := len(record)
-	cr.Parameters = make([]ParameterString, 0, cols-1)
-	for i := 1; i < cols; i++ {
-		cr.Parameters = append(cr.Parameters, ParameterString(record[i]))
+	if err := r.Err(); err != nil {
+		return nil, err
 	}
+	return cr, nil
+}
 
-	for s.Scan() {
-		record := strings.Split(s.Text(), ";")
-		if len(record) != cols {
-			return nil, errCSVParse
-		}
-		var row CSVRow
-		row.ValidDate, err = time.Parse(time.RFC3339, record[0])
-		if err != nil {
-			return nil, err
-		}
-		row.Values = make([]float64, 0, cols-1)
-		for i := 1; i < cols; i++ {
-			value, err := strconv.ParseFloat(record[i], 64)
-			if err != nil {
-				return nil, err
-			}
-			row.Values = append(row.Values, value)
-		}
-		cr.Rows = append(cr.Rows, row)
+// StreamCSV requests a forecast in CSV format and returns a reader over its
+// rows, which are parsed as they are received. The response is not cached. The
+// caller must call Close on the returned CSVRowReader when finished.
+func (c *Client) StreamCSV(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*CSVRowReader, error) {
+	rc, err := c.requestStream(ctx, ts, ps, ls, FormatCSV, options)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newCSVRowReader(rc, c.missingValues)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// RequestCSVLocations requests a forecast for multiple locations, for example
+// a PointList, a LocationSlice, or a list of stations, in CSV format. Each row
+// in the response is keyed by its location.
+func (c *Client) RequestCSVLocations(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*CSVLocationsResponse, error) {
+	data, err := c.Request(ctx, ts, ps, ls, FormatCSV, options)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newCSVLocationRowReader(ioutil.NopCloser(bytes.NewReader(data)), c.missingValues)
+	if err != nil {
+		return nil, err
+	}
+	clr := &CSVLocationsResponse{
+		Parameters: r.Parameters(),
+	}
+	for r.Next() {
+		clr.Rows = append(clr.Rows, r.Row())
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
 	}
+	return clr, nil
+}
 
-	return cr, s.Err()
+// StreamCSVLocations requests a forecast for multiple locations in CSV format
+// and returns a reader over its rows, which are parsed as they are received.
+// The response is not cached. The caller must call Close on the returned
+// CSVLocationRowReader when finished.
+func (c *Client) StreamCSVLocations(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*CSVLocationRowReader, error) {
+	rc, err := c.requestStream(ctx, ts, ps, ls, FormatCSV, options)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newCSVLocationRowReader(rc, c.missingValues)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return r, nil
 }
 
-// RequestCSVRegion requests a region forecast in CSV format.
+// RequestCSVRegion requests a region forecast for a single time and parameter
+// in CSV format.
 func (c *Client) RequestCSVRegion(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*CSVRegionResponse, error) {
+	crrs, err := c.RequestCSVRegions(ctx, ts, ps, ls, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(crrs) != 1 {
+		return nil, errCSVParse
+	}
+	return crrs[0], nil
+}
+
+// RequestCSVRegions requests a region forecast for multiple times and
+// parameters in CSV format. The response contains one CSVRegionResponse for
+// each time and parameter, in the order returned by the API.
+func (c *Client) RequestCSVRegions(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) ([]*CSVRegionResponse, error) {
 	data, err := c.Request(ctx, ts, ps, ls, FormatCSV, options)
 	if err != nil {
 		return nil, err
 	}
 
-	crr := &CSVRegionResponse{}
+	var crrs []*CSVRegionResponse
+	r, err := newCSVRegionRowReader(ioutil.NopCloser(bytes.NewReader(data)), c.missingValues, func(crr *CSVRegionResponse) {
+		crrs = append(crrs, crr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for r.Next() {
+		row := r.Row()
+		crr := crrs[len(crrs)-1]
+		crr.Lats = append(crr.Lats, row.Lat)
+		crr.Values = append(crr.Values, row.Values)
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return crrs, nil
+}
 
-	s := bufio.NewScanner(bytes.NewReader(data))
+// StreamCSVRegions requests a region forecast for multiple times and
+// parameters in CSV format and returns a reader over its rows, which are
+// parsed as they are received. The response is not cached. The caller must
+// call Close on the returned CSVRegionRowReader when finished.
+func (c *Client) StreamCSVRegions(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*CSVRegionRowReader, error) {
+	rc, err := c.requestStream(ctx, ts, ps, ls, FormatCSV, options)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newCSVRegionRowReader(rc, c.missingValues, nil)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return r, nil
+}
 
-	validDate, err := scanRow(s, "validdate")
+// RequestCSVRoute requests a region forecast in CSV format.
+func (c *Client) RequestCSVRoute(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*CSVRouteResponse, error) {
+	data, err := c.Request(ctx, ts, ps, ls, FormatCSV, routeOptions(options))
 	if err != nil {
 		return nil, err
 	}
-	crr.ValidDate, err = time.Parse("2006-01-02 15:04:05", validDate)
+
+	r, err := newCSVRouteRowReader(ioutil.NopCloser(bytes.NewReader(data)), c.missingValues)
 	if err != nil {
 		return nil, err
 	}
+	crr := &CSVRouteResponse{
+		Parameters: r.Parameters(),
+	}
+	for r.Next() {
+		crr.Rows = append(crr.Rows, r.Row())
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return crr, nil
+}
 
-	parameter, err := scanRow(s, "parameter")
+// StreamCSVRoute requests a route forecast in CSV format and returns a reader
+// over its rows, which are parsed as they are received. The response is not
+// cached. The caller must call Close on the returned CSVRouteRowReader when
+// finished.
+func (c *Client) StreamCSVRoute(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*CSVRouteRowReader, error) {
+	rc, err := c.requestStream(ctx, ts, ps, ls, FormatCSV, routeOptions(options))
 	if err != nil {
 		return nil, err
 	}
-	crr.Parameter = ParameterString(parameter)
+	r, err := newCSVRouteRowReader(rc, c.missingValues)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return r, nil
+}
 
-	if !s.Scan() {
+// newCSVRowReader returns a new CSVRowReader that reads from rc, after
+// reading and validating the header.
+func newCSVRowReader(rc io.ReadCloser, m missingValues) (*CSVRowReader, error) {
+	r := &CSVRowReader{
+		rc:            rc,
+		s:             newCSVScanner(rc),
+		missingValues: m,
+	}
+	if !r.s.Scan() {
 		return nil, errCSVParse
 	}
-	record := strings.Split(s.Text(), ";")
-	if len(record) == 0 || record[0] != "data" {
+	record := strings.Split(r.s.Text(), ";")
+	if record[0] != "validdate" {
 		return nil, errCSVParse
 	}
-	cols := len(record)
-	crr.Lons = make([]float64, 0, cols-1)
-	for i := 1; i < cols; i++ {
-		lon, err := strconv.ParseFloat(record[i], 64)
-		if err != nil {
-			return nil, err
+	r.parameters = parseCSVParameters(record[1:])
+	return r, nil
+}
+
+// Parameters returns the parameters in r's header.
+func (r *CSVRowReader) Parameters() []ParameterString {
+	return r.parameters
+}
+
+// Next advances r to the next row, which is then available through Row. It
+// returns false when there are no more rows, either by reaching the end of
+// the response or an error. After Next returns false, Err returns any error
+// that occurred.
+func (r *CSVRowReader) Next() bool {
+	if r.err != nil || !r.s.Scan() {
+		if r.err == nil {
+			r.err = r.s.Err()
 		}
-		crr.Lons = append(crr.Lons, lon)
+		return false
+	}
+	record := strings.Split(r.s.Text(), ";")
+	if len(record) != len(r.parameters)+1 {
+		r.err = errCSVParse
+		return false
+	}
+	var row CSVRow
+	if row.ValidDate, r.err = time.Parse(time.RFC3339, record[0]); r.err != nil {
+		return false
 	}
+	if row.Values, r.err = parseCSVValues(record[1:], r.missingValues); r.err != nil {
+		return false
+	}
+	r.row = row
+	return true
+}
 
-	for s.Scan() {
-		record := strings.Split(s.Text(), ";")
-		if len(record) != cols {
-			return nil, errCSVParse
+// Row returns the current row.
+func (r *CSVRowReader) Row() CSVRow {
+	return r.row
+}
+
+// Err returns the first error encountered by r.
+func (r *CSVRowReader) Err() error {
+	return r.err
+}
+
+// Close closes r.
+func (r *CSVRowReader) Close() error {
+	return r.rc.Close()
+}
+
+// newCSVLocationRowReader returns a new CSVLocationRowReader that reads from
+// rc, after reading and validating the header. The header may start with
+// station_id;validdate, lat;lon;validdate, or, for a single location,
+// validdate.
+func newCSVLocationRowReader(rc io.ReadCloser, m missingValues) (*CSVLocationRowReader, error) {
+	r := &CSVLocationRowReader{
+		rc:            rc,
+		s:             newCSVScanner(rc),
+		missingValues: m,
+	}
+	if !r.s.Scan() {
+		return nil, errCSVParse
+	}
+	record := strings.Split(r.s.Text(), ";")
+	switch {
+	case len(record) >= 2 && record[0] == "station_id" && record[1] == "validdate":
+		r.stations = true
+		r.columns = 2
+	case len(record) >= 3 && record[0] == "lat" && record[1] == "lon" && record[2] == "validdate":
+		r.columns = 3
+	case record[0] == "validdate":
+		r.columns = 1
+	default:
+		return nil, errCSVParse
+	}
+	r.parameters = parseCSVParameters(record[r.columns:])
+	return r, nil
+}
+
+// Parameters returns the parameters in r's header.
+func (r *CSVLocationRowReader) Parameters() []ParameterString {
+	return r.parameters
+}
+
+// Next advances r to the next row, which is then available through Row. It
+// returns false when there are no more rows, either by reaching the end of
+// the response or an error. After Next returns false, Err returns any error
+// that occurred.
+func (r *CSVLocationRowReader) Next() bool {
+	if r.err != nil || !r.s.Scan() {
+		if r.err == nil {
+			r.err = r.s.Err()
 		}
-		lat, err := strconv.ParseFloat(record[0], 64)
-		if err != nil {
-			return nil, err
+		return false
+	}
+	record := strings.Split(r.s.Text(), ";")
+	if len(record) != len(r.parameters)+r.columns {
+		r.err = errCSVParse
+		return false
+	}
+	var row CSVLocationRow
+	switch {
+	case r.stations:
+		row.StationID = record[0]
+	case r.columns == 3:
+		if row.Lat, r.err = strconv.ParseFloat(record[0], 64); r.err != nil {
+			return false
+		}
+		if row.Lon, r.err = strconv.ParseFloat(record[1], 64); r.err != nil {
+			return false
 		}
-		crr.Lats = append(crr.Lats, lat)
-		values := make([]float64, 0, cols-1)
-		for i := 1; i < cols; i++ {
-			value, err := strconv.ParseFloat(record[i], 64)
-			if err != nil {
+	}
+	if row.ValidDate, r.err = time.Parse(time.RFC3339, record[r.columns-1]); r.err != nil {
+		return false
+	}
+	if row.Values, r.err = parseCSVValues(record[r.columns:], r.missingValues); r.err != nil {
+		return false
+	}
+	r.row = row
+	return true
+}
+
+// Row returns the current row.
+func (r *CSVLocationRowReader) Row() CSVLocationRow {
+	return r.row
+}
+
+// Err returns the first error encountered by r.
+func (r *CSVLocationRowReader) Err() error {
+	return r.err
+}
+
+// Close closes r.
+func (r *CSVLocationRowReader) Close() error {
+	return r.rc.Close()
+}
+
+// newCSVRegionRowReader returns a new CSVRegionRowReader that reads from rc,
+// after reading and validating the header of the first block. onHeader, if
+// not nil, is called with the header of each block.
+func newCSVRegionRowReader(rc io.ReadCloser, m missingValues, onHeader func(*CSVRegionResponse)) (*CSVRegionRowReader, error) {
+	r := &CSVRegionRowReader{
+		rc:            rc,
+		s:             newCSVScanner(rc),
+		missingValues: m,
+		onHeader:      onHeader,
+	}
+	for r.s.Text() == "" {
+		if !r.s.Scan() {
+			if err := r.s.Err(); err != nil {
 				return nil, err
 			}
-			values = append(values, value)
+			return nil, errCSVParse
 		}
-		crr.Values = append(crr.Values, values)
 	}
+	record := strings.Split(r.s.Text(), ";")
+	if record[0] != "validdate" {
+		return nil, errCSVParse
+	}
+	header, err := scanCSVRegionHeader(r.s, record)
+	if err != nil {
+		return nil, err
+	}
+	r.header = header
+	if r.onHeader != nil {
+		r.onHeader(r.header)
+	}
+	return r, nil
+}
 
-	return crr, s.Err()
+// Next advances r to the next row, which is then available through Row. It
+// returns false when there are no more rows, either by reaching the end of
+// the response or an error. After Next returns false, Err returns any error
+// that occurred.
+func (r *CSVRegionRowReader) Next() bool {
+	for r.err == nil {
+		if !r.s.Scan() {
+			r.err = r.s.Err()
+			return false
+		}
+		if r.s.Text() == "" {
+			continue
+		}
+		record := strings.Split(r.s.Text(), ";")
+		if record[0] == "validdate" {
+			if r.header, r.err = scanCSVRegionHeader(r.s, record); r.err != nil {
+				return false
+			}
+			if r.onHeader != nil {
+				r.onHeader(r.header)
+			}
+			continue
+		}
+		if len(record) != len(r.header.Lons)+1 {
+			r.err = errCSVParse
+			return false
+		}
+		row := CSVRegionRow{
+			ValidDate: r.header.ValidDate,
+			Parameter: r.header.Parameter,
+			Lons:      r.header.Lons,
+		}
+		if row.Lat, r.err = strconv.ParseFloat(record[0], 64); r.err != nil {
+			return false
+		}
+		if row.Values, r.err = parseCSVValues(record[1:], r.missingValues); r.err != nil {
+			return false
+		}
+		r.row = row
+		return true
+	}
+	return false
 }
 
-// RequestCSVRoute requests a region forecast in CSV format.
-func (c *Client) RequestCSVRoute(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*CSVRouteResponse, error) {
+// Row returns the current row.
+func (r *CSVRegionRowReader) Row() CSVRegionRow {
+	return r.row
+}
+
+// Err returns the first error encountered by r.
+func (r *CSVRegionRowReader) Err() error {
+	return r.err
+}
+
+// Close closes r.
+func (r *CSVRegionRowReader) Close() error {
+	return r.rc.Close()
+}
+
+// newCSVRouteRowReader returns a new CSVRouteRowReader that reads from rc,
+// after reading and validating the header.
+func newCSVRouteRowReader(rc io.ReadCloser, m missingValues) (*CSVRouteRowReader, error) {
+	r := &CSVRouteRowReader{
+		rc:            rc,
+		s:             newCSVScanner(rc),
+		missingValues: m,
+	}
+	if !r.s.Scan() {
+		return nil, errCSVParse
+	}
+	record := strings.Split(r.s.Text(), ";")
+	if len(record) < 3 || record[0] != "lat" || record[1] != "lon" || record[2] != "validdate" {
+		return nil, errCSVParse
+	}
+	r.parameters = parseCSVParameters(record[3:])
+	return r, nil
+}
+
+// Parameters returns the parameters in r's header.
+func (r *CSVRouteRowReader) Parameters() []ParameterString {
+	return r.parameters
+}
+
+// Next advances r to the next row, which is then available through Row. It
+// returns false when there are no more rows, either by reaching the end of
+// the response or an error. After Next returns false, Err returns any error
+// that occurred.
+func (r *CSVRouteRowReader) Next() bool {
+	if r.err != nil || !r.s.Scan() {
+		if r.err == nil {
+			r.err = r.s.Err()
+		}
+		return false
+	}
+	record := strings.Split(r.s.Text(), ";")
+	if len(record) != len(r.parameters)+3 {
+		r.err = errCSVParse
+		return false
+	}
+	var row CSVRouteRow
+	if row.Lat, r.err = strconv.ParseFloat(record[0], 64); r.err != nil {
+		return false
+	}
+	if row.Lon, r.err = strconv.ParseFloat(record[1], 64); r.err != nil {
+		return false
+	}
+	if row.ValidDate, r.err = time.Parse(time.RFC3339, record[2]); r.err != nil {
+		return false
+	}
+	if row.Values, r.err = parseCSVValues(record[3:], r.missingValues); r.err != nil {
+		return false
+	}
+	r.row = row
+	return true
+}
+
+// Row returns the current row.
+func (r *CSVRouteRowReader) Row() CSVRouteRow {
+	return r.row
+}
+
+// Err returns the first error encountered by r.
+func (r *CSVRouteRowReader) Err() error {
+	return r.err
+}
+
+// Close closes r.
+func (r *CSVRouteRowReader) Close() error {
+	return r.rc.Close()
+}
+
+// newCSVScanner returns a new bufio.Scanner that scans the lines of a CSV
+// response from r.
+func newCSVScanner(r io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxCSVLineLength)
+	return s
+}
+
+// parseCSVParameters parses the parameters in a CSV header.
+func parseCSVParameters(record []string) []ParameterString {
+	parameters := make([]ParameterString, 0, len(record))
+	for _, field := range record {
+		parameters = append(parameters, ParameterString(field))
+	}
+	return parameters
+}
+
+// parseCSVValues parses the values in a CSV record, replacing empty fields
+// and missing values with NaN.
+func parseCSVValues(record []string, m missingValues) ([]float64, error) {
+	values := make([]float64, 0, len(record))
+	for _, field := range record {
+		value, err := parseValue(field)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, m.replace(value))
+	}
+	return values, nil
+}
+
+// routeOptions returns a copy of options with Route set.
+func routeOptions(options *RequestOptions) *RequestOptions {
 	var ro RequestOptions
 	if options != nil {
 		ro = *options
 	}
 	ro.Route = true
-	data, err := c.Request(ctx, ts, ps, ls, FormatCSV, &ro)
+	return &ro
+}
+
+// scanCSVRegionHeader scans the header of a CSV region block whose first
+// record, validDateRecord, has already been scanned.
+func scanCSVRegionHeader(s *bufio.Scanner, validDateRecord []string) (*CSVRegionResponse, error) {
+	if len(validDateRecord) != 2 {
+		return nil, errCSVParse
+	}
+	crr := &CSVRegionResponse{}
+	var err error
+	crr.ValidDate, err = time.Parse("2006-01-02 15:04:05", validDateRecord[1])
 	if err != nil {
 		return nil, err
 	}
 
-	crr := &CSVRouteResponse{}
-
-	s := bufio.NewScanner(bytes.NewReader(data))
+	parameter, err := scanRow(s, "parameter")
+	if err != nil {
+		return nil, err
+	}
+	crr.Parameter = ParameterString(parameter)
 
 	if !s.Scan() {
 		return nil, errCSVParse
 	}
 	record := strings.Split(s.Text(), ";")
-	if len(record) < 3 || record[0] != "lat" || record[1] != "lon" || record[2] != "validdate" {
+	if len(record) == 0 || record[0] != "data" {
 		return nil, errCSVParse
 	}
-	cols := len(record)
-	crr.Parameters = make([]ParameterString, 0, cols-3)
-	for i := 3; i < cols; i++ {
-		crr.Parameters = append(crr.Parameters, ParameterString(record[i]))
-	}
-
-	for s.Scan() {
-		record := strings.Split(s.Text(), ";")
-		if len(record) != cols {
-			return nil, errCSVParse
-		}
-		var row CSVRouteRow
-		row.Lat, err = strconv.ParseFloat(record[0], 64)
-		if err != nil {
-			return nil, err
-		}
-		row.Lon, err = strconv.ParseFloat(record[1], 64)
-		if err != nil {
-			return nil, err
-		}
-		row.ValidDate, err = time.Parse(time.RFC3339, record[2])
+	crr.Lons = make([]float64, 0, len(record)-1)
+	for i := 1; i < len(record); i++ {
+		lon, err := strconv.ParseFloat(record[i], 64)
 		if err != nil {
 			return nil, err
 		}
-		row.Values = make([]float64, 0, cols-3)
-		for i := 3; i < cols; i++ {
-			value, err := strconv.ParseFloat(record[i], 64)
-			if err != nil {
-				return nil, err
-			}
-			row.Values = append(row.Values, value)
-		}
-		crr.Rows = append(crr.Rows, row)
+		crr.Lons = append(crr.Lons, lon)
 	}
-
-	return crr, s.Err()
+	return crr, nil
 }
 
 func scanRow(s *bufio.Scanner, name string) (string, error) {
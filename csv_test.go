@@ -2,6 +2,9 @@ package meteomatics
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -137,3 +140,318 @@ func TestClientRequestCSVRoute(t *testing.T) {
 	assert.Equal(t, time.Date(2018, 10, 23, 15, 47, 46, 0, time.UTC), r.Rows[0].ValidDate)
 	assert.Equal(t, []float64{10.9, 0.02}, r.Rows[0].Values)
 }
+
+func TestClientRequestCSVRegions(t *testing.T) {
+	s := newTestServer(
+		t,
+		"/2016-12-19T12:00:00ZPT6H:PT6H/t_2m:C,wind_speed_10m:ms/50,10_40,20:3x2/csv",
+		"testdata/temperature_and_wind_speed_geographical_region_two_times.csv",
+	)
+	r, err := NewClient(WithBaseURL(s.URL)).RequestCSVRegions(
+		context.Background(),
+		TimePeriod{
+			Start:    time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC),
+			Duration: 6 * time.Hour,
+			Step:     6 * time.Hour,
+		},
+		ParameterSlice{
+			Parameter{
+				Name:  ParameterTemperature,
+				Level: LevelMeters(2),
+				Units: UnitsCelsius,
+			},
+			Parameter{
+				Name:  ParameterWindSpeed,
+				Level: LevelMeters(10),
+				Units: UnitsMetersPerSecond,
+			},
+		},
+		RectangleN{
+			Min: Point{
+				Lat: 40,
+				Lon: 10,
+			},
+			Max: Point{
+				Lat: 50,
+				Lon: 20,
+			},
+			NLon: 3,
+			NLat: 2,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	require.Len(t, r, 4)
+	for i, expected := range []struct {
+		validDate time.Time
+		parameter ParameterString
+		values    [][]float64
+	}{
+		{
+			validDate: time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC),
+			parameter: "t_2m:C",
+			values:    [][]float64{{1.5, 2.5, 3.5}, {4.5, 5.5, 6.5}},
+		},
+		{
+			validDate: time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC),
+			parameter: "wind_speed_10m:ms",
+			values:    [][]float64{{7, 8, 9}, {10, 11, 12}},
+		},
+		{
+			validDate: time.Date(2016, 12, 19, 18, 0, 0, 0, time.UTC),
+			parameter: "t_2m:C",
+			values:    [][]float64{{-1.5, -2.5, -3.5}, {-4.5, -5.5, -6.5}},
+		},
+		{
+			validDate: time.Date(2016, 12, 19, 18, 0, 0, 0, time.UTC),
+			parameter: "wind_speed_10m:ms",
+			values:    [][]float64{{0, 0.5, 1}, {1.5, 2, 2.5}},
+		},
+	} {
+		assert.Equal(t, expected.validDate, r[i].ValidDate)
+		assert.Equal(t, expected.parameter, r[i].Parameter)
+		assert.Equal(t, []float64{50, 40}, r[i].Lats)
+		assert.Equal(t, []float64{10, 15, 20}, r[i].Lons)
+		assert.Equal(t, expected.values, r[i].Values)
+	}
+}
+
+func TestClientStreamCSV(t *testing.T) {
+	s := newTestServer(
+		t,
+		"/2016-01-20T13:35:00ZP1D:PT3H/t_2m:C,relative_humidity_2m:p/47.423336,9.377225/csv",
+		"testdata/temperature_and_relative_humidity_time_series.csv",
+	)
+	r, err := NewClient(WithBaseURL(s.URL)).StreamCSV(
+		context.Background(),
+		TimePeriod{
+			Start:    time.Date(2016, 1, 20, 13, 35, 0, 0, time.UTC),
+			Duration: 24 * time.Hour,
+			Step:     3 * time.Hour,
+		},
+		ParameterSlice{
+			Parameter{
+				Name:  ParameterTemperature,
+				Level: LevelMeters(2),
+				Units: UnitsCelsius,
+			},
+			Parameter{
+				Name:  ParameterRelativeHumidity,
+				Level: LevelMeters(2),
+				Units: UnitsPercentage,
+			},
+		},
+		Point{
+			Lat: 47.423336,
+			Lon: 9.377225,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, []ParameterString{"t_2m:C", "relative_humidity_2m:p"}, r.Parameters())
+	var rows []CSVRow
+	for r.Next() {
+		rows = append(rows, r.Row())
+	}
+	require.NoError(t, r.Err())
+	require.Len(t, rows, 9)
+	assert.Equal(t, time.Date(2016, 1, 20, 13, 35, 0, 0, time.UTC), rows[0].ValidDate)
+	assert.Equal(t, []float64{-0.829, 99.2}, rows[0].Values)
+	assert.Equal(t, time.Date(2016, 1, 21, 13, 35, 0, 0, time.UTC), rows[8].ValidDate)
+	assert.Equal(t, []float64{-6.088, 100}, rows[8].Values)
+	assert.False(t, r.Next())
+}
+
+func TestClientStreamCSVInvalidHeader(t *testing.T) {
+	s := newTestServer(t, "/now/t_2m:C/postal_CH9000+postal_CH8000+postal_CH4000/csv", "testdata/csv_route_query.csv")
+	_, err := NewClient(WithBaseURL(s.URL)).StreamCSV(
+		context.Background(),
+		TimeNow,
+		Parameter{
+			Name:  ParameterTemperature,
+			Level: LevelMeters(2),
+			Units: UnitsCelsius,
+		},
+		LocationSlice{
+			Postal{
+				CountryCode: "CH",
+				ZIPCode:     "9000",
+			},
+			Postal{
+				CountryCode: "CH",
+				ZIPCode:     "8000",
+			},
+			Postal{
+				CountryCode: "CH",
+				ZIPCode:     "4000",
+			},
+		},
+		nil,
+	)
+	assert.Equal(t, errCSVParse, err)
+}
+
+func TestClientStreamCSVRegions(t *testing.T) {
+	s := newTestServer(
+		t,
+		"/2016-12-19T12:00:00ZPT6H:PT6H/t_2m:C,wind_speed_10m:ms/50,10_40,20:3x2/csv",
+		"testdata/temperature_and_wind_speed_geographical_region_two_times.csv",
+	)
+	r, err := NewClient(WithBaseURL(s.URL)).StreamCSVRegions(
+		context.Background(),
+		TimePeriod{
+			Start:    time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC),
+			Duration: 6 * time.Hour,
+			Step:     6 * time.Hour,
+		},
+		ParameterSlice{
+			Parameter{
+				Name:  ParameterTemperature,
+				Level: LevelMeters(2),
+				Units: UnitsCelsius,
+			},
+			Parameter{
+				Name:  ParameterWindSpeed,
+				Level: LevelMeters(10),
+				Units: UnitsMetersPerSecond,
+			},
+		},
+		RectangleN{
+			Min: Point{
+				Lat: 40,
+				Lon: 10,
+			},
+			Max: Point{
+				Lat: 50,
+				Lon: 20,
+			},
+			NLon: 3,
+			NLat: 2,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	defer r.Close()
+	var rows []CSVRegionRow
+	for r.Next() {
+		rows = append(rows, r.Row())
+	}
+	require.NoError(t, r.Err())
+	require.Len(t, rows, 8)
+	assert.Equal(t, CSVRegionRow{
+		ValidDate: time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC),
+		Parameter: "t_2m:C",
+		Lat:       50,
+		Lons:      []float64{10, 15, 20},
+		Values:    []float64{1.5, 2.5, 3.5},
+	}, rows[0])
+	assert.Equal(t, CSVRegionRow{
+		ValidDate: time.Date(2016, 12, 19, 18, 0, 0, 0, time.UTC),
+		Parameter: "wind_speed_10m:ms",
+		Lat:       40,
+		Lons:      []float64{10, 15, 20},
+		Values:    []float64{1.5, 2, 2.5},
+	}, rows[7])
+}
+
+func TestClientStreamCSVRegionsInvalidHeader(t *testing.T) {
+	s := newTestServer(t, "/now/t_2m:C/50,10_40,20:3x2/csv", "testdata/csv_route_query.csv")
+	_, err := NewClient(WithBaseURL(s.URL)).StreamCSVRegions(
+		context.Background(),
+		TimeNow,
+		Parameter{
+			Name:  ParameterTemperature,
+			Level: LevelMeters(2),
+			Units: UnitsCelsius,
+		},
+		RectangleN{
+			Min: Point{
+				Lat: 40,
+				Lon: 10,
+			},
+			Max: Point{
+				Lat: 50,
+				Lon: 20,
+			},
+			NLon: 3,
+			NLat: 2,
+		},
+		nil,
+	)
+	assert.Equal(t, errCSVParse, err)
+}
+
+func TestClientStreamCSVRoute(t *testing.T) {
+	s := newTestServer(
+		t,
+		"/now,now+1H,now+2H/t_2m:C,precip_1h:mm/postal_CH9000+postal_CH8000+postal_CH4000/csv?route=true",
+		"testdata/csv_route_query.csv",
+	)
+	r, err := NewClient(WithBaseURL(s.URL)).StreamCSVRoute(
+		context.Background(),
+		TimeSlice{
+			TimeNow,
+			NowOffset(1 * time.Hour),
+			NowOffset(2 * time.Hour),
+		},
+		ParameterSlice{
+			Parameter{
+				Name:  ParameterTemperature,
+				Level: LevelMeters(2),
+				Units: UnitsCelsius,
+			},
+			Parameter{
+				Name:     ParameterPrecipitation,
+				Interval: Interval(1 * time.Hour),
+				Units:    UnitsMillimeters,
+			},
+		},
+		LocationSlice{
+			Postal{
+				CountryCode: "CH",
+				ZIPCode:     "9000",
+			},
+			Postal{
+				CountryCode: "CH",
+				ZIPCode:     "8000",
+			},
+			Postal{
+				CountryCode: "CH",
+				ZIPCode:     "4000",
+			},
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, []ParameterString{"t_2m:C", "precip_1h:mm"}, r.Parameters())
+	require.True(t, r.Next())
+	row := r.Row()
+	assert.Equal(t, 47.4239, row.Lat)
+	assert.Equal(t, 9.3748, row.Lon)
+	assert.Equal(t, time.Date(2018, 10, 23, 15, 47, 46, 0, time.UTC), row.ValidDate)
+	assert.Equal(t, []float64{10.9, 0.02}, row.Values)
+	n := 1
+	for r.Next() {
+		n++
+	}
+	require.NoError(t, r.Err())
+	assert.Equal(t, 3, n)
+}
+
+func TestClientRequestCSVLocationsInvalidHeader(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("station_id;lat;t_2m:C\nwmo_06660;47;1\n"))
+	}))
+	defer s.Close()
+
+	_, err := NewClient(WithBaseURL(s.URL)).RequestCSVLocations(
+		context.Background(),
+		Time(time.Date(2016, 1, 20, 13, 35, 0, 0, time.UTC)),
+		ParameterString("t_2m:C"),
+		LocationString("wmo_06660"),
+		nil,
+	)
+	assert.True(t, errors.Is(err, errCSVParse))
+}
@@ -1,17 +1,281 @@
 package meteomatics
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
-// An Error is an error.
+// Errors that an *APIError can wrap, for use with errors.Is.
+var (
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrOutOfDomain      = errors.New("out of domain")
+	ErrInvalidParameter = errors.New("invalid parameter")
+)
+
+var (
+	availableDomainRx  = regexp.MustCompile(`Available domain:?\s*` + domainPattern)
+	queriedDomainRx    = regexp.MustCompile(`Queried domain:?\s*` + domainPattern)
+	invalidParameterRx = regexp.MustCompile(`\b(invalid|unknown|unsupported) parameters?\b|` +
+		`\bparameters? \S+ (is |are )?(not (known|supported|found)|invalid)\b|` +
+		`\bcould not parse parameters?\b`)
+	htmlBodyRx   = regexp.MustCompile(`(?is)<body[^>]*>(.*)</body>`)
+	htmlTagRx    = regexp.MustCompile(`(?s)<[^>]*>`)
+	whitespaceRx = regexp.MustCompile(`\s+`)
+)
+
+const (
+	floatPattern  = `(-?\d+(?:\.\d+)?)`
+	domainPattern = floatPattern + `,` + floatPattern + `_` + floatPattern + `,` + floatPattern
+)
+
+// An Error is an error. Credentials are redacted from its Request and from
+// its Response's Request.
 type Error struct {
 	Request      *http.Request
 	Response     *http.Response
 	ResponseBody []byte
+	APIError     *APIError
+}
+
+// A Domain is a rectangular spatial domain.
+type Domain struct {
+	Min Point
+	Max Point
+}
+
+// An ErrorReason is a reason given by the API for a failure, and the models to
+// which it applies.
+type ErrorReason struct {
+	Message string
+	Models  []string
+}
+
+// An APIError is an error message returned by the API.
+type APIError struct {
+	StatusCode      int
+	Message         string
+	Reasons         []ErrorReason
+	AvailableDomain *Domain
+	QueriedDomain   *Domain
 }
 
 func (e *Error) Error() string {
-	return fmt.Sprintf("%s: %d %s", e.Request.URL, e.Response.StatusCode, http.StatusText(e.Response.StatusCode))
+	s := fmt.Sprintf("%s: %d %s", e.Request.URL, e.Response.StatusCode, http.StatusText(e.Response.StatusCode))
+	if e.APIError != nil && e.APIError.Message != "" {
+		s += ": " + e.APIError.Error()
+	}
+	return s
+}
+
+// Unwrap returns e's APIError.
+func (e *Error) Unwrap() error {
+	if e.APIError == nil {
+		return nil
+	}
+	return e.APIError
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return http.StatusText(e.StatusCode)
+	}
+	if n := len(e.Reasons); n > 1 && e.Reasons[n-1].Message != e.Message {
+		return e.Message + ": " + e.Reasons[n-1].Message
+	}
+	return e.Message
+}
+
+// Unwrap returns the sentinel error that best describes e, or nil if there is
+// none.
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+	message := strings.ToLower(e.Message)
+	for _, reason := range e.Reasons {
+		message += " " + strings.ToLower(reason.Message)
+	}
+	switch {
+	case strings.Contains(message, "unauthorized") ||
+		strings.Contains(message, "wrong username") ||
+		strings.Contains(message, "invalid username"):
+		return ErrUnauthorized
+	case strings.Contains(message, "too many requests") ||
+		strings.Contains(message, "limit exceeded") ||
+		strings.Contains(message, "limit reached"):
+		return ErrRateLimited
+	case e.AvailableDomain != nil || e.QueriedDomain != nil ||
+		strings.Contains(message, "not enough data outside") ||
+		strings.Contains(message, "not available at queried location"):
+		return ErrOutOfDomain
+	case invalidParameterRx.MatchString(message):
+		return ErrInvalidParameter
+	default:
+		return nil
+	}
+}
+
+// newError returns a new *Error for the failed request req. Credentials are
+// redacted from the request and response.
+func newError(req *http.Request, resp *http.Response, respBody []byte) *Error {
+	redactedReq := redactRequest(req)
+	return &Error{
+		Request:      redactedReq,
+		Response:     redactResponse(resp, redactedReq),
+		ResponseBody: respBody,
+		APIError:     parseAPIError(resp.StatusCode, respBody),
+	}
+}
+
+// parseAPIError parses the body of an error response, which may be JSON, HTML,
+// or plain text.
+func parseAPIError(statusCode int, body []byte) *APIError {
+	var message string
+	switch body = bytes.TrimSpace(body); {
+	case len(body) == 0:
+	case body[0] == '{':
+		var jsonError struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(body, &jsonError); err != nil {
+			message = string(body)
+		} else if jsonError.Message != "" {
+			message = jsonError.Message
+		} else {
+			message = jsonError.Status
+		}
+	case body[0] == '<':
+		if m := htmlBodyRx.FindSubmatch(body); m != nil {
+			body = m[1]
+		}
+		message = html.UnescapeString(string(htmlTagRx.ReplaceAll(body, []byte(" "))))
+	default:
+		message = string(body)
+	}
+	e := parseAPIStatus(strings.TrimSpace(whitespaceRx.ReplaceAllString(message, " ")))
+	e.StatusCode = statusCode
+	return e
+}
+
+// parseAPIStatus parses an error message returned by the API, for example in
+// the status field of a JSON response.
+func parseAPIStatus(status string) *APIError {
+	e := &APIError{}
+	if status == "" {
+		return e
+	}
+	e.Reasons = parseErrorReasons(status)
+	e.Message = e.Reasons[0].Message
+	e.AvailableDomain = parseDomain(availableDomainRx, status)
+	e.QueriedDomain = parseDomain(queriedDomainRx, status)
+	return e
+}
+
+// parseErrorReasons parses a chain of reasons of the form
+//
+//	message models: (model1),(model2)(nested reasons)
+func parseErrorReasons(s string) []ErrorReason {
+	i := strings.Index(s, "models:")
+	if i == -1 {
+		return []ErrorReason{
+			{
+				Message: strings.TrimSpace(s),
+			},
+		}
+	}
+
+	reason := ErrorReason{
+		Message: strings.TrimSpace(s[:i]),
+	}
+	s = strings.TrimSpace(s[i+len("models:"):])
+	var nested string
+	adjacent := false
+	for strings.HasPrefix(s, "(") {
+		j := matchingParen(s)
+		if j == -1 {
+			break
+		}
+		group, rest := s[1:j], s[j+1:]
+		if adjacent {
+			nested = group
+			break
+		}
+		reason.Models = append(reason.Models, group)
+		switch {
+		case strings.HasPrefix(rest, ","):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "("):
+			adjacent = true
+		}
+		s = rest
+	}
+
+	reasons := []ErrorReason{reason}
+	if nested != "" {
+		reasons = append(reasons, parseErrorReasons(nested)...)
+	}
+	return reasons
+}
+
+// matchingParen returns the index of the parenthesis in s that closes the
+// parenthesis at s[0], or -1 if there is none.
+func matchingParen(s string) int {
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseDomain parses the first domain matched by rx in s.
+func parseDomain(rx *regexp.Regexp, s string) *Domain {
+	m := rx.FindStringSubmatch(s)
+	if m == nil {
+		return nil
+	}
+	var xs [4]float64
+	for i := range xs {
+		x, err := strconv.ParseFloat(m[i+1], 64)
+		if err != nil {
+			return nil
+		}
+		xs[i] = x
+	}
+	d := &Domain{
+		Min: Point{
+			Lat: xs[2],
+			Lon: xs[1],
+		},
+		Max: Point{
+			Lat: xs[0],
+			Lon: xs[3],
+		},
+	}
+	if d.Min.Lat > d.Max.Lat {
+		d.Min.Lat, d.Max.Lat = d.Max.Lat, d.Min.Lat
+	}
+	if d.Min.Lon > d.Max.Lon {
+		d.Min.Lon, d.Max.Lon = d.Max.Lon, d.Min.Lon
+	}
+	return d
 }
@@ -0,0 +1,189 @@
+package meteomatics
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAPIError(t *testing.T) {
+	outOfRangeError, err := ioutil.ReadFile("testdata/out_of_range_error.json")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name               string
+		statusCode         int
+		body               string
+		expectedMessage    string
+		expectedError      string
+		expectedSentinel   error
+		expectedReasons    []ErrorReason
+		expectedAvailable  *Domain
+		expectedQueried    *Domain
+		expectedNumReasons int
+	}{
+		{
+			name:          "empty",
+			statusCode:    http.StatusNotFound,
+			expectedError: "Not Found",
+		},
+		{
+			name:             "unauthorized_empty",
+			statusCode:       http.StatusUnauthorized,
+			expectedError:    "Unauthorized",
+			expectedSentinel: ErrUnauthorized,
+		},
+		{
+			name:             "rate_limited_text",
+			statusCode:       http.StatusTooManyRequests,
+			body:             "Too many requests\n",
+			expectedMessage:  "Too many requests",
+			expectedError:    "Too many requests",
+			expectedSentinel: ErrRateLimited,
+			expectedReasons: []ErrorReason{
+				{Message: "Too many requests"},
+			},
+		},
+		{
+			name:             "json_message",
+			statusCode:       http.StatusBadRequest,
+			body:             `{"status":"error","message":"Invalid parameter t_2m:X."}`,
+			expectedMessage:  "Invalid parameter t_2m:X.",
+			expectedError:    "Invalid parameter t_2m:X.",
+			expectedSentinel: ErrInvalidParameter,
+			expectedReasons: []ErrorReason{
+				{Message: "Invalid parameter t_2m:X."},
+			},
+		},
+		{
+			name:             "unknown_parameter",
+			statusCode:       http.StatusBadRequest,
+			body:             "Unknown parameter foo:C\n",
+			expectedMessage:  "Unknown parameter foo:C",
+			expectedError:    "Unknown parameter foo:C",
+			expectedSentinel: ErrInvalidParameter,
+		},
+		{
+			name:             "unauthorized_parameter_text",
+			statusCode:       http.StatusBadRequest,
+			body:             `{"status":"error","message":"Unauthorized: your account cannot access parameter t_2m:C"}`,
+			expectedMessage:  "Unauthorized: your account cannot access parameter t_2m:C",
+			expectedError:    "Unauthorized: your account cannot access parameter t_2m:C",
+			expectedSentinel: ErrUnauthorized,
+		},
+		{
+			name:             "quota_parameter_text",
+			statusCode:       http.StatusBadRequest,
+			body:             "Daily request limit exceeded for parameter requests\n",
+			expectedMessage:  "Daily request limit exceeded for parameter requests",
+			expectedError:    "Daily request limit exceeded for parameter requests",
+			expectedSentinel: ErrRateLimited,
+		},
+		{
+			name:            "unclassified_parameter_text",
+			statusCode:      http.StatusBadRequest,
+			body:            "Please add the parameter route=true for route queries\n",
+			expectedMessage: "Please add the parameter route=true for route queries",
+			expectedError:   "Please add the parameter route=true for route queries",
+		},
+		{
+			name:             "html",
+			statusCode:       http.StatusUnauthorized,
+			body:             "<html>\n<head><title>401 Authorization Required</title></head>\n<body>\n<h1>Unauthorized</h1>\n<p>Wrong username &amp; password</p>\n</body>\n</html>\n",
+			expectedMessage:  "Unauthorized Wrong username & password",
+			expectedError:    "Unauthorized Wrong username & password",
+			expectedSentinel: ErrUnauthorized,
+			expectedReasons: []ErrorReason{
+				{Message: "Unauthorized Wrong username & password"},
+			},
+		},
+		{
+			name:             "out_of_range",
+			statusCode:       http.StatusNotFound,
+			body:             string(outOfRangeError),
+			expectedMessage:  "Not enough data outside temporal and/or spatial domain",
+			expectedError:    "Not enough data outside temporal and/or spatial domain: Model ecmwf-era-interim not available at queried location. Available domain 90.0000000000000000,-180.0000000000000000_-90.0000000000000000,180.0000000000000000. Queried domain: 0.0000000000000000,190.0000000000000000_0.0000000000000000,190.0000000000000000.",
+			expectedSentinel: ErrOutOfDomain,
+			expectedAvailable: &Domain{
+				Min: Point{Lat: -90, Lon: -180},
+				Max: Point{Lat: 90, Lon: 180},
+			},
+			expectedQueried: &Domain{
+				Min: Point{Lat: 0, Lon: 190},
+				Max: Point{Lat: 0, Lon: 190},
+			},
+			expectedNumReasons: 8,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := parseAPIError(tc.statusCode, []byte(tc.body))
+			assert.Equal(t, tc.statusCode, e.StatusCode)
+			assert.Equal(t, tc.expectedMessage, e.Message)
+			assert.Equal(t, tc.expectedError, e.Error())
+			assert.Equal(t, tc.expectedSentinel, e.Unwrap())
+			if tc.expectedReasons != nil {
+				assert.Equal(t, tc.expectedReasons, e.Reasons)
+			}
+			if tc.expectedNumReasons != 0 {
+				assert.Len(t, e.Reasons, tc.expectedNumReasons)
+			}
+			assert.Equal(t, tc.expectedAvailable, e.AvailableDomain)
+			assert.Equal(t, tc.expectedQueried, e.QueriedDomain)
+		})
+	}
+}
+
+func TestParseErrorReasons(t *testing.T) {
+	outOfRangeError, err := ioutil.ReadFile("testdata/out_of_range_error.json")
+	require.NoError(t, err)
+	reasons := parseAPIError(http.StatusOK, outOfRangeError).Reasons
+	require.Len(t, reasons, 8)
+	assert.Equal(t, ErrorReason{
+		Message: "Not enough data outside temporal and/or spatial domain",
+		Models: []string{
+			"MM SWISS1K",
+			"UKMO NOWCAST WIND",
+			"UKMO EURO4",
+			"ECMWF IFS",
+			"NCEP GFS",
+			"ECMWF VAREPS",
+			"ECMWF MMSF",
+			"ECMWF ERA5",
+			"ECMWF ERA INTERIM",
+		},
+	}, reasons[0])
+	assert.Equal(t, ErrorReason{
+		Message: "Not enough data outside temporal and/or spatial domain",
+		Models: []string{
+			"ECMWF ERA5",
+			"ECMWF ERA INTERIM",
+		},
+	}, reasons[6])
+	assert.Equal(t, ErrorReason{
+		Message: "Model ecmwf-era-interim not available at queried location. Available domain 90.0000000000000000,-180.0000000000000000_-90.0000000000000000,180.0000000000000000. Queried domain: 0.0000000000000000,190.0000000000000000_0.0000000000000000,190.0000000000000000.",
+	}, reasons[7])
+}
+
+func TestClientRequestAPIError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"status":"error","message":"Unknown parameter t_2m:X"}`))
+	}))
+	defer s.Close()
+
+	_, err := NewClient(WithBaseURL(s.URL)).Request(context.Background(), TimeNow, ParameterString("t_2m:X"), Point{}, FormatCSV, nil)
+	require.Error(t, err)
+	assert.Equal(t, s.URL+"/now/t_2m:X/0,0/csv: 400 Bad Request: Unknown parameter t_2m:X", err.Error())
+	assert.True(t, errors.Is(err, ErrInvalidParameter))
+	assert.False(t, errors.Is(err, ErrOutOfDomain))
+	var apiError *APIError
+	require.True(t, errors.As(err, &apiError))
+	assert.Equal(t, "Unknown parameter t_2m:X", apiError.Message)
+}
@@ -26,6 +26,14 @@ var (
 		formatString: "csv",
 		contentType:  "text/csv",
 	}
+	FormatGeoTIFF = Format{
+		formatString: "geotiff",
+		contentType:  "image/tiff",
+	}
+	FormatGRIB2 = Format{
+		formatString: "grib2",
+		contentType:  "application/grib2",
+	}
 	FormatHTML = Format{
 		formatString: "html",
 		contentType:  "text/html",
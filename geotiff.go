@@ -0,0 +1,618 @@
+package meteomatics
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GeoTIFF errors.
+var (
+	ErrGeoTIFFUnsupported = errors.New("unsupported GeoTIFF")
+	errGeoTIFFParse       = errors.New("geotiff parse error")
+)
+
+// TIFF tags.
+const (
+	tiffTagImageWidth            = 256
+	tiffTagImageLength           = 257
+	tiffTagBitsPerSample         = 258
+	tiffTagCompression           = 259
+	tiffTagStripOffsets          = 273
+	tiffTagSamplesPerPixel       = 277
+	tiffTagRowsPerStrip          = 278
+	tiffTagStripByteCounts       = 279
+	tiffTagPredictor             = 317
+	tiffTagTileWidth             = 322
+	tiffTagTileLength            = 323
+	tiffTagTileOffsets           = 324
+	tiffTagTileByteCounts        = 325
+	tiffTagSampleFormat          = 339
+	tiffTagModelPixelScale       = 33550
+	tiffTagModelTiepoint         = 33922
+	tiffTagModelTransformation   = 34264
+	tiffTagGeoKeyDirectory       = 34735
+	tiffTagGeoDoubleParams       = 34736
+	tiffTagGeoASCIIParams        = 34737
+	tiffTagGDALNoData            = 42113
+	geoKeyRasterType             = 1025
+	geoKeyRasterPixelIsPoint     = 2
+	tiffCompressionNone          = 1
+	tiffCompressionDeflate       = 8
+	tiffCompressionAdobeDeflate  = 32946
+	tiffPredictorNone            = 1
+	tiffPredictorHorizontal      = 2
+	tiffPredictorFloatingPoint   = 3
+	tiffSampleFormatUnsigned     = 1
+	tiffSampleFormatSigned       = 2
+	tiffSampleFormatFloatingType = 3
+)
+
+// tiffMaxCompressionRatio is the maximum compression ratio of deflate, and so
+// bounds the size of the raster in a TIFF file.
+const tiffMaxCompressionRatio = 1032
+
+// TIFF field types.
+const (
+	tiffByte      = 1
+	tiffASCII     = 2
+	tiffShort     = 3
+	tiffLong      = 4
+	tiffRational  = 5
+	tiffSByte     = 6
+	tiffUndefined = 7
+	tiffSShort    = 8
+	tiffSLong     = 9
+	tiffSRational = 10
+	tiffFloat     = 11
+	tiffDouble    = 12
+)
+
+// A GeoTIFFResponse is a response to a GeoTIFF request. Values are indexed by
+// row then column. GeoTransform maps pixel coordinates to model coordinates,
+// in the same order as GDAL:
+//
+//	x = GeoTransform[0] + col*GeoTransform[1] + row*GeoTransform[2]
+//	y = GeoTransform[3] + col*GeoTransform[4] + row*GeoTransform[5]
+//
+// where (col, row) = (0, 0) is the outer corner of the top left pixel. GeoKeys
+// maps GeoKey IDs to their values, which are ints, []float64s, or strings.
+type GeoTIFFResponse struct {
+	Width        int
+	Height       int
+	Values       [][]float64
+	GeoTransform [6]float64
+	GeoKeys      map[int]interface{}
+}
+
+// A tiffField is a TIFF field.
+type tiffField struct {
+	fieldType int
+	count     int
+	data      []byte
+}
+
+// complete returns whether f's data contains all of its values.
+func (f tiffField) complete() bool {
+	typeSize := tiffTypeSize(f.fieldType)
+	return typeSize != 0 && f.count >= 0 && len(f.data) >= f.count*typeSize
+}
+
+// A tiffReader reads a TIFF file.
+type tiffReader struct {
+	data      []byte
+	byteOrder binary.ByteOrder
+	fields    map[int]tiffField
+}
+
+// RequestGeoTIFF requests a forecast for a single time and parameter in
+// GeoTIFF format. If the returned file does not contain a georeference then it
+// is derived from ls, which must then be a RectangleN or RectangleRes.
+func (c *Client) RequestGeoTIFF(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*GeoTIFFResponse, error) {
+	data, err := c.Request(ctx, ts, ps, ls, FormatGeoTIFF, options)
+	if err != nil {
+		return nil, err
+	}
+	gr, err := ParseGeoTIFF(data)
+	if err != nil {
+		return nil, err
+	}
+	if gr.GeoTransform == ([6]float64{}) {
+		if gr.GeoTransform, err = locationGeoTransform(ls); err != nil {
+			return nil, err
+		}
+	}
+	return gr, nil
+}
+
+// ParseGeoTIFF parses the first image in a single-band GeoTIFF file. Values
+// equal to the GDAL no data value are replaced by NaN.
+func ParseGeoTIFF(data []byte) (*GeoTIFFResponse, error) {
+	r := &tiffReader{
+		data: data,
+	}
+	switch {
+	case len(data) < 8:
+		return nil, errGeoTIFFParse
+	case data[0] == 'I' && data[1] == 'I':
+		r.byteOrder = binary.LittleEndian
+	case data[0] == 'M' && data[1] == 'M':
+		r.byteOrder = binary.BigEndian
+	default:
+		return nil, errGeoTIFFParse
+	}
+	if r.byteOrder.Uint16(data[2:]) != 42 {
+		return nil, errGeoTIFFParse
+	}
+	if err := r.readIFD(int(r.byteOrder.Uint32(data[4:]))); err != nil {
+		return nil, err
+	}
+
+	gr := &GeoTIFFResponse{}
+	var err error
+	if gr.Values, err = r.raster(); err != nil {
+		return nil, err
+	}
+	gr.Height = len(gr.Values)
+	if gr.Height > 0 {
+		gr.Width = len(gr.Values[0])
+	}
+	if gr.GeoKeys, err = r.geoKeys(); err != nil {
+		return nil, err
+	}
+	if gr.GeoTransform, err = r.geoTransform(); err != nil {
+		return nil, err
+	}
+	if gr.GeoKeys[geoKeyRasterType] == geoKeyRasterPixelIsPoint {
+		// Tie points refer to pixel centers, so shift the transform by half a
+		// pixel to refer to pixel corners.
+		gr.GeoTransform[0] -= (gr.GeoTransform[1] + gr.GeoTransform[2]) / 2
+		gr.GeoTransform[3] -= (gr.GeoTransform[4] + gr.GeoTransform[5]) / 2
+	}
+	return gr, nil
+}
+
+// Point returns the model coordinates of the center of the pixel at col, row.
+func (r *GeoTIFFResponse) Point(col, row int) Point {
+	x, y := float64(col)+0.5, float64(row)+0.5
+	gt := r.GeoTransform
+	return Point{
+		Lat: gt[3] + x*gt[4] + y*gt[5],
+		Lon: gt[0] + x*gt[1] + y*gt[2],
+	}
+}
+
+// readIFD reads the image file directory at offset.
+func (r *tiffReader) readIFD(offset int) error {
+	if offset < 8 || offset+2 > len(r.data) {
+		return errGeoTIFFParse
+	}
+	n := int(r.byteOrder.Uint16(r.data[offset:]))
+	offset += 2
+	if offset+12*n > len(r.data) {
+		return errGeoTIFFParse
+	}
+	r.fields = make(map[int]tiffField, n)
+	for i := 0; i < n; i++ {
+		entry := r.data[offset+12*i : offset+12*(i+1)]
+		f := tiffField{
+			fieldType: int(r.byteOrder.Uint16(entry[2:])),
+			count:     int(r.byteOrder.Uint32(entry[4:])),
+		}
+		typeSize := tiffTypeSize(f.fieldType)
+		if typeSize == 0 {
+			return errGeoTIFFParse
+		}
+		size := typeSize * f.count
+		if size <= 4 {
+			f.data = entry[8 : 8+size]
+		} else {
+			valueOffset := int(r.byteOrder.Uint32(entry[8:]))
+			if size < 0 || valueOffset < 0 || valueOffset+size > len(r.data) {
+				return errGeoTIFFParse
+			}
+			f.data = r.data[valueOffset : valueOffset+size]
+		}
+		r.fields[int(r.byteOrder.Uint16(entry))] = f
+	}
+	return nil
+}
+
+// ints returns the values of the integer field tag.
+func (r *tiffReader) ints(tag int) ([]int, bool) {
+	f, ok := r.fields[tag]
+	if !ok || !f.complete() {
+		return nil, false
+	}
+	values := make([]int, 0, f.count)
+	for i := 0; i < f.count; i++ {
+		switch f.fieldType {
+		case tiffByte, tiffUndefined:
+			values = append(values, int(f.data[i]))
+		case tiffSByte:
+			values = append(values, int(int8(f.data[i])))
+		case tiffShort:
+			values = append(values, int(r.byteOrder.Uint16(f.data[2*i:])))
+		case tiffSShort:
+			values = append(values, int(int16(r.byteOrder.Uint16(f.data[2*i:]))))
+		case tiffLong:
+			values = append(values, int(r.byteOrder.Uint32(f.data[4*i:])))
+		case tiffSLong:
+			values = append(values, int(int32(r.byteOrder.Uint32(f.data[4*i:]))))
+		default:
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+// int returns the first value of the integer field tag, or defaultValue if
+// it is not present.
+func (r *tiffReader) int(tag, defaultValue int) (int, error) {
+	if _, ok := r.fields[tag]; !ok {
+		return defaultValue, nil
+	}
+	values, ok := r.ints(tag)
+	if !ok || len(values) == 0 {
+		return 0, errGeoTIFFParse
+	}
+	return values[0], nil
+}
+
+// floats returns the values of the numeric field tag.
+func (r *tiffReader) floats(tag int) ([]float64, bool) {
+	f, ok := r.fields[tag]
+	if !ok || !f.complete() {
+		return nil, false
+	}
+	values := make([]float64, 0, f.count)
+	switch f.fieldType {
+	case tiffFloat:
+		for i := 0; i < f.count; i++ {
+			values = append(values, float64(math.Float32frombits(r.byteOrder.Uint32(f.data[4*i:]))))
+		}
+	case tiffDouble:
+		for i := 0; i < f.count; i++ {
+			values = append(values, math.Float64frombits(r.byteOrder.Uint64(f.data[8*i:])))
+		}
+	default:
+		ints, ok := r.ints(tag)
+		if !ok {
+			return nil, false
+		}
+		for _, x := range ints {
+			values = append(values, float64(x))
+		}
+	}
+	return values, true
+}
+
+// ascii returns the value of the ASCII field tag.
+func (r *tiffReader) ascii(tag int) (string, bool) {
+	f, ok := r.fields[tag]
+	if !ok || f.fieldType != tiffASCII {
+		return "", false
+	}
+	return string(f.data), true
+}
+
+// raster returns the values of the image.
+func (r *tiffReader) raster() ([][]float64, error) {
+	width, err := r.int(tiffTagImageWidth, 0)
+	if err != nil {
+		return nil, err
+	}
+	height, err := r.int(tiffTagImageLength, 0)
+	if err != nil {
+		return nil, err
+	}
+	bitsPerSample, err := r.int(tiffTagBitsPerSample, 1)
+	if err != nil {
+		return nil, err
+	}
+	samplesPerPixel, err := r.int(tiffTagSamplesPerPixel, 1)
+	if err != nil {
+		return nil, err
+	}
+	sampleFormat, err := r.int(tiffTagSampleFormat, tiffSampleFormatUnsigned)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := r.int(tiffTagCompression, tiffCompressionNone)
+	if err != nil {
+		return nil, err
+	}
+	predictor, err := r.int(tiffTagPredictor, tiffPredictorNone)
+	if err != nil {
+		return nil, err
+	}
+	if width <= 0 || height <= 0 || bitsPerSample <= 0 {
+		return nil, errGeoTIFFParse
+	}
+	switch {
+	case samplesPerPixel != 1:
+		return nil, fmt.Errorf("%d samples per pixel: %w", samplesPerPixel, ErrGeoTIFFUnsupported)
+	case bitsPerSample%8 != 0 || bitsPerSample > 64:
+		return nil, fmt.Errorf("%d bits per sample: %w", bitsPerSample, ErrGeoTIFFUnsupported)
+	case compression != tiffCompressionNone && compression != tiffCompressionDeflate && compression != tiffCompressionAdobeDeflate:
+		return nil, fmt.Errorf("compression %d: %w", compression, ErrGeoTIFFUnsupported)
+	case predictor < tiffPredictorNone || predictor > tiffPredictorFloatingPoint:
+		return nil, fmt.Errorf("predictor %d: %w", predictor, ErrGeoTIFFUnsupported)
+	}
+	bytesPerSample := bitsPerSample / 8
+	if n, ok := product([]int{width, height, bytesPerSample}); !ok || n/tiffMaxCompressionRatio > len(r.data) {
+		return nil, errGeoTIFFParse
+	}
+
+	// Strips are treated as tiles that span the width of the image.
+	tileWidth, tileHeight := width, height
+	offsets, ok := r.ints(tiffTagTileOffsets)
+	byteCounts, _ := r.ints(tiffTagTileByteCounts)
+	if ok {
+		if tileWidth, err = r.int(tiffTagTileWidth, 0); err != nil {
+			return nil, err
+		}
+		if tileHeight, err = r.int(tiffTagTileLength, 0); err != nil {
+			return nil, err
+		}
+	} else {
+		if offsets, ok = r.ints(tiffTagStripOffsets); !ok {
+			return nil, errGeoTIFFParse
+		}
+		byteCounts, _ = r.ints(tiffTagStripByteCounts)
+		if tileHeight, err = r.int(tiffTagRowsPerStrip, height); err != nil {
+			return nil, err
+		}
+		if tileHeight > height {
+			tileHeight = height
+		}
+	}
+	if tileWidth <= 0 || tileHeight <= 0 {
+		return nil, errGeoTIFFParse
+	}
+	tilesAcross := (width + tileWidth - 1) / tileWidth
+	tilesDown := (height + tileHeight - 1) / tileHeight
+	if len(offsets) != tilesAcross*tilesDown || len(byteCounts) != len(offsets) {
+		return nil, errGeoTIFFParse
+	}
+
+	noData := math.NaN()
+	if s, ok := r.ascii(tiffTagGDALNoData); ok {
+		if x, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimRight(s, "\x00")), 64); err == nil {
+			noData = x
+		}
+	}
+
+	values := make([][]float64, height)
+	for i := range values {
+		values[i] = make([]float64, width)
+	}
+	rowSize := tileWidth * bytesPerSample
+	tileSize, ok := product([]int{tileHeight, rowSize})
+	if !ok || tileSize/tiffMaxCompressionRatio > len(r.data) {
+		return nil, errGeoTIFFParse
+	}
+	for t, offset := range offsets {
+		if offset < 0 || offset+byteCounts[t] > len(r.data) {
+			return nil, errGeoTIFFParse
+		}
+		tile := r.data[offset : offset+byteCounts[t]]
+		if compression != tiffCompressionNone {
+			zr, err := zlib.NewReader(bytes.NewReader(tile))
+			if err != nil {
+				return nil, err
+			}
+			tile, err = ioutil.ReadAll(io.LimitReader(zr, int64(tileSize)+1))
+			if err != nil {
+				return nil, err
+			}
+			if len(tile) > tileSize {
+				return nil, errGeoTIFFParse
+			}
+		} else if predictor != tiffPredictorNone {
+			// Predictors are reversed in place, so copy the tile to avoid
+			// modifying data.
+			tile = append([]byte(nil), tile...)
+		}
+		tileRow0, tileCol0 := t/tilesAcross*tileHeight, t%tilesAcross*tileWidth
+		for i := 0; i < tileHeight && tileRow0+i < height; i++ {
+			if (i+1)*rowSize > len(tile) {
+				return nil, errGeoTIFFParse
+			}
+			row := tile[i*rowSize : (i+1)*rowSize]
+			byteOrder := r.byteOrder
+			switch predictor {
+			case tiffPredictorHorizontal:
+				undoHorizontalPredictor(row, bytesPerSample, r.byteOrder)
+			case tiffPredictorFloatingPoint:
+				row = undoFloatingPointPredictor(row, bytesPerSample)
+				byteOrder = binary.BigEndian
+			}
+			for j := 0; j < tileWidth && tileCol0+j < width; j++ {
+				value, err := decodeTIFFSample(row[j*bytesPerSample:(j+1)*bytesPerSample], sampleFormat, byteOrder)
+				if err != nil {
+					return nil, err
+				}
+				if value == noData {
+					value = math.NaN()
+				}
+				values[tileRow0+i][tileCol0+j] = value
+			}
+		}
+	}
+	return values, nil
+}
+
+// geoKeys returns the GeoKeys.
+func (r *tiffReader) geoKeys() (map[int]interface{}, error) {
+	directory, ok := r.ints(tiffTagGeoKeyDirectory)
+	if !ok {
+		return nil, nil
+	}
+	if len(directory) < 4 || len(directory) < 4+4*directory[3] {
+		return nil, errGeoTIFFParse
+	}
+	doubles, _ := r.floats(tiffTagGeoDoubleParams)
+	ascii, _ := r.ascii(tiffTagGeoASCIIParams)
+	geoKeys := make(map[int]interface{}, directory[3])
+	for i := 1; i <= directory[3]; i++ {
+		key, location, count, valueOffset := directory[4*i], directory[4*i+1], directory[4*i+2], directory[4*i+3]
+		switch location {
+		case 0:
+			geoKeys[key] = valueOffset
+		case tiffTagGeoKeyDirectory:
+			if count < 1 || valueOffset >= len(directory) {
+				return nil, errGeoTIFFParse
+			}
+			geoKeys[key] = directory[valueOffset]
+		case tiffTagGeoDoubleParams:
+			if valueOffset+count > len(doubles) {
+				return nil, errGeoTIFFParse
+			}
+			geoKeys[key] = doubles[valueOffset : valueOffset+count]
+		case tiffTagGeoASCIIParams:
+			if valueOffset+count > len(ascii) {
+				return nil, errGeoTIFFParse
+			}
+			geoKeys[key] = strings.TrimRight(ascii[valueOffset:valueOffset+count], "|\x00")
+		}
+	}
+	return geoKeys, nil
+}
+
+// geoTransform returns the geotransform defined by the model transformation
+// tag, or by the model tie point and pixel scale tags. It returns the zero
+// geotransform if there is none.
+func (r *tiffReader) geoTransform() ([6]float64, error) {
+	if m, ok := r.floats(tiffTagModelTransformation); ok {
+		if len(m) != 16 {
+			return [6]float64{}, errGeoTIFFParse
+		}
+		return [6]float64{m[3], m[0], m[1], m[7], m[4], m[5]}, nil
+	}
+	tiePoints, ok := r.floats(tiffTagModelTiepoint)
+	if !ok {
+		return [6]float64{}, nil
+	}
+	scale, ok := r.floats(tiffTagModelPixelScale)
+	if !ok || len(tiePoints) < 6 || len(scale) < 2 {
+		return [6]float64{}, errGeoTIFFParse
+	}
+	i, j, x, y := tiePoints[0], tiePoints[1], tiePoints[3], tiePoints[4]
+	return [6]float64{x - i*scale[0], scale[0], 0, y + j*scale[1], 0, -scale[1]}, nil
+}
+
+// locationGeoTransform returns the geotransform of a RectangleN or
+// RectangleRes, whose grid points are the centers of the pixels.
+func locationGeoTransform(ls LocationStringer) ([6]float64, error) {
+	var min, max Point
+	var resLat, resLon float64
+	switch l := ls.(type) {
+	case RectangleN:
+		min, max = l.Min, l.Max
+		resLat, resLon = resolution(l.Min.Lat, l.Max.Lat, l.NLat), resolution(l.Min.Lon, l.Max.Lon, l.NLon)
+	case RectangleRes:
+		min, max = l.Min, l.Max
+		resLat, resLon = l.ResLat, l.ResLon
+	default:
+		return [6]float64{}, fmt.Errorf("%s: %w", ls.LocationString(), ErrGeoTIFFUnsupported)
+	}
+	return [6]float64{min.Lon - resLon/2, resLon, 0, max.Lat + resLat/2, 0, -resLat}, nil
+}
+
+// undoHorizontalPredictor reverses horizontal differencing of the integer
+// samples in row.
+func undoHorizontalPredictor(row []byte, bytesPerSample int, byteOrder binary.ByteOrder) {
+	switch bytesPerSample {
+	case 1:
+		for i := 1; i < len(row); i++ {
+			row[i] += row[i-1]
+		}
+	case 2:
+		for i := 2; i+2 <= len(row); i += 2 {
+			byteOrder.PutUint16(row[i:], byteOrder.Uint16(row[i:])+byteOrder.Uint16(row[i-2:]))
+		}
+	case 4:
+		for i := 4; i+4 <= len(row); i += 4 {
+			byteOrder.PutUint32(row[i:], byteOrder.Uint32(row[i:])+byteOrder.Uint32(row[i-4:]))
+		}
+	case 8:
+		for i := 8; i+8 <= len(row); i += 8 {
+			byteOrder.PutUint64(row[i:], byteOrder.Uint64(row[i:])+byteOrder.Uint64(row[i-8:]))
+		}
+	}
+}
+
+// undoFloatingPointPredictor reverses the floating point predictor on row and
+// returns the samples in big-endian byte order.
+func undoFloatingPointPredictor(row []byte, bytesPerSample int) []byte {
+	for i := 1; i < len(row); i++ {
+		row[i] += row[i-1]
+	}
+	n := len(row) / bytesPerSample
+	result := make([]byte, len(row))
+	for i := 0; i < n; i++ {
+		for b := 0; b < bytesPerSample; b++ {
+			result[i*bytesPerSample+b] = row[b*n+i]
+		}
+	}
+	return result
+}
+
+// decodeTIFFSample decodes a single sample.
+func decodeTIFFSample(b []byte, sampleFormat int, byteOrder binary.ByteOrder) (float64, error) {
+	switch {
+	case sampleFormat == tiffSampleFormatFloatingType && len(b) == 4:
+		return float64(math.Float32frombits(byteOrder.Uint32(b))), nil
+	case sampleFormat == tiffSampleFormatFloatingType && len(b) == 8:
+		return math.Float64frombits(byteOrder.Uint64(b)), nil
+	case sampleFormat == tiffSampleFormatFloatingType:
+		return 0, fmt.Errorf("%d byte float: %w", len(b), ErrGeoTIFFUnsupported)
+	}
+	var x uint64
+	switch len(b) {
+	case 1:
+		x = uint64(b[0])
+	case 2:
+		x = uint64(byteOrder.Uint16(b))
+	case 4:
+		x = uint64(byteOrder.Uint32(b))
+	case 8:
+		x = byteOrder.Uint64(b)
+	default:
+		return 0, fmt.Errorf("%d byte integer: %w", len(b), ErrGeoTIFFUnsupported)
+	}
+	switch sampleFormat {
+	case tiffSampleFormatUnsigned:
+		return float64(x), nil
+	case tiffSampleFormatSigned:
+		shift := uint(64 - 8*len(b))
+		return float64(int64(x<<shift) >> shift), nil
+	default:
+		return 0, fmt.Errorf("sample format %d: %w", sampleFormat, ErrGeoTIFFUnsupported)
+	}
+}
+
+// tiffTypeSize returns the size in bytes of a TIFF field type.
+func tiffTypeSize(fieldType int) int {
+	switch fieldType {
+	case tiffByte, tiffASCII, tiffSByte, tiffUndefined:
+		return 1
+	case tiffShort, tiffSShort:
+		return 2
+	case tiffLong, tiffSLong, tiffFloat:
+		return 4
+	case tiffRational, tiffSRational, tiffDouble:
+		return 8
+	default:
+		return 0
+	}
+}
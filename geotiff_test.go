@@ -0,0 +1,352 @@
+package meteomatics
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A testTIFFField is a field to be encoded by encodeTestTIFF. value is a
+// []uint16, []uint32, []float64, or string.
+type testTIFFField struct {
+	tag   uint16
+	value interface{}
+}
+
+// encodeTestTIFF encodes a TIFF file with a single image file directory with
+// fields, followed by chunks. The offsets of chunks are written to the field
+// offsetsTag.
+func encodeTestTIFF(t *testing.T, byteOrder binary.ByteOrder, fields []testTIFFField, offsetsTag uint16, chunks [][]byte) []byte {
+	t.Helper()
+
+	header := []byte("II\x2a\x00\x08\x00\x00\x00")
+	if byteOrder == binary.BigEndian {
+		header = []byte("MM\x00\x2a\x00\x00\x00\x08")
+	}
+
+	byteCounts := make([]uint32, 0, len(chunks))
+	for _, chunk := range chunks {
+		byteCounts = append(byteCounts, uint32(len(chunk)))
+	}
+	byteCountsTag := uint16(tiffTagStripByteCounts)
+	if offsetsTag == tiffTagTileOffsets {
+		byteCountsTag = tiffTagTileByteCounts
+	}
+	fields = append(fields,
+		testTIFFField{tag: offsetsTag, value: make([]uint32, len(chunks))},
+		testTIFFField{tag: byteCountsTag, value: byteCounts},
+	)
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].tag < fields[j].tag
+	})
+
+	encode := func(offsets []uint32) []byte {
+		ifdSize := 2 + 12*len(fields) + 4
+		extra := &bytes.Buffer{}
+		ifd := &bytes.Buffer{}
+		require.NoError(t, binary.Write(ifd, byteOrder, uint16(len(fields))))
+		for _, f := range fields {
+			value := f.value
+			if f.tag == offsetsTag {
+				value = offsets
+			}
+			var fieldType, count uint16
+			data := &bytes.Buffer{}
+			switch v := value.(type) {
+			case []uint16:
+				fieldType, count = tiffShort, uint16(len(v))
+			case []uint32:
+				fieldType, count = tiffLong, uint16(len(v))
+			case []float64:
+				fieldType, count = tiffDouble, uint16(len(v))
+			case string:
+				fieldType, count = tiffASCII, uint16(len(v)+1)
+				value = append([]byte(v), 0)
+			}
+			require.NoError(t, binary.Write(data, byteOrder, value))
+			require.NoError(t, binary.Write(ifd, byteOrder, f.tag))
+			require.NoError(t, binary.Write(ifd, byteOrder, fieldType))
+			require.NoError(t, binary.Write(ifd, byteOrder, uint32(count)))
+			if data.Len() <= 4 {
+				ifd.Write(data.Bytes())
+				ifd.Write(make([]byte, 4-data.Len()))
+			} else {
+				require.NoError(t, binary.Write(ifd, byteOrder, uint32(len(header)+ifdSize+extra.Len())))
+				extra.Write(data.Bytes())
+			}
+		}
+		require.NoError(t, binary.Write(ifd, byteOrder, uint32(0)))
+		return append(append(append([]byte(nil), header...), ifd.Bytes()...), extra.Bytes()...)
+	}
+
+	offsets := make([]uint32, len(chunks))
+	offset := uint32(len(encode(offsets)))
+	for i, chunk := range chunks {
+		offsets[i] = offset
+		offset += uint32(len(chunk))
+	}
+	return append(encode(offsets), bytes.Join(chunks, nil)...)
+}
+
+// testTIFFFloats returns values encoded as float32s.
+func testTIFFFloats(t *testing.T, byteOrder binary.ByteOrder, values ...float32) []byte {
+	b := &bytes.Buffer{}
+	require.NoError(t, binary.Write(b, byteOrder, values))
+	return b.Bytes()
+}
+
+func TestParseGeoTIFFStrips(t *testing.T) {
+	data := encodeTestTIFF(t, binary.LittleEndian, []testTIFFField{
+		{tag: tiffTagImageWidth, value: []uint32{3}},
+		{tag: tiffTagImageLength, value: []uint32{2}},
+		{tag: tiffTagBitsPerSample, value: []uint16{32}},
+		{tag: tiffTagRowsPerStrip, value: []uint32{1}},
+		{tag: tiffTagSampleFormat, value: []uint16{tiffSampleFormatFloatingType}},
+		{tag: tiffTagModelPixelScale, value: []float64{5, 10, 0}},
+		{tag: tiffTagModelTiepoint, value: []float64{0, 0, 0, 7.5, 55, 0}},
+		{tag: tiffTagGeoKeyDirectory, value: []uint16{
+			1, 1, 0, 3,
+			1024, 0, 1, 2,
+			1025, 0, 1, 1,
+			2049, tiffTagGeoASCIIParams, 7, 0,
+		}},
+		{tag: tiffTagGeoASCIIParams, value: "WGS 84|"},
+		{tag: tiffTagGDALNoData, value: "-999"},
+	}, tiffTagStripOffsets, [][]byte{
+		testTIFFFloats(t, binary.LittleEndian, 1.5, 2.5, 3.5),
+		testTIFFFloats(t, binary.LittleEndian, 4.5, 5.5, -999),
+	})
+
+	r, err := ParseGeoTIFF(data)
+	require.NoError(t, err)
+	assert.Equal(t, 3, r.Width)
+	assert.Equal(t, 2, r.Height)
+	assert.Equal(t, []float64{1.5, 2.5, 3.5}, r.Values[0])
+	assert.Equal(t, []float64{4.5, 5.5}, r.Values[1][:2])
+	assert.True(t, math.IsNaN(r.Values[1][2]))
+	assert.Equal(t, [6]float64{7.5, 5, 0, 55, 0, -10}, r.GeoTransform)
+	assert.Equal(t, map[int]interface{}{
+		1024: 2,
+		1025: 1,
+		2049: "WGS 84",
+	}, r.GeoKeys)
+	assert.Equal(t, Point{Lat: 50, Lon: 10}, r.Point(0, 0))
+	assert.Equal(t, Point{Lat: 40, Lon: 20}, r.Point(2, 1))
+}
+
+func TestParseGeoTIFFTiles(t *testing.T) {
+	// Encode 2x2 tiles with deflate compression and the floating point
+	// predictor.
+	encodeTile := func(values ...float32) []byte {
+		raw := testTIFFFloats(t, binary.BigEndian, values...)
+		const width, bytesPerSample = 2, 4
+		b := &bytes.Buffer{}
+		w := zlib.NewWriter(b)
+		for row := 0; row < len(raw)/(width*bytesPerSample); row++ {
+			rowBytes := raw[row*width*bytesPerSample : (row+1)*width*bytesPerSample]
+			planes := make([]byte, len(rowBytes))
+			for i := 0; i < width; i++ {
+				for j := 0; j < bytesPerSample; j++ {
+					planes[j*width+i] = rowBytes[i*bytesPerSample+j]
+				}
+			}
+			for i := len(planes) - 1; i > 0; i-- {
+				planes[i] -= planes[i-1]
+			}
+			_, err := w.Write(planes)
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Close())
+		return b.Bytes()
+	}
+
+	data := encodeTestTIFF(t, binary.BigEndian, []testTIFFField{
+		{tag: tiffTagImageWidth, value: []uint32{3}},
+		{tag: tiffTagImageLength, value: []uint32{2}},
+		{tag: tiffTagBitsPerSample, value: []uint16{32}},
+		{tag: tiffTagCompression, value: []uint16{tiffCompressionDeflate}},
+		{tag: tiffTagPredictor, value: []uint16{tiffPredictorFloatingPoint}},
+		{tag: tiffTagTileWidth, value: []uint32{2}},
+		{tag: tiffTagTileLength, value: []uint32{2}},
+		{tag: tiffTagSampleFormat, value: []uint16{tiffSampleFormatFloatingType}},
+		{tag: tiffTagModelPixelScale, value: []float64{5, 10, 0}},
+		{tag: tiffTagModelTiepoint, value: []float64{0, 0, 0, 10, 50, 0}},
+		{tag: tiffTagGeoKeyDirectory, value: []uint16{
+			1, 1, 0, 1,
+			1025, 0, 1, 2,
+		}},
+	}, tiffTagTileOffsets, [][]byte{
+		encodeTile(1.5, 2.5, 4.5, 5.5),
+		encodeTile(3.5, 0, 6.5, 0),
+	})
+
+	r, err := ParseGeoTIFF(data)
+	require.NoError(t, err)
+	assert.Equal(t, [][]float64{{1.5, 2.5, 3.5}, {4.5, 5.5, 6.5}}, r.Values)
+	assert.Equal(t, [6]float64{7.5, 5, 0, 55, 0, -10}, r.GeoTransform)
+	assert.Equal(t, Point{Lat: 50, Lon: 10}, r.Point(0, 0))
+}
+
+func TestParseGeoTIFFErrors(t *testing.T) {
+	_, err := ParseGeoTIFF([]byte("GIF89a"))
+	assert.True(t, errors.Is(err, errGeoTIFFParse))
+
+	data := encodeTestTIFF(t, binary.LittleEndian, []testTIFFField{
+		{tag: tiffTagImageWidth, value: []uint32{1}},
+		{tag: tiffTagImageLength, value: []uint32{1}},
+		{tag: tiffTagBitsPerSample, value: []uint16{8}},
+		{tag: tiffTagCompression, value: []uint16{5}},
+	}, tiffTagStripOffsets, [][]byte{{0}})
+	_, err = ParseGeoTIFF(data)
+	assert.True(t, errors.Is(err, ErrGeoTIFFUnsupported))
+
+	for _, tc := range []struct {
+		name   string
+		fields []testTIFFField
+	}{
+		{
+			name: "zero_bits_per_sample",
+			fields: []testTIFFField{
+				{tag: tiffTagBitsPerSample, value: []uint16{0}},
+				{tag: tiffTagPredictor, value: []uint16{tiffPredictorFloatingPoint}},
+			},
+		},
+		{
+			name: "geo_key_zero_count",
+			fields: []testTIFFField{
+				{tag: tiffTagBitsPerSample, value: []uint16{8}},
+				{tag: tiffTagGeoKeyDirectory, value: []uint16{1, 1, 0, 1, 1024, tiffTagGeoKeyDirectory, 0, 8}},
+			},
+		},
+		{
+			name: "geo_key_offset_out_of_range",
+			fields: []testTIFFField{
+				{tag: tiffTagBitsPerSample, value: []uint16{8}},
+				{tag: tiffTagGeoKeyDirectory, value: []uint16{1, 1, 0, 1, 1024, tiffTagGeoKeyDirectory, 1, 8}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fields := append([]testTIFFField{
+				{tag: tiffTagImageWidth, value: []uint32{1}},
+				{tag: tiffTagImageLength, value: []uint32{1}},
+			}, tc.fields...)
+			data := encodeTestTIFF(t, binary.LittleEndian, fields, tiffTagStripOffsets, [][]byte{{0, 0, 0, 0}})
+			_, err := ParseGeoTIFF(data)
+			assert.True(t, errors.Is(err, errGeoTIFFParse))
+		})
+	}
+
+	// The first field, ImageWidth, has an unknown type and a huge count.
+	unknownType := encodeTestTIFF(t, binary.LittleEndian, []testTIFFField{
+		{tag: tiffTagImageWidth, value: []uint32{1}},
+		{tag: tiffTagImageLength, value: []uint32{1}},
+		{tag: tiffTagBitsPerSample, value: []uint16{8}},
+	}, tiffTagStripOffsets, [][]byte{{0}})
+	binary.LittleEndian.PutUint16(unknownType[12:], 13)
+	binary.LittleEndian.PutUint32(unknownType[14:], math.MaxUint32)
+	_, err = ParseGeoTIFF(unknownType)
+	assert.True(t, errors.Is(err, errGeoTIFFParse))
+
+	// A strip that decompresses to more than one row.
+	b := &bytes.Buffer{}
+	zw := zlib.NewWriter(b)
+	_, err = zw.Write(make([]byte, 1<<20))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	_, err = ParseGeoTIFF(encodeTestTIFF(t, binary.LittleEndian, []testTIFFField{
+		{tag: tiffTagImageWidth, value: []uint32{1}},
+		{tag: tiffTagImageLength, value: []uint32{1}},
+		{tag: tiffTagBitsPerSample, value: []uint16{8}},
+		{tag: tiffTagCompression, value: []uint16{tiffCompressionDeflate}},
+	}, tiffTagStripOffsets, [][]byte{b.Bytes()}))
+	assert.True(t, errors.Is(err, errGeoTIFFParse))
+}
+
+func TestParseGeoTIFFMalformed(t *testing.T) {
+	data := encodeTestTIFF(t, binary.LittleEndian, []testTIFFField{
+		{tag: tiffTagImageWidth, value: []uint32{2}},
+		{tag: tiffTagImageLength, value: []uint32{1}},
+		{tag: tiffTagBitsPerSample, value: []uint16{32}},
+		{tag: tiffTagPredictor, value: []uint16{tiffPredictorFloatingPoint}},
+		{tag: tiffTagSampleFormat, value: []uint16{tiffSampleFormatFloatingType}},
+		{tag: tiffTagGeoKeyDirectory, value: []uint16{
+			1, 1, 0, 2,
+			1024, tiffTagGeoKeyDirectory, 1, 1,
+			2049, tiffTagGeoASCIIParams, 7, 0,
+		}},
+		{tag: tiffTagGeoASCIIParams, value: "WGS 84|"},
+	}, tiffTagStripOffsets, [][]byte{make([]byte, 8)})
+	_, err := ParseGeoTIFF(data)
+	require.NoError(t, err)
+
+	// Corrupting any byte must not panic.
+	for i := range data {
+		for _, b := range []byte{0x00, 0x01, 0x80, 0xff} {
+			corrupted := append([]byte(nil), data...)
+			corrupted[i] = b
+			assert.NotPanics(t, func() {
+				_, _ = ParseGeoTIFF(corrupted)
+			})
+		}
+	}
+	for n := range data {
+		assert.NotPanics(t, func() {
+			_, _ = ParseGeoTIFF(data[:n])
+		})
+	}
+}
+
+func TestClientRequestGeoTIFF(t *testing.T) {
+	data := encodeTestTIFF(t, binary.LittleEndian, []testTIFFField{
+		{tag: tiffTagImageWidth, value: []uint32{3}},
+		{tag: tiffTagImageLength, value: []uint32{2}},
+		{tag: tiffTagBitsPerSample, value: []uint16{16}},
+		{tag: tiffTagSampleFormat, value: []uint16{tiffSampleFormatSigned}},
+	}, tiffTagStripOffsets, [][]byte{
+		{0x01, 0x00, 0x02, 0x00, 0x03, 0x00, 0xff, 0xff, 0xfe, 0xff, 0xfd, 0xff},
+	})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2016-12-19T12:00:00Z/t_2m:C/50,10_40,20:3x2/geotiff", r.URL.Path)
+		_, _ = w.Write(data)
+	}))
+	defer s.Close()
+
+	r, err := NewClient(WithBaseURL(s.URL)).RequestGeoTIFF(
+		context.Background(),
+		Time(time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC)),
+		Parameter{
+			Name:  ParameterTemperature,
+			Level: LevelMeters(2),
+			Units: UnitsCelsius,
+		},
+		RectangleN{
+			Min: Point{
+				Lat: 40,
+				Lon: 10,
+			},
+			Max: Point{
+				Lat: 50,
+				Lon: 20,
+			},
+			NLon: 3,
+			NLat: 2,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, [][]float64{{1, 2, 3}, {-1, -2, -3}}, r.Values)
+	assert.Equal(t, [6]float64{7.5, 5, 0, 55, 0, -10}, r.GeoTransform)
+	assert.Equal(t, Point{Lat: 40, Lon: 20}, r.Point(2, 1))
+}
@@ -0,0 +1,664 @@
+package meteomatics
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// GRIB2 errors.
+var (
+	ErrGRIB2Unsupported = errors.New("unsupported GRIB2 template")
+	errGRIB2Parse       = errors.New("grib2 parse error")
+)
+
+// GRIB2 scanning mode flags.
+const (
+	grib2ScanNegativeI  = 0x80
+	grib2ScanPositiveJ  = 0x40
+	grib2ScanJAdjacent  = 0x20
+	grib2ScanAlternate  = 0x10
+	grib2MissingDefault = 0xffffffff
+)
+
+//nolint:gochecknoglobals
+var (
+	grib2Magic = []byte("GRIB")
+	grib2End   = []byte("7777")
+)
+
+// A GRIB2Parameter identifies a GRIB2 parameter by its discipline, category,
+// and number in the WMO code tables.
+type GRIB2Parameter struct {
+	Discipline int
+	Category   int
+	Number     int
+}
+
+// A GRIB2Level is a GRIB2 fixed surface. Type is from WMO code table 4.5.
+type GRIB2Level struct {
+	Type  int
+	Value float64
+}
+
+// A GRIB2Message is a field decoded from a GRIB2 message. Values are indexed by
+// lat then lon, Lats are sorted from north to south and Lons from west to
+// east, and missing values are NaN.
+type GRIB2Message struct {
+	Parameter     GRIB2Parameter
+	Level         GRIB2Level
+	ReferenceTime time.Time
+	ValidTime     time.Time
+	Lats          []float64
+	Lons          []float64
+	Values        [][]float64
+}
+
+// A grib2Grid is a regular lat/lon grid from a grid definition section.
+type grib2Grid struct {
+	ni, nj       int
+	la1, lo1     float64
+	la2, lo2     float64
+	scanningMode byte
+}
+
+// A grib2Packing is a data representation section.
+type grib2Packing struct {
+	template        int
+	n               int
+	r               float64
+	e               int
+	d               int
+	nBits           int
+	missingMgmt     int
+	ng              int
+	widthRef        int
+	widthBits       int
+	lengthRef       int
+	lengthIncrement int
+	lastLength      int
+	lengthBits      int
+	order           int
+	extraOctets     int
+}
+
+// A bitReader reads big-endian bit fields.
+type bitReader struct {
+	data []byte
+	bit  int
+}
+
+// RequestGRIB2 requests a forecast in GRIB2 format. Only regular lat/lon grids
+// with simple or complex packing are supported.
+func (c *Client) RequestGRIB2(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) ([]*GRIB2Message, error) {
+	data, err := c.Request(ctx, ts, ps, ls, FormatGRIB2, options)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGRIB2(data)
+}
+
+// ParseGRIB2 parses the GRIB2 messages in data. Each field in each message is
+// returned as a separate GRIB2Message.
+func ParseGRIB2(data []byte) ([]*GRIB2Message, error) {
+	var messages []*GRIB2Message
+	for len(data) > 0 {
+		i := bytes.Index(data, grib2Magic)
+		if i == -1 {
+			break
+		}
+		data = data[i:]
+		if len(data) < 16 || data[7] != 2 {
+			return nil, errGRIB2Parse
+		}
+		length := binary.BigEndian.Uint64(data[8:16])
+		if length < 16 || length > uint64(len(data)) {
+			return nil, errGRIB2Parse
+		}
+		fields, err := parseGRIB2Message(data[:length])
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, fields...)
+		data = data[length:]
+	}
+	if len(messages) == 0 {
+		return nil, errGRIB2Parse
+	}
+	return messages, nil
+}
+
+// parseGRIB2Message parses the fields in a single GRIB2 message.
+func parseGRIB2Message(data []byte) ([]*GRIB2Message, error) {
+	discipline := int(data[6])
+	var (
+		fields        []*GRIB2Message
+		referenceTime time.Time
+		grid          *grib2Grid
+		parameter     GRIB2Parameter
+		level         GRIB2Level
+		validTime     time.Time
+		packing       *grib2Packing
+		bitmap        []byte
+	)
+	offset := 16
+	for {
+		if offset+4 > len(data) {
+			return nil, errGRIB2Parse
+		}
+		if bytes.Equal(data[offset:offset+4], grib2End) {
+			return fields, nil
+		}
+		if offset+5 > len(data) {
+			return nil, errGRIB2Parse
+		}
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		if length < 5 || offset+length > len(data) {
+			return nil, errGRIB2Parse
+		}
+		section := data[offset : offset+length]
+		var err error
+		switch section[4] {
+		case 1:
+			referenceTime, err = parseGRIB2Identification(section)
+		case 2:
+		case 3:
+			grid, err = parseGRIB2Grid(section)
+		case 4:
+			parameter, level, validTime, err = parseGRIB2Product(section, discipline, referenceTime)
+		case 5:
+			packing, err = parseGRIB2Packing(section)
+		case 6:
+			bitmap, err = parseGRIB2Bitmap(section, bitmap)
+		case 7:
+			if grid == nil || packing == nil {
+				return nil, errGRIB2Parse
+			}
+			var values []float64
+			values, err = packing.unpack(section[5:], bitmap, grid.ni*grid.nj)
+			if err != nil {
+				return nil, err
+			}
+			field := &GRIB2Message{
+				Parameter:     parameter,
+				Level:         level,
+				ReferenceTime: referenceTime,
+				ValidTime:     validTime,
+			}
+			field.Lats, field.Lons, field.Values = grid.grid(values)
+			fields = append(fields, field)
+		default:
+			err = errGRIB2Parse
+		}
+		if err != nil {
+			return nil, err
+		}
+		offset += length
+	}
+}
+
+// parseGRIB2Identification parses an identification section and returns the
+// reference time.
+func parseGRIB2Identification(section []byte) (time.Time, error) {
+	if len(section) < 21 {
+		return time.Time{}, errGRIB2Parse
+	}
+	return grib2Time(section[12:19]), nil
+}
+
+// parseGRIB2Grid parses a grid definition section.
+func parseGRIB2Grid(section []byte) (*grib2Grid, error) {
+	if len(section) < 14 {
+		return nil, errGRIB2Parse
+	}
+	if template := binary.BigEndian.Uint16(section[12:]); template != 0 {
+		return nil, fmt.Errorf("grid definition template 3.%d: %w", template, ErrGRIB2Unsupported)
+	}
+	if len(section) < 72 {
+		return nil, errGRIB2Parse
+	}
+	basicAngle := binary.BigEndian.Uint32(section[38:])
+	subdivisions := binary.BigEndian.Uint32(section[42:])
+	unit := 1e-6
+	if basicAngle != 0 && basicAngle != grib2MissingDefault && subdivisions != 0 && subdivisions != grib2MissingDefault {
+		unit = float64(basicAngle) / float64(subdivisions)
+	}
+	g := &grib2Grid{
+		ni:           int(binary.BigEndian.Uint32(section[30:])),
+		nj:           int(binary.BigEndian.Uint32(section[34:])),
+		la1:          float64(grib2Int(section[46:50])) * unit,
+		lo1:          float64(grib2Int(section[50:54])) * unit,
+		la2:          float64(grib2Int(section[55:59])) * unit,
+		lo2:          float64(grib2Int(section[59:63])) * unit,
+		scanningMode: section[71],
+	}
+	if g.ni <= 0 || g.nj <= 0 || g.scanningMode&grib2ScanAlternate != 0 {
+		return nil, errGRIB2Parse
+	}
+	if _, ok := product([]int{g.ni, g.nj}); !ok {
+		return nil, errGRIB2Parse
+	}
+	return g, nil
+}
+
+// parseGRIB2Product parses a product definition section.
+func parseGRIB2Product(section []byte, discipline int, referenceTime time.Time) (GRIB2Parameter, GRIB2Level, time.Time, error) {
+	if len(section) < 9 {
+		return GRIB2Parameter{}, GRIB2Level{}, time.Time{}, errGRIB2Parse
+	}
+	template := binary.BigEndian.Uint16(section[7:])
+	var endOffset int
+	switch template {
+	case 0, 1:
+	case 8:
+		endOffset = 34
+	case 11:
+		endOffset = 37
+	default:
+		return GRIB2Parameter{}, GRIB2Level{}, time.Time{}, fmt.Errorf("product definition template 4.%d: %w", template, ErrGRIB2Unsupported)
+	}
+	if len(section) < 34 || len(section) < endOffset+7 {
+		return GRIB2Parameter{}, GRIB2Level{}, time.Time{}, errGRIB2Parse
+	}
+	parameter := GRIB2Parameter{
+		Discipline: discipline,
+		Category:   int(section[9]),
+		Number:     int(section[10]),
+	}
+	level := GRIB2Level{
+		Type:  int(section[22]),
+		Value: grib2Scaled(section[23], section[24:28]),
+	}
+	var validTime time.Time
+	if endOffset != 0 {
+		validTime = grib2Time(section[endOffset : endOffset+7])
+	} else {
+		unit, ok := grib2TimeUnit(section[17])
+		if !ok {
+			return GRIB2Parameter{}, GRIB2Level{}, time.Time{}, errGRIB2Parse
+		}
+		validTime = referenceTime.Add(time.Duration(grib2Int(section[18:22])) * unit)
+	}
+	return parameter, level, validTime, nil
+}
+
+// parseGRIB2Packing parses a data representation section.
+func parseGRIB2Packing(section []byte) (*grib2Packing, error) {
+	if len(section) < 11 {
+		return nil, errGRIB2Parse
+	}
+	p := &grib2Packing{
+		template: int(binary.BigEndian.Uint16(section[9:])),
+	}
+	var minLength int
+	switch p.template {
+	case 0:
+		minLength = 21
+	case 2:
+		minLength = 47
+	case 3:
+		minLength = 49
+	default:
+		return nil, fmt.Errorf("data representation template 5.%d: %w", p.template, ErrGRIB2Unsupported)
+	}
+	if len(section) < minLength {
+		return nil, errGRIB2Parse
+	}
+	p.n = int(binary.BigEndian.Uint32(section[5:]))
+	p.r = float64(math.Float32frombits(binary.BigEndian.Uint32(section[11:])))
+	p.e = grib2Int(section[15:17])
+	p.d = grib2Int(section[17:19])
+	p.nBits = int(section[19])
+	if p.template == 0 {
+		return p, nil
+	}
+	if section[21] > 2 {
+		return nil, errGRIB2Parse
+	}
+	p.missingMgmt = int(section[22])
+	p.ng = int(binary.BigEndian.Uint32(section[31:]))
+	if p.ng <= 0 || p.ng > p.n {
+		return nil, errGRIB2Parse
+	}
+	p.widthRef = int(section[35])
+	p.widthBits = int(section[36])
+	p.lengthRef = int(binary.BigEndian.Uint32(section[37:]))
+	p.lengthIncrement = int(section[41])
+	p.lastLength = int(binary.BigEndian.Uint32(section[42:]))
+	p.lengthBits = int(section[46])
+	if p.template == 3 {
+		p.order = int(section[47])
+		p.extraOctets = int(section[48])
+		if p.order < 1 || p.order > 2 || p.extraOctets == 0 {
+			return nil, errGRIB2Parse
+		}
+	}
+	return p, nil
+}
+
+// parseGRIB2Bitmap parses a bitmap section. previous is the previously defined
+// bitmap, which is reused if the bitmap indicator is 254.
+func parseGRIB2Bitmap(section, previous []byte) ([]byte, error) {
+	if len(section) < 6 {
+		return nil, errGRIB2Parse
+	}
+	switch section[5] {
+	case 0:
+		return section[6:], nil
+	case 254:
+		return previous, nil
+	case 255:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("bitmap indicator %d: %w", section[5], ErrGRIB2Unsupported)
+	}
+}
+
+// unpack unpacks the values in a data section. If bitmap is not nil then
+// values are only present where the bitmap is set, and other values are NaN.
+func (p *grib2Packing) unpack(data, bitmap []byte, nPoints int) ([]float64, error) {
+	// Check the number of packed values against the number of grid points
+	// and the available bits before allocating them.
+	if p.n < 0 || p.n > nPoints || p.template == 0 && p.nBits > 0 && p.n > len(data)*8/p.nBits {
+		return nil, errGRIB2Parse
+	}
+
+	var xs []float64
+	var err error
+	if p.template == 0 {
+		xs, err = p.unpackSimple(data)
+	} else {
+		xs, err = p.unpackComplex(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scale := math.Pow(2, float64(p.e))
+	decimalScale := math.Pow(10, float64(-p.d))
+	for i, x := range xs {
+		if !math.IsNaN(x) {
+			xs[i] = (p.r + x*scale) * decimalScale
+		}
+	}
+
+	if bitmap == nil {
+		if len(xs) != nPoints {
+			return nil, errGRIB2Parse
+		}
+		return xs, nil
+	}
+	if len(bitmap)*8 < nPoints {
+		return nil, errGRIB2Parse
+	}
+	values := make([]float64, nPoints)
+	j := 0
+	for i := range values {
+		if bitmap[i/8]&(0x80>>uint(i%8)) == 0 {
+			values[i] = math.NaN()
+			continue
+		}
+		if j >= len(xs) {
+			return nil, errGRIB2Parse
+		}
+		values[i] = xs[j]
+		j++
+	}
+	return values, nil
+}
+
+// unpackSimple unpacks values with simple packing.
+func (p *grib2Packing) unpackSimple(data []byte) ([]float64, error) {
+	xs := make([]float64, p.n)
+	if p.nBits == 0 {
+		return xs, nil
+	}
+	r := &bitReader{data: data}
+	for i := range xs {
+		x, err := r.read(p.nBits)
+		if err != nil {
+			return nil, err
+		}
+		xs[i] = float64(x)
+	}
+	return xs, nil
+}
+
+// unpackComplex unpacks values with complex packing, and optionally spatial
+// differencing. Missing values are NaN.
+func (p *grib2Packing) unpackComplex(data []byte) ([]float64, error) {
+	r := &bitReader{data: data}
+
+	var extras []int
+	if p.template == 3 {
+		for i := 0; i <= p.order; i++ {
+			if r.bit/8+p.extraOctets > len(data) {
+				return nil, errGRIB2Parse
+			}
+			extras = append(extras, grib2Int(data[r.bit/8:r.bit/8+p.extraOctets]))
+			r.bit += 8 * p.extraOctets
+		}
+	}
+
+	refs, err := r.readN(p.ng, p.nBits)
+	if err != nil {
+		return nil, err
+	}
+	r.align()
+	widths, err := r.readN(p.ng, p.widthBits)
+	if err != nil {
+		return nil, err
+	}
+	r.align()
+	lengths, err := r.readN(p.ng, p.lengthBits)
+	if err != nil {
+		return nil, err
+	}
+	r.align()
+
+	xs := make([]float64, 0, p.n)
+	for g := 0; g < p.ng; g++ {
+		width := widths[g] + p.widthRef
+		length := p.lengthRef + lengths[g]*p.lengthIncrement
+		if g == p.ng-1 {
+			length = p.lastLength
+		}
+		if width > 32 || len(xs)+length > p.n {
+			return nil, errGRIB2Parse
+		}
+		for i := 0; i < length; i++ {
+			var x int
+			if width > 0 {
+				if x, err = r.read(width); err != nil {
+					return nil, err
+				}
+			}
+			missingBits := width
+			if width == 0 {
+				missingBits, x = p.nBits, refs[g]
+			}
+			if p.isMissing(x, missingBits) {
+				xs = append(xs, math.NaN())
+				continue
+			}
+			if width > 0 {
+				x += refs[g]
+			}
+			xs = append(xs, float64(x))
+		}
+	}
+	if len(xs) != p.n {
+		return nil, errGRIB2Parse
+	}
+
+	if p.template == 3 {
+		p.undifference(xs, extras)
+	}
+	return xs, nil
+}
+
+// isMissing returns whether the packed value x of width bits represents a
+// missing value.
+func (p *grib2Packing) isMissing(x, bits int) bool {
+	if bits == 0 || p.missingMgmt == 0 {
+		return false
+	}
+	primary := 1<<uint(bits) - 1
+	return x == primary || p.missingMgmt == 2 && x == primary-1
+}
+
+// undifference reverses spatial differencing of the non-missing values in xs.
+// extras contains the first value(s) and the overall minimum of the
+// differences.
+func (p *grib2Packing) undifference(xs []float64, extras []int) {
+	minimum := float64(extras[len(extras)-1])
+	var prev [2]float64
+	n := 0
+	for i, x := range xs {
+		if math.IsNaN(x) {
+			continue
+		}
+		switch {
+		case n < p.order:
+			xs[i] = float64(extras[n])
+		case p.order == 1:
+			xs[i] = x + minimum + prev[1]
+		default:
+			xs[i] = x + minimum + 2*prev[1] - prev[0]
+		}
+		prev[0], prev[1] = prev[1], xs[i]
+		n++
+	}
+}
+
+// grid returns the coordinates of g and values rearranged so that they are
+// indexed by lat from north to south then lon from west to east.
+func (g *grib2Grid) grid(values []float64) ([]float64, []float64, [][]float64) {
+	lats := make([]float64, g.nj)
+	lons := make([]float64, g.ni)
+	north, south := g.la1, g.la2
+	if north < south {
+		north, south = south, north
+	}
+	west, east := g.lo1, g.lo2
+	if g.scanningMode&grib2ScanNegativeI != 0 {
+		west, east = east, west
+	}
+	if east < west {
+		east += 360
+	}
+	for j := range lats {
+		lats[j] = north - float64(j)*resolution(south, north, g.nj)
+	}
+	for i := range lons {
+		lons[i] = west + float64(i)*resolution(west, east, g.ni)
+	}
+
+	grid := make([][]float64, g.nj)
+	for j := range grid {
+		grid[j] = make([]float64, g.ni)
+	}
+	for k, value := range values {
+		var i, j int
+		if g.scanningMode&grib2ScanJAdjacent != 0 {
+			i, j = k/g.nj, k%g.nj
+		} else {
+			i, j = k%g.ni, k/g.ni
+		}
+		if g.scanningMode&grib2ScanNegativeI != 0 {
+			i = g.ni - 1 - i
+		}
+		if g.scanningMode&grib2ScanPositiveJ != 0 {
+			j = g.nj - 1 - j
+		}
+		grid[j][i] = value
+	}
+	return lats, lons, grid
+}
+
+// read reads an unsigned value of n bits.
+func (r *bitReader) read(n int) (int, error) {
+	if r.bit+n > 8*len(r.data) {
+		return 0, errGRIB2Parse
+	}
+	x := 0
+	for i := 0; i < n; i++ {
+		bit := r.bit + i
+		x = x<<1 | int(r.data[bit/8]>>uint(7-bit%8)&1)
+	}
+	r.bit += n
+	return x, nil
+}
+
+// readN reads count unsigned values of n bits each.
+func (r *bitReader) readN(count, n int) ([]int, error) {
+	if count < 0 || n > 0 && count > (8*len(r.data)-r.bit)/n {
+		return nil, errGRIB2Parse
+	}
+	xs := make([]int, count)
+	for i := range xs {
+		x, err := r.read(n)
+		if err != nil {
+			return nil, err
+		}
+		xs[i] = x
+	}
+	return xs, nil
+}
+
+// align advances r to the next byte boundary.
+func (r *bitReader) align() {
+	r.bit = (r.bit + 7) &^ 7
+}
+
+// grib2Int decodes a GRIB2 sign-magnitude integer.
+func grib2Int(b []byte) int {
+	x := 0
+	for _, c := range b {
+		x = x<<8 | int(c)
+	}
+	signBit := 1 << uint(8*len(b)-1)
+	if x&signBit != 0 {
+		return -(x &^ signBit)
+	}
+	return x
+}
+
+// grib2Scaled decodes a scale factor and scaled value.
+func grib2Scaled(scaleFactor byte, scaledValue []byte) float64 {
+	if scaleFactor == 0xff {
+		return math.NaN()
+	}
+	return float64(grib2Int(scaledValue)) * math.Pow(10, -float64(grib2Int([]byte{scaleFactor})))
+}
+
+// grib2Time decodes a year, month, day, hour, minute, and second.
+func grib2Time(b []byte) time.Time {
+	return time.Date(int(binary.BigEndian.Uint16(b)), time.Month(b[2]), int(b[3]), int(b[4]), int(b[5]), int(b[6]), 0, time.UTC)
+}
+
+// grib2TimeUnit returns the duration of a time unit from WMO code table 4.4.
+func grib2TimeUnit(unit byte) (time.Duration, bool) {
+	switch unit {
+	case 0:
+		return time.Minute, true
+	case 1:
+		return time.Hour, true
+	case 2:
+		return 24 * time.Hour, true
+	case 10:
+		return 3 * time.Hour, true
+	case 11:
+		return 6 * time.Hour, true
+	case 12:
+		return 12 * time.Hour, true
+	case 13:
+		return time.Second, true
+	default:
+		return 0, false
+	}
+}
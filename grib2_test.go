@@ -0,0 +1,285 @@
+package meteomatics
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A testBitWriter writes big-endian bit fields.
+type testBitWriter struct {
+	bytes.Buffer
+	current byte
+	n       uint
+}
+
+func (w *testBitWriter) write(x, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		w.current = w.current<<1 | byte(x>>uint(i)&1)
+		w.n++
+		if w.n == 8 {
+			w.WriteByte(w.current)
+			w.current, w.n = 0, 0
+		}
+	}
+}
+
+func (w *testBitWriter) align() {
+	if w.n != 0 {
+		w.write(0, int(8-w.n))
+	}
+}
+
+// testGRIB2Section returns a GRIB2 section with the given number and contents.
+func testGRIB2Section(number byte, contents ...interface{}) []byte {
+	b := &bytes.Buffer{}
+	for _, c := range contents {
+		_ = binary.Write(b, binary.BigEndian, c)
+	}
+	section := make([]byte, 5, 5+b.Len())
+	binary.BigEndian.PutUint32(section, uint32(5+b.Len()))
+	section[4] = number
+	return append(section, b.Bytes()...)
+}
+
+// testGRIB2Time returns t encoded as a GRIB2 time.
+func testGRIB2Time(t time.Time) []interface{} {
+	return []interface{}{
+		uint16(t.Year()), uint8(t.Month()), uint8(t.Day()),
+		uint8(t.Hour()), uint8(t.Minute()), uint8(t.Second()),
+	}
+}
+
+// testGRIB2Grid returns a grid definition section for a regular lat/lon grid.
+func testGRIB2Grid(ni, nj int, la1, lo1, la2, lo2 float64, scanningMode byte) []byte {
+	return testGRIB2Section(3,
+		uint8(0), uint32(ni*nj), uint8(0), uint8(0), uint16(0),
+		uint8(6), uint8(0), uint32(0), uint8(0), uint32(0), uint8(0), uint32(0),
+		uint32(ni), uint32(nj), uint32(0), uint32(0xffffffff),
+		testGRIB2Angle(la1), testGRIB2Angle(lo1), uint8(0x30),
+		testGRIB2Angle(la2), testGRIB2Angle(lo2),
+		uint32(0), uint32(0), scanningMode,
+	)
+}
+
+// testGRIB2Angle returns x encoded as a GRIB2 sign-magnitude angle.
+func testGRIB2Angle(x float64) uint32 {
+	if x < 0 {
+		return 0x80000000 | uint32(math.Round(-x*1e6))
+	}
+	return uint32(math.Round(x * 1e6))
+}
+
+func newTestGRIB2(t *testing.T) []byte {
+	t.Helper()
+	referenceTime := time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC)
+
+	var sections [][]byte
+	sections = append(sections, testGRIB2Section(1, append([]interface{}{
+		uint16(98), uint16(0), uint8(2), uint8(1), uint8(1),
+	}, append(testGRIB2Time(referenceTime), uint8(0), uint8(1))...)...))
+
+	// Temperature at 2m with simple packing and a bitmap.
+	sections = append(sections,
+		testGRIB2Grid(3, 2, 50, 10, 40, 20, 0),
+		testGRIB2Section(4,
+			uint16(0), uint16(0),
+			uint8(0), uint8(0), uint8(2), uint8(0), uint8(0), uint16(0), uint8(0),
+			uint8(1), uint32(6),
+			uint8(103), uint8(0), uint32(2), uint8(255), uint8(0), uint32(0),
+		),
+		testGRIB2Section(5,
+			uint32(5), uint16(0),
+			float32(0), uint16(0), uint16(1), uint8(8), uint8(0),
+		),
+		testGRIB2Section(6, uint8(0), uint8(0xf8)),
+		testGRIB2Section(7, []byte{15, 25, 35, 45, 55}),
+	)
+
+	// Precipitation with complex packing, spatial differencing, and a missing
+	// value, scanned from south to north.
+	w := &testBitWriter{}
+	w.write(10, 16)     // First value.
+	w.write(0x8004, 16) // Minimum difference, -4.
+	for _, ref := range []int{0, 0} {
+		w.write(ref, 4)
+	}
+	w.align()
+	for _, width := range []int{4, 4} {
+		w.write(width, 3)
+	}
+	w.align()
+	for _, length := range []int{0, 0} {
+		w.write(length, 1)
+	}
+	w.align()
+	for _, x := range []int{0, 6, 7} {
+		w.write(x, 4)
+	}
+	for _, x := range []int{15, 0, 13} {
+		w.write(x, 4)
+	}
+	w.align()
+	sections = append(sections,
+		testGRIB2Grid(3, 2, 40, 10, 50, 20, 0x40),
+		testGRIB2Section(4, append(append([]interface{}{
+			uint16(0), uint16(8),
+			uint8(1), uint8(8), uint8(2), uint8(0), uint8(0), uint16(0), uint8(0),
+			uint8(1), uint32(5),
+			uint8(1), uint8(0), uint32(0), uint8(255), uint8(0), uint32(0),
+		}, testGRIB2Time(referenceTime.Add(6*time.Hour))...),
+			uint8(1), uint32(0), uint8(1), uint8(2), uint8(1), uint32(1), uint8(1), uint32(0),
+		)...),
+		testGRIB2Section(5,
+			uint32(6), uint16(3),
+			float32(0), uint16(0), uint16(0), uint8(4), uint8(0),
+			uint8(1), uint8(1), uint32(0), uint32(0), uint32(2),
+			uint8(0), uint8(3), uint32(3), uint8(1), uint32(3), uint8(1),
+			uint8(1), uint8(2),
+		),
+		testGRIB2Section(6, uint8(255)),
+		testGRIB2Section(7, w.Bytes()),
+	)
+
+	body := bytes.Join(sections, nil)
+	b := &bytes.Buffer{}
+	b.WriteString("GRIB")
+	b.Write([]byte{0, 0, 0, 2})
+	require.NoError(t, binary.Write(b, binary.BigEndian, uint64(16+len(body)+4)))
+	b.Write(body)
+	b.WriteString("7777")
+	return b.Bytes()
+}
+
+func TestParseGRIB2(t *testing.T) {
+	messages, err := ParseGRIB2(newTestGRIB2(t))
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+
+	m := messages[0]
+	assert.Equal(t, GRIB2Parameter{Discipline: 0, Category: 0, Number: 0}, m.Parameter)
+	assert.Equal(t, GRIB2Level{Type: 103, Value: 2}, m.Level)
+	assert.Equal(t, time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC), m.ReferenceTime)
+	assert.Equal(t, time.Date(2016, 12, 19, 18, 0, 0, 0, time.UTC), m.ValidTime)
+	assert.Equal(t, []float64{50, 40}, m.Lats)
+	assert.Equal(t, []float64{10, 15, 20}, m.Lons)
+	require.Len(t, m.Values, 2)
+	assert.Equal(t, []float64{1.5, 2.5, 3.5}, m.Values[0])
+	assert.Equal(t, []float64{4.5, 5.5}, m.Values[1][:2])
+	assert.True(t, math.IsNaN(m.Values[1][2]))
+
+	m = messages[1]
+	assert.Equal(t, GRIB2Parameter{Discipline: 0, Category: 1, Number: 8}, m.Parameter)
+	assert.Equal(t, 1, m.Level.Type)
+	assert.Equal(t, time.Date(2016, 12, 19, 18, 0, 0, 0, time.UTC), m.ValidTime)
+	assert.Equal(t, []float64{50, 40}, m.Lats)
+	assert.Equal(t, []float64{10, 15, 20}, m.Lons)
+	require.Len(t, m.Values, 2)
+	assert.True(t, math.IsNaN(m.Values[0][0]))
+	assert.Equal(t, []float64{11, 20}, m.Values[0][1:])
+	assert.Equal(t, []float64{10, 12, 15}, m.Values[1])
+}
+
+func TestParseGRIB2Errors(t *testing.T) {
+	data := newTestGRIB2(t)
+
+	_, err := ParseGRIB2(nil)
+	assert.True(t, errors.Is(err, errGRIB2Parse))
+
+	_, err = ParseGRIB2(data[:len(data)-10])
+	assert.True(t, errors.Is(err, errGRIB2Parse))
+
+	packingOffset := bytes.Index(data, testGRIB2Section(5,
+		uint32(5), uint16(0),
+		float32(0), uint16(0), uint16(1), uint8(8), uint8(0),
+	))
+	require.NotEqual(t, -1, packingOffset)
+	for _, n := range []uint32{6, 7, math.MaxUint32} {
+		tooManyValues := append([]byte(nil), data...)
+		binary.BigEndian.PutUint32(tooManyValues[packingOffset+5:], n)
+		_, err = ParseGRIB2(tooManyValues)
+		assert.True(t, errors.Is(err, errGRIB2Parse))
+	}
+
+	complexPackingOffset := bytes.Index(data, testGRIB2Section(5,
+		uint32(6), uint16(3),
+		float32(0), uint16(0), uint16(0), uint8(4), uint8(0),
+		uint8(1), uint8(1), uint32(0), uint32(0), uint32(2),
+		uint8(0), uint8(3), uint32(3), uint8(1), uint32(3), uint8(1),
+		uint8(1), uint8(2),
+	))
+	require.NotEqual(t, -1, complexPackingOffset)
+	for _, ng := range []uint32{0, 7, math.MaxUint32} {
+		badGroups := append([]byte(nil), data...)
+		binary.BigEndian.PutUint32(badGroups[complexPackingOffset+31:], ng)
+		_, err = ParseGRIB2(badGroups)
+		assert.True(t, errors.Is(err, errGRIB2Parse))
+	}
+
+	_, err = (&bitReader{data: []byte{0}}).readN(math.MaxInt32, 1)
+	assert.True(t, errors.Is(err, errGRIB2Parse))
+
+	gridOffset := bytes.Index(data, testGRIB2Grid(3, 2, 50, 10, 40, 20, 0))
+	require.NotEqual(t, -1, gridOffset)
+
+	largeGrid := append([]byte(nil), data...)
+	binary.BigEndian.PutUint32(largeGrid[gridOffset+30:], math.MaxUint32)
+	binary.BigEndian.PutUint32(largeGrid[gridOffset+34:], math.MaxUint32)
+	_, err = ParseGRIB2(largeGrid)
+	assert.True(t, errors.Is(err, errGRIB2Parse))
+
+	unsupported := append([]byte(nil), data...)
+	unsupported[gridOffset+13] = 10
+	_, err = ParseGRIB2(unsupported)
+	assert.True(t, errors.Is(err, ErrGRIB2Unsupported))
+}
+
+func TestClientRequestGRIB2(t *testing.T) {
+	data := newTestGRIB2(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2016-12-19T18:00:00Z/t_2m:C,precip_6h:mm/50,10_40,20:3x2/grib2", r.URL.Path)
+		_, _ = w.Write(data)
+	}))
+	defer s.Close()
+
+	messages, err := NewClient(WithBaseURL(s.URL)).RequestGRIB2(
+		context.Background(),
+		Time(time.Date(2016, 12, 19, 18, 0, 0, 0, time.UTC)),
+		ParameterSlice{
+			Parameter{
+				Name:  ParameterTemperature,
+				Level: LevelMeters(2),
+				Units: UnitsCelsius,
+			},
+			Parameter{
+				Name:     ParameterPrecipitation,
+				Interval: Interval(6 * time.Hour),
+				Units:    UnitsMillimeters,
+			},
+		},
+		RectangleN{
+			Min: Point{
+				Lat: 40,
+				Lon: 10,
+			},
+			Max: Point{
+				Lat: 50,
+				Lon: 20,
+			},
+			NLon: 3,
+			NLat: 2,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	assert.Len(t, messages, 2)
+}
@@ -0,0 +1,61 @@
+package meteomatics
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// An InitDate is the initialization time of the model run that provides a
+// parameter at a valid date.
+type InitDate struct {
+	Parameter ParameterString
+	ValidDate time.Time
+	InitDate  time.Time
+}
+
+// initDateJSONResponse is a JSON response to a get_init_date request.
+type initDateJSONResponse struct {
+	Status string `json:"status"`
+	Data   []struct {
+		Parameter ParameterString `json:"parameter"`
+		Dates     []struct {
+			Date  time.Time `json:"date"`
+			Value time.Time `json:"value"`
+		} `json:"dates"`
+	} `json:"data"`
+}
+
+// GetInitDate returns the initialization times of the latest runs of model
+// that provide the parameters ps at the times ts. Pass an InitDate in
+// RequestOptions to pin later requests to the same model run.
+func (c *Client) GetInitDate(ctx context.Context, model Model, ts TimeStringer, ps ParameterStringer) ([]InitDate, error) {
+	v := url.Values{}
+	v.Set("model", string(model))
+	v.Set("valid_date", string(ts.TimeString()))
+	v.Set("parameters", string(ps.ParameterString()))
+	data, err := c.get(ctx, c.baseURL+"/get_init_date?"+v.Encode(), FormatJSON.ContentType())
+	if err != nil {
+		return nil, err
+	}
+
+	var idjr initDateJSONResponse
+	if err := json.Unmarshal(data, &idjr); err != nil {
+		return nil, err
+	}
+	if idjr.Status != "OK" {
+		return nil, parseAPIStatus(idjr.Status)
+	}
+	var initDates []InitDate
+	for _, d := range idjr.Data {
+		for _, date := range d.Dates {
+			initDates = append(initDates, InitDate{
+				Parameter: d.Parameter,
+				ValidDate: date.Date,
+				InitDate:  date.Value,
+			})
+		}
+	}
+	return initDates, nil
+}
@@ -0,0 +1,55 @@
+package meteomatics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientGetInitDate(t *testing.T) {
+	s := newTestServer(
+		t,
+		"/get_init_date?model=ecmwf-ifs&parameters=t_2m%3AC&valid_date=2019-04-16T12%3A00%3A00ZP1D%3AP1D",
+		"testdata/init_date.json",
+	)
+	defer s.Close()
+
+	initDates, err := NewClient(WithBaseURL(s.URL)).GetInitDate(
+		context.Background(),
+		"ecmwf-ifs",
+		TimePeriod{
+			Start:    time.Date(2019, 4, 16, 12, 0, 0, 0, time.UTC),
+			Duration: 24 * time.Hour,
+			Step:     24 * time.Hour,
+		},
+		Parameter{
+			Name:  ParameterTemperature,
+			Level: LevelMeters(2),
+			Units: UnitsCelsius,
+		},
+	)
+	require.NoError(t, err)
+	initDate := time.Date(2019, 4, 16, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []InitDate{
+		{
+			Parameter: "t_2m:C",
+			ValidDate: time.Date(2019, 4, 16, 12, 0, 0, 0, time.UTC),
+			InitDate:  initDate,
+		},
+		{
+			Parameter: "t_2m:C",
+			ValidDate: time.Date(2019, 4, 17, 12, 0, 0, 0, time.UTC),
+			InitDate:  initDate,
+		},
+	}, initDates)
+}
+
+func TestRequestOptionsInitDate(t *testing.T) {
+	assert.Equal(t, "init_date=2019-04-16T00%3A00%3A00Z&source=ecmwf-ifs", (&RequestOptions{
+		Source:   "ecmwf-ifs",
+		InitDate: time.Date(2019, 4, 16, 0, 0, 0, 0, time.UTC),
+	}).Values().Encode())
+}
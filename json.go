@@ -3,15 +3,47 @@ package meteomatics
 import (
 	"context"
 	"encoding/json"
+	"math"
+	"sort"
 	"time"
 )
 
-// A JSONDate is a value at a date.
+// A JSONDate is a value at a date. A missing value is NaN, which is encoded
+// as null.
 type JSONDate struct {
 	Date  time.Time `json:"date"`
 	Value float64   `json:"value"`
 }
 
+// MarshalJSON implements encoding/json.Marshaler. NaN is encoded as null.
+func (d JSONDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Date  time.Time `json:"date"`
+		Value *float64  `json:"value"`
+	}{
+		Date:  d.Date,
+		Value: nullableJSONValue(d.Value),
+	})
+}
+
+// UnmarshalJSON implements encoding/json.Unmarshaler. A null value is decoded
+// as NaN.
+func (d *JSONDate) UnmarshalJSON(data []byte) error {
+	var jsonDate struct {
+		Date  time.Time `json:"date"`
+		Value *float64  `json:"value"`
+	}
+	if err := json.Unmarshal(data, &jsonDate); err != nil {
+		return err
+	}
+	d.Date = jsonDate.Date
+	d.Value = math.NaN()
+	if jsonDate.Value != nil {
+		d.Value = *jsonDate.Value
+	}
+	return nil
+}
+
 // A JSONCoordinates is a series of values.
 type JSONCoordinates struct {
 	Dates     []JSONDate `json:"dates"`
@@ -20,6 +52,15 @@ type JSONCoordinates struct {
 	StationID string     `json:"station_id"`
 }
 
+// Station returns c's station as a WMOStation, METARStation, Postal, or
+// ProviderStation. It returns nil if c is not a station.
+func (c *JSONCoordinates) Station() (LocationStringer, error) {
+	if c.StationID == "" {
+		return nil, nil
+	}
+	return ParseStationID(c.StationID)
+}
+
 // A JSONData is a parameter measured at a coordinate.
 type JSONData struct {
 	Coordinates []JSONCoordinates `json:"coordinates"`
@@ -35,12 +76,42 @@ type JSONResponse struct {
 	Data          []JSONData `json:"data"`
 }
 
-// A JSONRouteParameter is a JSON route parameter.
+// A JSONRouteParameter is a JSON route parameter. A missing value is NaN,
+// which is encoded as null.
 type JSONRouteParameter struct {
 	Parameter ParameterString `json:"parameter"`
 	Value     float64         `json:"value"`
 }
 
+// MarshalJSON implements encoding/json.Marshaler. NaN is encoded as null.
+func (p JSONRouteParameter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Parameter ParameterString `json:"parameter"`
+		Value     *float64        `json:"value"`
+	}{
+		Parameter: p.Parameter,
+		Value:     nullableJSONValue(p.Value),
+	})
+}
+
+// UnmarshalJSON implements encoding/json.Unmarshaler. A null value is decoded
+// as NaN.
+func (p *JSONRouteParameter) UnmarshalJSON(data []byte) error {
+	var jsonRouteParameter struct {
+		Parameter ParameterString `json:"parameter"`
+		Value     *float64        `json:"value"`
+	}
+	if err := json.Unmarshal(data, &jsonRouteParameter); err != nil {
+		return err
+	}
+	p.Parameter = jsonRouteParameter.Parameter
+	p.Value = math.NaN()
+	if jsonRouteParameter.Value != nil {
+		p.Value = *jsonRouteParameter.Value
+	}
+	return nil
+}
+
 // A JSONRouteData is a JSON route data.
 type JSONRouteData struct {
 	Lat        float64              `json:"lat"`
@@ -58,6 +129,31 @@ type JSONRouteResponse struct {
 	Data          []JSONRouteData `json:"data"`
 }
 
+// A JSONRegionDate is a grid of values at a date. Values are indexed by lat
+// then lon, and missing values are NaN.
+type JSONRegionDate struct {
+	Date   time.Time
+	Values [][]float64
+}
+
+// A JSONRegionData is a parameter over a region.
+type JSONRegionData struct {
+	Parameter ParameterString
+	Dates     []JSONRegionDate
+}
+
+// A JSONRegionResponse is a response to a JSON region request. Lats are sorted
+// from north to south and Lons from west to east.
+type JSONRegionResponse struct {
+	Version       string
+	User          string
+	DateGenerated time.Time
+	Status        string
+	Lats          []float64
+	Lons          []float64
+	Data          []JSONRegionData
+}
+
 // RequestJSON requests a forecast in JSON format.
 func (c *Client) RequestJSON(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*JSONResponse, error) {
 	data, err := c.Request(ctx, ts, ps, ls, FormatJSON, options)
@@ -71,17 +167,13 @@ func (c *Client) RequestJSON(ctx context.Context, ts TimeStringer, ps ParameterS
 	if jr.Status != "OK" {
 		return nil, jr
 	}
+	c.missingValues.replaceJSONResponse(jr)
 	return jr, nil
 }
 
 // RequestJSONRoute requests a forecast in JSON format.
 func (c *Client) RequestJSONRoute(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*JSONRouteResponse, error) {
-	var ro RequestOptions
-	if options != nil {
-		ro = *options
-	}
-	ro.Route = true
-	data, err := c.Request(ctx, ts, ps, ls, FormatJSON, &ro)
+	data, err := c.Request(ctx, ts, ps, ls, FormatJSON, routeOptions(options))
 	if err != nil {
 		return nil, err
 	}
@@ -92,13 +184,124 @@ func (c *Client) RequestJSONRoute(ctx context.Context, ts TimeStringer, ps Param
 	if jrr.Status != "OK" {
 		return nil, jrr
 	}
+	c.missingValues.replaceJSONRouteResponse(jrr)
 	return jrr, nil
 }
 
+// RequestJSONRegion requests a region forecast in JSON format.
+func (c *Client) RequestJSONRegion(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*JSONRegionResponse, error) {
+	jr, err := c.RequestJSON(ctx, ts, ps, ls, options)
+	if err != nil {
+		return nil, err
+	}
+	return jr.Region(), nil
+}
+
+// Region returns r's values as grids.
+func (r *JSONResponse) Region() *JSONRegionResponse {
+	jrr := &JSONRegionResponse{
+		Version:       r.Version,
+		User:          r.User,
+		DateGenerated: r.DateGenerated,
+		Status:        r.Status,
+	}
+
+	latIndex := make(map[float64]int)
+	lonIndex := make(map[float64]int)
+	for _, data := range r.Data {
+		for _, coordinates := range data.Coordinates {
+			latIndex[coordinates.Lat] = 0
+			lonIndex[coordinates.Lon] = 0
+		}
+	}
+	jrr.Lats = sortedKeys(latIndex)
+	sort.Sort(sort.Reverse(sort.Float64Slice(jrr.Lats)))
+	for i, lat := range jrr.Lats {
+		latIndex[lat] = i
+	}
+	jrr.Lons = sortedKeys(lonIndex)
+	for i, lon := range jrr.Lons {
+		lonIndex[lon] = i
+	}
+
+	jrr.Data = make([]JSONRegionData, 0, len(r.Data))
+	for _, data := range r.Data {
+		dateIndex := make(map[time.Time]int)
+		var dates []time.Time
+		for _, coordinates := range data.Coordinates {
+			for _, date := range coordinates.Dates {
+				if _, ok := dateIndex[date.Date]; !ok {
+					dateIndex[date.Date] = 0
+					dates = append(dates, date.Date)
+				}
+			}
+		}
+		sort.Slice(dates, func(i, j int) bool {
+			return dates[i].Before(dates[j])
+		})
+
+		regionData := JSONRegionData{
+			Parameter: data.Parameter,
+			Dates:     make([]JSONRegionDate, 0, len(dates)),
+		}
+		for i, date := range dates {
+			dateIndex[date] = i
+			values := make([][]float64, len(jrr.Lats))
+			for j := range values {
+				values[j] = make([]float64, len(jrr.Lons))
+				for k := range values[j] {
+					values[j][k] = math.NaN()
+				}
+			}
+			regionData.Dates = append(regionData.Dates, JSONRegionDate{
+				Date:   date,
+				Values: values,
+			})
+		}
+		for _, coordinates := range data.Coordinates {
+			j, k := latIndex[coordinates.Lat], lonIndex[coordinates.Lon]
+			for _, date := range coordinates.Dates {
+				regionData.Dates[dateIndex[date.Date]].Values[j][k] = date.Value
+			}
+		}
+		jrr.Data = append(jrr.Data, regionData)
+	}
+
+	return jrr
+}
+
 func (r *JSONResponse) Error() string {
-	return r.Status
+	return parseAPIStatus(r.Status).Error()
+}
+
+// Unwrap returns r's status parsed as an *APIError.
+func (r *JSONResponse) Unwrap() error {
+	return parseAPIStatus(r.Status)
 }
 
 func (r *JSONRouteResponse) Error() string {
-	return r.Status
+	return parseAPIStatus(r.Status).Error()
+}
+
+// Unwrap returns r's status parsed as an *APIError.
+func (r *JSONRouteResponse) Unwrap() error {
+	return parseAPIStatus(r.Status)
+}
+
+// sortedKeys returns the keys of m in increasing order.
+func sortedKeys(m map[float64]int) []float64 {
+	keys := make([]float64, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Float64s(keys)
+	return keys
+}
+
+// nullableJSONValue returns nil if x is NaN, and a pointer to x otherwise.
+func nullableJSONValue(x float64) *float64 {
+	if math.IsNaN(x) {
+		return nil
+	}
+	return &x
 }
@@ -2,6 +2,9 @@ package meteomatics
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"math"
 	"strings"
 	"testing"
 	"time"
@@ -149,4 +152,110 @@ func TestClientRequestJSONError(t *testing.T) {
 	)
 	require.Error(t, err)
 	assert.True(t, strings.HasPrefix(err.Error(), "Not enough data outside temporal and/or spatial domain"))
+	assert.True(t, errors.Is(err, ErrOutOfDomain))
+}
+
+func TestClientRequestJSONRegion(t *testing.T) {
+	s := newTestServer(
+		t,
+		"/2016-12-20T00:00:00ZP1D:P1D/t_2m:C,relative_humidity_2m:p/50,10_40,20:2x3/json",
+		"testdata/temperature_and_relative_humidity_region.json",
+	)
+	r, err := NewClient(WithBaseURL(s.URL)).RequestJSONRegion(
+		context.Background(),
+		TimePeriod{
+			Start:    time.Date(2016, 12, 20, 0, 0, 0, 0, time.UTC),
+			Duration: 24 * time.Hour,
+			Step:     24 * time.Hour,
+		},
+		ParameterSlice{
+			Parameter{
+				Name:  ParameterTemperature,
+				Level: LevelMeters(2),
+				Units: UnitsCelsius,
+			},
+			Parameter{
+				Name:  ParameterRelativeHumidity,
+				Level: LevelMeters(2),
+				Units: UnitsPercentage,
+			},
+		},
+		RectangleN{
+			Min: Point{
+				Lat: 40,
+				Lon: 10,
+			},
+			Max: Point{
+				Lat: 50,
+				Lon: 20,
+			},
+			NLon: 2,
+			NLat: 3,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "OK", r.Status)
+	assert.Equal(t, []float64{50, 45, 40}, r.Lats)
+	assert.Equal(t, []float64{10, 20}, r.Lons)
+	require.Len(t, r.Data, 2)
+	assert.Equal(t, ParameterString("t_2m:C"), r.Data[0].Parameter)
+	require.Len(t, r.Data[0].Dates, 2)
+	assert.Equal(t, time.Date(2016, 12, 20, 0, 0, 0, 0, time.UTC), r.Data[0].Dates[0].Date)
+	assert.Equal(t, [][]float64{{5.1, 5.2}, {4.6, 4.7}, {4.1, 4.2}}, r.Data[0].Dates[0].Values)
+	assert.Equal(t, time.Date(2016, 12, 21, 0, 0, 0, 0, time.UTC), r.Data[0].Dates[1].Date)
+	assert.Equal(t, [][]float64{{6.1, 6.2}, {5.6, 5.7}, {5.1, 5.2}}, r.Data[0].Dates[1].Values)
+	assert.Equal(t, ParameterString("relative_humidity_2m:p"), r.Data[1].Parameter)
+	require.Len(t, r.Data[1].Dates, 2)
+	assert.Equal(t, 106.1, r.Data[1].Dates[1].Values[0][0])
+	assert.True(t, math.IsNaN(r.Data[1].Dates[1].Values[2][1]))
+}
+
+func TestJSONNullValues(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+		v    interface{}
+	}{
+		{
+			name: "date",
+			data: `{"date":"2016-01-20T14:35:00Z","value":null}`,
+			v:    &JSONDate{},
+		},
+		{
+			name: "route_parameter",
+			data: `{"parameter":"t_2m:C","value":null}`,
+			v:    &JSONRouteParameter{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, json.Unmarshal([]byte(tc.data), tc.v))
+			switch v := tc.v.(type) {
+			case *JSONDate:
+				assert.True(t, math.IsNaN(v.Value))
+			case *JSONRouteParameter:
+				assert.True(t, math.IsNaN(v.Value))
+			}
+			data, err := json.Marshal(tc.v)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.data, string(data))
+		})
+	}
+
+	data, err := json.Marshal(&JSONResponse{
+		Data: []JSONData{
+			{
+				Coordinates: []JSONCoordinates{
+					{
+						Dates: []JSONDate{
+							{Value: math.NaN()},
+							{Value: 1.5},
+						},
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"dates":[{"date":"0001-01-01T00:00:00Z","value":null},{"date":"0001-01-01T00:00:00Z","value":1.5}]`)
 }
@@ -0,0 +1,145 @@
+package meteomatics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errLightningFormat   = errors.New("lightning requests require CSV or JSON format")
+	errLightningLocation = errors.New("lightning requests require a RectangleN or RectangleRes location")
+	errLightningParse    = errors.New("lightning parse error")
+)
+
+// A LightningStrike is a lightning strike. Current is in kiloamperes and is
+// negative for strikes of negative polarity.
+type LightningStrike struct {
+	Time    time.Time
+	Lat     float64
+	Lon     float64
+	Current float64
+}
+
+// lightningJSONResponse is a JSON response to a lightning request.
+type lightningJSONResponse struct {
+	Status string `json:"status"`
+	Data   []struct {
+		Time    time.Time `json:"stroke_time:sql"`
+		Lat     float64   `json:"stroke_lat:d"`
+		Lon     float64   `json:"stroke_lon:d"`
+		Current float64   `json:"stroke_current:kA"`
+	} `json:"data"`
+}
+
+// RequestLightning requests the lightning strikes between start and end
+// within the bounding box of ls, which must be a RectangleN or RectangleRes,
+// in fs, which must be FormatCSV or FormatJSON.
+func (c *Client) RequestLightning(ctx context.Context, start, end time.Time, ls LocationStringer, fs FormatStringer) ([]LightningStrike, error) {
+	var bounds Domain
+	switch l := ls.(type) {
+	case RectangleN:
+		bounds = Domain{Min: l.Min, Max: l.Max}
+	case RectangleRes:
+		bounds = Domain{Min: l.Min, Max: l.Max}
+	default:
+		return nil, errLightningLocation
+	}
+	formatString := fs.FormatString()
+	if formatString != FormatCSV.FormatString() && formatString != FormatJSON.FormatString() {
+		return nil, errLightningFormat
+	}
+
+	v := url.Values{}
+	v.Set("time_range", formatTime(start)+"--"+formatTime(end))
+	v.Set("bounding_box", string(formatFloat(bounds.Max.Lat)+","+formatFloat(bounds.Min.Lon)+
+		","+formatFloat(bounds.Min.Lat)+","+formatFloat(bounds.Max.Lon)))
+	v.Set("format", string(formatString))
+	data, err := c.get(ctx, c.baseURL+"/get_lightning_list?"+v.Encode(), fs.ContentType())
+	if err != nil {
+		return nil, err
+	}
+
+	if formatString == FormatJSON.FormatString() {
+		return parseLightningJSON(data)
+	}
+	return parseLightningCSV(data)
+}
+
+// parseLightningCSV parses the lightning strikes in a CSV response.
+func parseLightningCSV(data []byte) ([]LightningStrike, error) {
+	s := newCSVScanner(bytes.NewReader(data))
+	if !s.Scan() {
+		return nil, errLightningParse
+	}
+	header := strings.Split(s.Text(), ";")
+	if len(header) != 4 ||
+		header[0] != "stroke_time:sql" ||
+		header[1] != "stroke_lat:d" ||
+		header[2] != "stroke_lon:d" ||
+		header[3] != "stroke_current:kA" {
+		return nil, errLightningParse
+	}
+	var strikes []LightningStrike
+	for s.Scan() {
+		strike, err := parseLightningRecord(s.Text())
+		if err != nil {
+			return nil, err
+		}
+		strikes = append(strikes, strike)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return strikes, nil
+}
+
+// parseLightningRecord parses the lightning strike in a line of a CSV
+// response.
+func parseLightningRecord(line string) (LightningStrike, error) {
+	record := strings.Split(line, ";")
+	if len(record) != 4 {
+		return LightningStrike{}, errLightningParse
+	}
+	var strike LightningStrike
+	var err error
+	if strike.Time, err = time.Parse(time.RFC3339, record[0]); err != nil {
+		return LightningStrike{}, err
+	}
+	if strike.Lat, err = strconv.ParseFloat(record[1], 64); err != nil {
+		return LightningStrike{}, err
+	}
+	if strike.Lon, err = strconv.ParseFloat(record[2], 64); err != nil {
+		return LightningStrike{}, err
+	}
+	if strike.Current, err = strconv.ParseFloat(record[3], 64); err != nil {
+		return LightningStrike{}, err
+	}
+	return strike, nil
+}
+
+// parseLightningJSON parses the lightning strikes in a JSON response.
+func parseLightningJSON(data []byte) ([]LightningStrike, error) {
+	var ljr lightningJSONResponse
+	if err := json.Unmarshal(data, &ljr); err != nil {
+		return nil, err
+	}
+	if ljr.Status != "OK" {
+		return nil, parseAPIStatus(ljr.Status)
+	}
+	strikes := make([]LightningStrike, 0, len(ljr.Data))
+	for _, d := range ljr.Data {
+		strikes = append(strikes, LightningStrike{
+			Time:    d.Time,
+			Lat:     d.Lat,
+			Lon:     d.Lon,
+			Current: d.Current,
+		})
+	}
+	return strikes, nil
+}
@@ -0,0 +1,79 @@
+package meteomatics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRequestLightning(t *testing.T) {
+	for _, tc := range []struct {
+		format   Format
+		filename string
+	}{
+		{
+			format:   FormatCSV,
+			filename: "testdata/lightning.csv",
+		},
+		{
+			format:   FormatJSON,
+			filename: "testdata/lightning.json",
+		},
+	} {
+		t.Run(string(tc.format.FormatString()), func(t *testing.T) {
+			s := newTestServer(
+				t,
+				"/get_lightning_list?bounding_box=65%2C-15%2C35%2C20&format="+string(tc.format.FormatString())+"&time_range=2018-05-20T12%3A00%3A00Z--2018-05-21T12%3A00%3A00Z",
+				tc.filename,
+			)
+			defer s.Close()
+
+			strikes, err := NewClient(WithBaseURL(s.URL)).RequestLightning(
+				context.Background(),
+				time.Date(2018, 5, 20, 12, 0, 0, 0, time.UTC),
+				time.Date(2018, 5, 21, 12, 0, 0, 0, time.UTC),
+				RectangleN{
+					Min: Point{
+						Lat: 35,
+						Lon: -15,
+					},
+					Max: Point{
+						Lat: 65,
+						Lon: 20,
+					},
+				},
+				tc.format,
+			)
+			require.NoError(t, err)
+			assert.Equal(t, []LightningStrike{
+				{
+					Time:    time.Date(2018, 5, 20, 12, 0, 25, 800000000, time.UTC),
+					Lat:     41.0694,
+					Lon:     16.4578,
+					Current: -19.3,
+				},
+				{
+					Time:    time.Date(2018, 5, 20, 12, 1, 2, 0, time.UTC),
+					Lat:     45.2931,
+					Lon:     9.2164,
+					Current: 24.7,
+				},
+			}, strikes)
+		})
+	}
+}
+
+func TestClientRequestLightningErrors(t *testing.T) {
+	c := NewClient()
+	start := time.Date(2018, 5, 20, 12, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	_, err := c.RequestLightning(context.Background(), start, end, Point{Lat: 47, Lon: 9}, FormatCSV)
+	assert.Equal(t, errLightningLocation, err)
+
+	_, err = c.RequestLightning(context.Background(), start, end, RectangleN{}, FormatPNG)
+	assert.Equal(t, errLightningFormat, err)
+}
@@ -1,10 +1,14 @@
 package meteomatics
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 )
 
+var errInvalidStationID = errors.New("invalid station ID")
+
 // A LocationString is a string representing a location.
 type LocationString string
 
@@ -135,6 +139,63 @@ func (p Postal) LocationString() LocationString {
 	return LocationString("postal_" + p.CountryCode + p.ZIPCode)
 }
 
+// A WMOStation is a station identified by its WMO ID, for example "06660".
+type WMOStation string
+
+// LocationString returns s as a LocationString.
+func (s WMOStation) LocationString() LocationString {
+	return LocationString("wmo_" + s)
+}
+
+// A METARStation is a station identified by its METAR ID, for example "LSZH".
+type METARStation string
+
+// LocationString returns s as a LocationString.
+func (s METARStation) LocationString() LocationString {
+	return LocationString("metar_" + s)
+}
+
+// A ProviderStation is a station identified by a provider-scoped ID, for
+// example Provider "mch" and ID "SMA".
+type ProviderStation struct {
+	Provider string
+	ID       string
+}
+
+// LocationString returns s as a LocationString.
+func (s ProviderStation) LocationString() LocationString {
+	return LocationString(s.Provider + "_" + s.ID)
+}
+
+// ParseStationID parses a station ID, as returned in JSONCoordinates.StationID,
+// into a WMOStation, METARStation, Postal, or ProviderStation.
+func ParseStationID(stationID string) (LocationStringer, error) {
+	i := strings.IndexByte(stationID, '_')
+	if i <= 0 || i == len(stationID)-1 {
+		return nil, fmt.Errorf("%q: %w", stationID, errInvalidStationID)
+	}
+	provider, id := stationID[:i], stationID[i+1:]
+	switch provider {
+	case "wmo":
+		return WMOStation(id), nil
+	case "metar":
+		return METARStation(id), nil
+	case "postal":
+		if len(id) <= 2 {
+			return nil, fmt.Errorf("%q: %w", stationID, errInvalidStationID)
+		}
+		return Postal{
+			CountryCode: id[:2],
+			ZIPCode:     id[2:],
+		}, nil
+	default:
+		return ProviderStation{
+			Provider: provider,
+			ID:       id,
+		}, nil
+	}
+}
+
 // A LocationSlice is a slice of LocationStringers.
 type LocationSlice []LocationStringer
 
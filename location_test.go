@@ -1,6 +1,7 @@
 package meteomatics
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -167,7 +168,57 @@ func TestLocationString(t *testing.T) {
 			},
 			expected: "postal_CH9014+postal_DE10117",
 		},
+		{
+			ls:       WMOStation("06660"),
+			expected: "wmo_06660",
+		},
+		{
+			ls:       METARStation("LSZH"),
+			expected: "metar_LSZH",
+		},
+		{
+			ls: ProviderStation{
+				Provider: "mch",
+				ID:       "SMA",
+			},
+			expected: "mch_SMA",
+		},
+		{
+			ls: LocationSlice{
+				WMOStation("06660"),
+				METARStation("LSZH"),
+				ProviderStation{
+					Provider: "mch",
+					ID:       "SMA",
+				},
+			},
+			expected: "wmo_06660+metar_LSZH+mch_SMA",
+		},
 	} {
 		assert.Equal(t, tc.expected, tc.ls.LocationString())
 	}
 }
+
+func TestParseStationID(t *testing.T) {
+	for _, tc := range []LocationStringer{
+		WMOStation("06660"),
+		METARStation("LSZH"),
+		ProviderStation{
+			Provider: "mch",
+			ID:       "SMA",
+		},
+		Postal{
+			CountryCode: "CH",
+			ZIPCode:     "9014",
+		},
+	} {
+		actual, err := ParseStationID(string(tc.LocationString()))
+		assert.NoError(t, err)
+		assert.Equal(t, tc, actual)
+	}
+
+	for _, stationID := range []string{"", "06660", "_06660", "wmo_", "postal_CH"} {
+		_, err := ParseStationID(stationID)
+		assert.True(t, errors.Is(err, errInvalidStationID), stationID)
+	}
+}
@@ -0,0 +1,125 @@
+// Package meteomaticstest provides utilities for testing code that uses the
+// meteomatics package without network access.
+package meteomaticstest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	"github.com/twpayne/go-meteomatics"
+)
+
+// credentialQueryKeys are the query parameters that contain credentials and
+// are ignored when matching requests.
+var credentialQueryKeys = []string{
+	"access_token",
+	"password",
+	"username",
+}
+
+// A Response is a canned response.
+type Response struct {
+	StatusCode  int // If zero, http.StatusOK is used.
+	ContentType string
+	Body        []byte
+}
+
+// A FixtureServer is a fake Meteomatics API server that serves canned
+// responses keyed by the time, parameter, location, and format path segments
+// and request options of each request. Credentials are ignored.
+type FixtureServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses map[string]Response
+	requests  []string
+}
+
+// NewFixtureServer returns a new, started, FixtureServer with no responses.
+// The caller should call Close when finished.
+func NewFixtureServer() *FixtureServer {
+	s := &FixtureServer{
+		responses: make(map[string]Response),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a new meteomatics.Client that sends requests to s, with
+// options set.
+func (s *FixtureServer) Client(options ...meteomatics.ClientOption) *meteomatics.Client {
+	return meteomatics.NewClient(append([]meteomatics.ClientOption{
+		meteomatics.WithBaseURL(s.URL),
+		meteomatics.WithHTTPClient(s.Server.Client()),
+	}, options...)...)
+}
+
+// Handle sets the response to the request for ts, ps, ls, fs, and options.
+func (s *FixtureServer) Handle(ts meteomatics.TimeStringer, ps meteomatics.ParameterStringer, ls meteomatics.LocationStringer, fs meteomatics.FormatStringer, options *meteomatics.RequestOptions, response Response) {
+	key := fmt.Sprintf("/%s/%s/%s/%s", ts.TimeString(), ps.ParameterString(), ls.LocationString(), fs.FormatString())
+	if values := options.Values(); values != nil {
+		key += "?" + values.Encode()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[key] = response
+}
+
+// HandleFile sets the response to the request for ts, ps, ls, fs, and options
+// to be the contents of filename with fs's content type.
+func (s *FixtureServer) HandleFile(ts meteomatics.TimeStringer, ps meteomatics.ParameterStringer, ls meteomatics.LocationStringer, fs meteomatics.FormatStringer, options *meteomatics.RequestOptions, filename string) error {
+	body, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	s.Handle(ts, ps, ls, fs, options, Response{
+		ContentType: fs.ContentType(),
+		Body:        body,
+	})
+	return nil
+}
+
+// Requests returns the paths and queries, excluding credentials, of all
+// requests received by s.
+func (s *FixtureServer) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *FixtureServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	key := requestKey(r.URL)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, key)
+	response, ok := s.responses[key]
+	s.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "no fixture for "+key, http.StatusNotFound)
+		return
+	}
+	if response.ContentType != "" {
+		w.Header().Set("Content-Type", response.ContentType)
+	}
+	if response.StatusCode != 0 {
+		w.WriteHeader(response.StatusCode)
+	}
+	_, _ = w.Write(response.Body)
+}
+
+// requestKey returns the path and query of u, excluding credentials.
+func requestKey(u *url.URL) string {
+	key := u.Path
+	query := u.Query()
+	for _, k := range credentialQueryKeys {
+		query.Del(k)
+	}
+	if len(query) != 0 {
+		key += "?" + query.Encode()
+	}
+	return key
+}
@@ -0,0 +1,75 @@
+package meteomaticstest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/twpayne/go-meteomatics"
+	"github.com/twpayne/go-meteomatics/meteomaticstest"
+)
+
+func TestFixtureServer(t *testing.T) {
+	s := meteomaticstest.NewFixtureServer()
+	defer s.Close()
+
+	ts := meteomatics.TimePeriod{
+		Start:    time.Date(2016, 1, 20, 13, 35, 0, 0, time.UTC),
+		Duration: 24 * time.Hour,
+		Step:     3 * time.Hour,
+	}
+	ps := meteomatics.ParameterSlice{
+		meteomatics.Parameter{
+			Name:  meteomatics.ParameterTemperature,
+			Level: meteomatics.LevelMeters(2),
+			Units: meteomatics.UnitsCelsius,
+		},
+		meteomatics.Parameter{
+			Name:  meteomatics.ParameterRelativeHumidity,
+			Level: meteomatics.LevelMeters(2),
+			Units: meteomatics.UnitsPercentage,
+		},
+	}
+	ls := meteomatics.Point{
+		Lat: 47.423336,
+		Lon: 9.377225,
+	}
+	require.NoError(t, s.HandleFile(ts, ps, ls, meteomatics.FormatCSV, nil, "../testdata/temperature_and_relative_humidity_time_series.csv"))
+	s.Handle(meteomatics.TimeNow, ps, ls, meteomatics.FormatCSV, &meteomatics.RequestOptions{Source: "mix"}, meteomaticstest.Response{
+		StatusCode: http.StatusBadRequest,
+		Body:       []byte("Unknown source"),
+	})
+
+	c := s.Client(meteomatics.WithBasicAuth("username", "password"))
+	r, err := c.RequestCSV(context.Background(), ts, ps, ls, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []meteomatics.ParameterString{"t_2m:C", "relative_humidity_2m:p"}, r.Parameters)
+	assert.Len(t, r.Rows, 9)
+
+	_, err = c.RequestCSV(context.Background(), meteomatics.TimeNow, ps, ls, &meteomatics.RequestOptions{Source: "mix"})
+	var e *meteomatics.Error
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusBadRequest, e.Response.StatusCode)
+	assert.Equal(t, "Unknown source", e.APIError.Message)
+
+	_, err = c.RequestCSV(context.Background(), meteomatics.TimeNow, ps, ls, nil)
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusNotFound, e.Response.StatusCode)
+
+	resp, err := http.Get(s.URL + "/2016-01-20T13:35:00ZP1D:PT3H/t_2m:C,relative_humidity_2m:p/47.423336,9.377225/csv?access_token=secret") //nolint:noctx
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.Equal(t, []string{
+		"/2016-01-20T13:35:00ZP1D:PT3H/t_2m:C,relative_humidity_2m:p/47.423336,9.377225/csv",
+		"/now/t_2m:C,relative_humidity_2m:p/47.423336,9.377225/csv?source=mix",
+		"/now/t_2m:C,relative_humidity_2m:p/47.423336,9.377225/csv",
+		"/2016-01-20T13:35:00ZP1D:PT3H/t_2m:C,relative_humidity_2m:p/47.423336,9.377225/csv",
+	}, s.Requests())
+}
@@ -0,0 +1,467 @@
+package meteomaticstest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits on the size of synthetic responses.
+const (
+	maxParameters = 10
+	maxTimes      = 10000
+	maxPoints     = 1000000
+)
+
+var (
+	errInvalidDuration  = errors.New("invalid duration")
+	errInvalidLocation  = errors.New("invalid location")
+	errInvalidParameter = errors.New("invalid parameter")
+	errInvalidTime      = errors.New("invalid time")
+	errTooLarge         = errors.New("request too large")
+)
+
+// A point is a point location, optionally identified by a station ID.
+type point struct {
+	lat       float64
+	lon       float64
+	stationID string
+}
+
+// A region is a regular grid of points.
+type region struct {
+	lats []float64
+	lons []float64
+}
+
+// A query is a parsed request.
+type query struct {
+	times      []time.Time
+	parameters []string
+	points     []point
+	region     *region
+}
+
+// regionShortcuts are the bounds of the location shortcuts, as max lat, min
+// lon, min lat, and max lon.
+var regionShortcuts = map[string][4]float64{
+	"world":         {90, -180, -90, 180},
+	"global":        {90, -180, -90, 180},
+	"africa":        {38, -26, -35, 60},
+	"asia":          {78, 25, -11, 180},
+	"australia":     {-10, 112, -44, 154},
+	"europe":        {72, -25, 34, 45},
+	"north-america": {84, -168, 7, -52},
+	"south-america": {13, -82, -56, -34},
+}
+
+// parseQuery parses the time, parameter, and location path segments of a
+// request.
+func parseQuery(timeSegment, parameterSegment, locationSegment string, now time.Time) (*query, error) {
+	times, err := parseTimes(timeSegment, now)
+	if err != nil {
+		return nil, err
+	}
+	parameters, err := parseParameters(parameterSegment)
+	if err != nil {
+		return nil, err
+	}
+	points, r, err := parseLocations(locationSegment)
+	if err != nil {
+		return nil, err
+	}
+	return &query{
+		times:      times,
+		parameters: parameters,
+		points:     points,
+		region:     r,
+	}, nil
+}
+
+// parseTimes parses a time path segment.
+func parseTimes(s string, now time.Time) ([]time.Time, error) {
+	var times []time.Time
+	for _, item := range strings.Split(s, ",") {
+		itemTimes, err := parseTimeItem(item, now)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, itemTimes...)
+		if len(times) > maxTimes {
+			return nil, errTooLarge
+		}
+	}
+	return times, nil
+}
+
+// parseTimeItem parses a single time, time range, or time period.
+func parseTimeItem(s string, now time.Time) ([]time.Time, error) {
+	if i := strings.Index(s, "--"); i != -1 {
+		j := strings.Index(s[i:], ":P")
+		if j == -1 {
+			return nil, fmt.Errorf("%s: %w", s, errInvalidTime)
+		}
+		start, err := parseTime(s[:i], now)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseTime(s[i+2:i+j], now)
+		if err != nil {
+			return nil, err
+		}
+		step, err := parseDuration(s[i+j+1:])
+		if err != nil {
+			return nil, err
+		}
+		return timeSteps(start, end, step)
+	}
+
+	if i := strings.IndexByte(s, 'P'); i != -1 {
+		j := strings.Index(s[i:], ":P")
+		if j == -1 {
+			return nil, fmt.Errorf("%s: %w", s, errInvalidTime)
+		}
+		start, err := parseTime(s[:i], now)
+		if err != nil {
+			return nil, err
+		}
+		duration, err := parseDuration(s[i : i+j])
+		if err != nil {
+			return nil, err
+		}
+		step, err := parseDuration(s[i+j+1:])
+		if err != nil {
+			return nil, err
+		}
+		return timeSteps(start, start.Add(duration), step)
+	}
+
+	t, err := parseTime(s, now)
+	if err != nil {
+		return nil, err
+	}
+	return []time.Time{t}, nil
+}
+
+// parseTime parses a single time.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case s == "now":
+		return now, nil
+	case s == "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case s == "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case s == "today":
+		return today, nil
+	case strings.HasPrefix(s, "now+") || strings.HasPrefix(s, "now-"):
+		offset := s[len("now+"):]
+		if len(offset) < 2 {
+			return time.Time{}, fmt.Errorf("%s: %w", s, errInvalidTime)
+		}
+		n, err := strconv.Atoi(offset[:len(offset)-1])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: %w", s, errInvalidTime)
+		}
+		var unit time.Duration
+		switch offset[len(offset)-1] {
+		case 'D':
+			unit = 24 * time.Hour
+		case 'H':
+			unit = time.Hour
+		case 'M':
+			unit = time.Minute
+		case 'S':
+			unit = time.Second
+		default:
+			return time.Time{}, fmt.Errorf("%s: %w", s, errInvalidTime)
+		}
+		d := time.Duration(n) * unit
+		if s[3] == '-' {
+			d = -d
+		}
+		return now.Add(d), nil
+	default:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: %w", s, errInvalidTime)
+		}
+		return t.UTC(), nil
+	}
+}
+
+// parseDuration parses an ISO 8601 duration, for example P1D or PT3H.
+func parseDuration(s string) (time.Duration, error) {
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("%s: %w", s, errInvalidDuration)
+	}
+	var d time.Duration
+	inTime := false
+	for rest := s[1:]; rest != ""; {
+		if rest[0] == 'T' {
+			inTime = true
+			rest = rest[1:]
+			continue
+		}
+		i := strings.IndexFunc(rest, func(r rune) bool {
+			return (r < '0' || '9' < r) && r != '.'
+		})
+		if i <= 0 {
+			return 0, fmt.Errorf("%s: %w", s, errInvalidDuration)
+		}
+		n, err := strconv.ParseFloat(rest[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", s, errInvalidDuration)
+		}
+		var unit time.Duration
+		switch {
+		case !inTime && rest[i] == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && rest[i] == 'D':
+			unit = 24 * time.Hour
+		case inTime && rest[i] == 'H':
+			unit = time.Hour
+		case inTime && rest[i] == 'M':
+			unit = time.Minute
+		case inTime && rest[i] == 'S':
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("%s: %w", s, errInvalidDuration)
+		}
+		d += time.Duration(n * float64(unit))
+		rest = rest[i+1:]
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s: %w", s, errInvalidDuration)
+	}
+	return d, nil
+}
+
+// timeSteps returns the times from start to end inclusive every step.
+func timeSteps(start, end time.Time, step time.Duration) ([]time.Time, error) {
+	var times []time.Time
+	for t := start; !t.After(end); t = t.Add(step) {
+		times = append(times, t)
+		if len(times) > maxTimes {
+			return nil, errTooLarge
+		}
+	}
+	return times, nil
+}
+
+// parseParameters parses a parameter path segment.
+func parseParameters(s string) ([]string, error) {
+	parameters := strings.Split(s, ",")
+	if len(parameters) > maxParameters {
+		return nil, fmt.Errorf("%w: more than %d parameters", errTooLarge, maxParameters)
+	}
+	for _, parameter := range parameters {
+		i := strings.IndexByte(parameter, ':')
+		if i <= 0 || i == len(parameter)-1 {
+			return nil, fmt.Errorf("%s: %w", parameter, errInvalidParameter)
+		}
+	}
+	return parameters, nil
+}
+
+// parseLocations parses a location path segment into either a list of points
+// or a region.
+func parseLocations(s string) ([]point, *region, error) {
+	var points []point
+	var r *region
+	var lineEnd *point
+	for _, item := range strings.Split(s, "+") {
+		location, spec := item, ""
+		if i := strings.IndexByte(item, ':'); i != -1 {
+			location, spec = item[:i], item[i+1:]
+		}
+
+		var itemPoints []point
+		var itemRegion *region
+		var err error
+		switch bounds, ok := regionShortcuts[location]; {
+		case ok:
+			itemRegion, err = parseRegion(bounds, spec)
+		case strings.Contains(location, "_") && strings.Contains(location, ","):
+			var ps []point
+			ps, err = parsePoints(strings.Split(location, "_"))
+			if err != nil || len(ps) != 2 {
+				return nil, nil, fmt.Errorf("%s: %w", item, errInvalidLocation)
+			}
+			if strings.Contains(spec, "x") || strings.Contains(spec, ",") {
+				itemRegion, err = parseRegion([4]float64{ps[0].lat, ps[0].lon, ps[1].lat, ps[1].lon}, spec)
+			} else {
+				itemPoints, err = linePoints(ps[0], ps[1], spec, false)
+				lineEnd = &ps[1]
+			}
+		case spec != "" && lineEnd != nil:
+			var ps []point
+			ps, err = parsePoints([]string{location})
+			if err != nil {
+				return nil, nil, err
+			}
+			itemPoints, err = linePoints(*lineEnd, ps[0], spec, true)
+			lineEnd = &ps[0]
+		case strings.Contains(location, ","):
+			itemPoints, err = parsePoints([]string{location})
+			lineEnd = nil
+		case strings.Contains(location, "_") && spec == "":
+			itemPoints = []point{stationPoint(location)}
+			lineEnd = nil
+		default:
+			err = fmt.Errorf("%s: %w", item, errInvalidLocation)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if itemRegion != nil {
+			if r != nil || len(points) != 0 {
+				return nil, nil, fmt.Errorf("%s: %w: regions cannot be combined with other locations", s, errInvalidLocation)
+			}
+			r = itemRegion
+		}
+		if len(itemPoints) != 0 && r != nil {
+			return nil, nil, fmt.Errorf("%s: %w: regions cannot be combined with other locations", s, errInvalidLocation)
+		}
+		points = append(points, itemPoints...)
+		if len(points) > maxPoints {
+			return nil, nil, errTooLarge
+		}
+	}
+	return points, r, nil
+}
+
+// parsePoints parses points of the form lat,lon.
+func parsePoints(ss []string) ([]point, error) {
+	points := make([]point, 0, len(ss))
+	for _, s := range ss {
+		latLon := strings.Split(s, ",")
+		if len(latLon) != 2 {
+			return nil, fmt.Errorf("%s: %w", s, errInvalidLocation)
+		}
+		lat, err := strconv.ParseFloat(latLon[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s, errInvalidLocation)
+		}
+		lon, err := strconv.ParseFloat(latLon[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s, errInvalidLocation)
+		}
+		points = append(points, point{
+			lat: lat,
+			lon: lon,
+		})
+	}
+	return points, nil
+}
+
+// parseRegion parses a region with the given bounds and a spec of the form
+// NLONxNLAT or RESLAT,RESLON.
+func parseRegion(bounds [4]float64, spec string) (*region, error) {
+	maxLat, minLon, minLat, maxLon := bounds[0], bounds[1], bounds[2], bounds[3]
+	if minLat > maxLat || minLon > maxLon {
+		return nil, fmt.Errorf("%s: %w", spec, errInvalidLocation)
+	}
+	r := &region{}
+	if ns := strings.Split(spec, "x"); len(ns) == 2 {
+		nLon, err := strconv.Atoi(ns[0])
+		if err != nil || nLon < 1 {
+			return nil, fmt.Errorf("%s: %w", spec, errInvalidLocation)
+		}
+		nLat, err := strconv.Atoi(ns[1])
+		if err != nil || nLat < 1 {
+			return nil, fmt.Errorf("%s: %w", spec, errInvalidLocation)
+		}
+		if nLat > maxPoints || nLon > maxPoints || nLat*nLon > maxPoints {
+			return nil, errTooLarge
+		}
+		r.lats = linspace(maxLat, minLat, nLat)
+		r.lons = linspace(minLon, maxLon, nLon)
+		return r, nil
+	}
+	if res := strings.Split(spec, ","); len(res) == 2 {
+		resLat, err := strconv.ParseFloat(res[0], 64)
+		if err != nil || resLat <= 0 {
+			return nil, fmt.Errorf("%s: %w", spec, errInvalidLocation)
+		}
+		resLon, err := strconv.ParseFloat(res[1], 64)
+		if err != nil || resLon <= 0 {
+			return nil, fmt.Errorf("%s: %w", spec, errInvalidLocation)
+		}
+		// Check the number of points before converting them to ints, which
+		// could overflow.
+		fLat := math.Floor((maxLat-minLat)/resLat+1e-9) + 1
+		fLon := math.Floor((maxLon-minLon)/resLon+1e-9) + 1
+		if !(1 <= fLat && fLat <= maxPoints && 1 <= fLon && fLon <= maxPoints) {
+			return nil, errTooLarge
+		}
+		nLat, nLon := int(fLat), int(fLon)
+		if nLat*nLon > maxPoints {
+			return nil, errTooLarge
+		}
+		for i := 0; i < nLat; i++ {
+			r.lats = append(r.lats, round(maxLat-float64(i)*resLat, 6))
+		}
+		for i := 0; i < nLon; i++ {
+			r.lons = append(r.lons, round(minLon+float64(i)*resLon, 6))
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("%s: %w", spec, errInvalidLocation)
+}
+
+// linePoints returns the points on the line from start to end. If
+// continuation is true then start is omitted, as it ends the previous segment.
+func linePoints(start, end point, spec string, continuation bool) ([]point, error) {
+	n, err := strconv.Atoi(spec)
+	if err != nil || n < 1 || n > maxPoints {
+		return nil, fmt.Errorf("%s: %w", spec, errInvalidLocation)
+	}
+	lats := linspace(start.lat, end.lat, n)
+	lons := linspace(start.lon, end.lon, n)
+	points := make([]point, 0, n)
+	for i := range lats {
+		if continuation && i == 0 {
+			continue
+		}
+		points = append(points, point{
+			lat: lats[i],
+			lon: lons[i],
+		})
+	}
+	return points, nil
+}
+
+// stationPoint returns a point for a station ID, for example postal_CH9000 or
+// wmo_06660, with a synthetic but deterministic location.
+func stationPoint(stationID string) point {
+	h := hash(stationID)
+	return point{
+		lat:       round(float64(h%18000)/100-90, 4),
+		lon:       round(float64(h/18000%36000)/100-180, 4),
+		stationID: stationID,
+	}
+}
+
+// linspace returns n values evenly spaced from start to end inclusive.
+func linspace(start, end float64, n int) []float64 {
+	if n == 1 {
+		return []float64{start}
+	}
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = round(start+(end-start)*float64(i)/float64(n-1), 6)
+	}
+	return values
+}
+
+// round rounds x to the given number of decimal places.
+func round(x float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(x*scale) / scale
+}
@@ -0,0 +1,111 @@
+package meteomaticstest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	for _, tc := range []struct {
+		s           string
+		expected    time.Duration
+		expectedErr bool
+	}{
+		{s: "P1W", expected: 7 * 24 * time.Hour},
+		{s: "P2D", expected: 48 * time.Hour},
+		{s: "PT3H", expected: 3 * time.Hour},
+		{s: "PT30M", expected: 30 * time.Minute},
+		{s: "PT10S", expected: 10 * time.Second},
+		{s: "P1DT12H", expected: 36 * time.Hour},
+		{s: "P", expectedErr: true},
+		{s: "1D", expectedErr: true},
+		{s: "P1H", expectedErr: true},
+		{s: "PT0H", expectedErr: true},
+	} {
+		actual, err := parseDuration(tc.s)
+		if tc.expectedErr {
+			assert.Error(t, err, tc.s)
+		} else {
+			assert.NoError(t, err, tc.s)
+			assert.Equal(t, tc.expected, actual, tc.s)
+		}
+	}
+}
+
+func TestParseLocations(t *testing.T) {
+	for _, tc := range []struct {
+		s              string
+		expectedPoints []point
+		expectedRegion *region
+		expectedErr    bool
+	}{
+		{
+			s: "47.41,9.35+47.51,8.74",
+			expectedPoints: []point{
+				{lat: 47.41, lon: 9.35},
+				{lat: 47.51, lon: 8.74},
+			},
+		},
+		{
+			s: "50,10_50,20:3+60,20:2",
+			expectedPoints: []point{
+				{lat: 50, lon: 10},
+				{lat: 50, lon: 15},
+				{lat: 50, lon: 20},
+				{lat: 60, lon: 20},
+			},
+		},
+		{
+			s: "50,10_40,20:3x2",
+			expectedRegion: &region{
+				lats: []float64{50, 40},
+				lons: []float64{10, 15, 20},
+			},
+		},
+		{
+			s: "50,10_40,20:5,10",
+			expectedRegion: &region{
+				lats: []float64{50, 45, 40},
+				lons: []float64{10, 20},
+			},
+		},
+		{
+			s:           "50,10_40,20:3x2+50,10",
+			expectedErr: true,
+		},
+		{
+			s:           "invalid",
+			expectedErr: true,
+		},
+		{
+			s:           "90,-180_-90,180:4294967296x4294967296",
+			expectedErr: true,
+		},
+		{
+			s:           "90,-180_-90,180:1000001x1",
+			expectedErr: true,
+		},
+		{
+			s:           "90,-180_-90,180:1e-300,1",
+			expectedErr: true,
+		},
+	} {
+		points, r, err := parseLocations(tc.s)
+		if tc.expectedErr {
+			assert.Error(t, err, tc.s)
+			continue
+		}
+		require.NoError(t, err, tc.s)
+		assert.Equal(t, tc.expectedPoints, points, tc.s)
+		assert.Equal(t, tc.expectedRegion, r, tc.s)
+	}
+
+	points, _, err := parseLocations("postal_CH9000+wmo_06660")
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, "postal_CH9000", points[0].stationID)
+	assert.Equal(t, "wmo_06660", points[1].stationID)
+}
@@ -0,0 +1,156 @@
+package meteomaticstest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/twpayne/go-meteomatics"
+)
+
+// ErrNoRecording is returned when replaying a request that was not recorded.
+var ErrNoRecording = errors.New("no recording")
+
+var errInvalidGoldenFile = errors.New("invalid golden file")
+
+// accessTokenRx matches access tokens in response bodies.
+var accessTokenRx = regexp.MustCompile(`("access_token"\s*:\s*)"[^"]*"`)
+
+// scrubbedHeaders are response headers that are not recorded.
+var scrubbedHeaders = []string{
+	"Set-Cookie",
+}
+
+// A Mode is a recording mode.
+type Mode int
+
+// Modes.
+const (
+	// ModeReplay replays recorded responses and fails requests that were not
+	// recorded.
+	ModeReplay Mode = iota
+	// ModeRecord performs real requests and records their responses.
+	ModeRecord
+	// ModeReplayOrRecord replays recorded responses, and performs and
+	// records requests that were not recorded.
+	ModeReplayOrRecord
+)
+
+// A Recorder is an http.RoundTripper that records responses into golden files
+// and replays them. Credentials are scrubbed from the recorded requests and
+// responses, so golden files can be committed.
+type Recorder struct {
+	dir       string
+	mode      Mode
+	transport http.RoundTripper
+}
+
+// NewRecorder returns a new Recorder that stores golden files in dir and uses
+// transport to perform real requests. If transport is nil then
+// http.DefaultTransport is used.
+func NewRecorder(dir string, mode Mode, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{
+		dir:       dir,
+		mode:      mode,
+		transport: transport,
+	}
+}
+
+// Client returns a new http.Client that uses r, for use with
+// meteomatics.WithHTTPClient.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{
+		Transport: r,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.Method + " " + meteomatics.RedactURL(req.URL).String()
+	filename := r.filename(key)
+
+	if r.mode != ModeRecord {
+		resp, err := replay(filename, req)
+		switch {
+		case err == nil:
+			return resp, nil
+		case !os.IsNotExist(err):
+			return nil, err
+		case r.mode == ModeReplay:
+			return nil, fmt.Errorf("%w: %s", ErrNoRecording, key)
+		}
+	}
+
+	return r.record(filename, key, req)
+}
+
+// filename returns the golden file name for key.
+func (r *Recorder) filename(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(r.dir, hex.EncodeToString(sum[:8])+".http")
+}
+
+// record performs req and records the response in filename.
+func (r *Recorder) record(filename, key string, req *http.Request) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	scrubbed := *resp
+	scrubbed.Header = resp.Header.Clone()
+	for _, header := range scrubbedHeaders {
+		scrubbed.Header.Del(header)
+	}
+	scrubbedBody := accessTokenRx.ReplaceAll(body, []byte(`$1"REDACTED"`))
+	scrubbed.Body = ioutil.NopCloser(bytes.NewReader(scrubbedBody))
+	scrubbed.ContentLength = int64(len(scrubbedBody))
+	scrubbed.Header.Set("Content-Length", strconv.Itoa(len(scrubbedBody)))
+	scrubbed.TransferEncoding = nil
+	dump, err := httputil.DumpResponse(&scrubbed, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filename, append([]byte("# "+key+"\n"), dump...), 0o644); err != nil { //nolint:gosec
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// replay returns the response recorded in filename.
+func replay(filename string, req *http.Request) (*http.Response, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(bytes.NewReader(data))
+	if line, err := br.ReadString('\n'); err != nil || !strings.HasPrefix(line, "# ") {
+		return nil, fmt.Errorf("%s: %w", filename, errInvalidGoldenFile)
+	}
+	return http.ReadResponse(br, req)
+}
@@ -0,0 +1,72 @@
+package meteomaticstest_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/twpayne/go-meteomatics"
+	"github.com/twpayne/go-meteomatics/meteomaticstest"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-meteomatics-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			w.Header().Set("Set-Cookie", "session=secret-cookie")
+			_, _ = w.Write([]byte(`{"access_token": "secret-token", "token_type": "bearer"}`))
+		default:
+			assert.Equal(t, "secret-token", r.URL.Query().Get("access_token"))
+			w.Header().Set("Content-Type", "text/csv")
+			_, _ = w.Write([]byte("validdate;t_2m:C\n2019-06-10T00:00:00Z;12.5\n"))
+		}
+	}))
+
+	request := func(mode meteomaticstest.Mode) (*meteomatics.CSVResponse, error) {
+		return meteomatics.NewClient(
+			meteomatics.WithBaseURL(s.URL),
+			meteomatics.WithHTTPClient(meteomaticstest.NewRecorder(dir, mode, nil).Client()),
+			meteomatics.WithTokenURL(s.URL+"/token"),
+			meteomatics.WithTokenAuth("username", "password"),
+		).RequestCSV(context.Background(), meteomatics.TimeNow, meteomatics.ParameterString("t_2m:C"), meteomatics.Point{Lat: 47, Lon: 9}, nil)
+	}
+
+	_, err = request(meteomaticstest.ModeReplay)
+	assert.True(t, errors.Is(err, meteomaticstest.ErrNoRecording))
+
+	r, err := request(meteomaticstest.ModeRecord)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{12.5}, r.Rows[0].Values)
+
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.http"))
+	require.NoError(t, err)
+	assert.Len(t, filenames, 2)
+	for _, filename := range filenames {
+		data, err := ioutil.ReadFile(filename)
+		require.NoError(t, err)
+		assert.False(t, strings.Contains(string(data), "secret"), string(data))
+	}
+
+	s.Close()
+
+	r, err = request(meteomaticstest.ModeReplay)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{12.5}, r.Rows[0].Values)
+
+	r, err = request(meteomaticstest.ModeReplayOrRecord)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{12.5}, r.Rows[0].Values)
+}
@@ -0,0 +1,427 @@
+package meteomaticstest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twpayne/go-meteomatics"
+)
+
+// TokenPath is the path at which a Server issues access tokens.
+const TokenPath = "/api/v1/token"
+
+var errPNGRequest = errors.New("PNG requests require a single parameter and time")
+
+// A Server is a fake Meteomatics API server that parses requests and responds
+// with deterministic synthetic data, as returned by Value.
+type Server struct {
+	*httptest.Server
+	now      func() time.Time
+	username string
+	password string
+	mu       sync.Mutex
+	tokens   map[string]bool
+}
+
+// A ServerOption sets an option on a Server.
+type ServerOption func(*Server)
+
+// A jsonError is a JSON error response.
+type jsonError struct {
+	Version       string    `json:"version"`
+	User          string    `json:"user"`
+	DateGenerated time.Time `json:"dateGenerated"`
+	Status        string    `json:"status"`
+	Message       string    `json:"message"`
+}
+
+// WithCredentials requires that requests are authenticated with username and
+// password, either with basic authentication or with an access token issued
+// at TokenPath.
+func WithCredentials(username, password string) ServerOption {
+	return func(s *Server) {
+		s.username = username
+		s.password = password
+	}
+}
+
+// WithNow sets the function used to determine the current time, for relative
+// times such as meteomatics.TimeNow.
+func WithNow(now func() time.Time) ServerOption {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// NewServer returns a new, started, Server with options set. The caller should
+// call Close when finished.
+func NewServer(options ...ServerOption) *Server {
+	s := &Server{
+		now: func() time.Time {
+			return time.Now().UTC().Truncate(time.Second)
+		},
+		tokens: make(map[string]bool),
+	}
+	for _, o := range options {
+		o(s)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a new meteomatics.Client that sends requests to s, with
+// options set.
+func (s *Server) Client(options ...meteomatics.ClientOption) *meteomatics.Client {
+	return meteomatics.NewClient(append([]meteomatics.ClientOption{
+		meteomatics.WithBaseURL(s.URL),
+		meteomatics.WithTokenURL(s.URL + TokenPath),
+		meteomatics.WithHTTPClient(s.Server.Client()),
+	}, options...)...)
+}
+
+// Value returns the synthetic value of parameter at lat, lon, and t.
+func Value(parameter string, lat, lon float64, t time.Time) float64 {
+	h := hash(parameter)
+	hours := float64(t.Unix()) / 3600
+	value := float64(h%50) +
+		10*math.Cos(lat*math.Pi/180)*math.Sin(lon*math.Pi/180+float64(h%7)) +
+		5*math.Sin(2*math.Pi*hours/24)
+	return round(value, 3)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == TokenPath {
+		s.serveToken(w, r)
+		return
+	}
+
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(segments) != 4 {
+		http.NotFound(w, r)
+		return
+	}
+	format := segments[3]
+
+	if !s.authorized(r) {
+		s.writeError(w, format, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	q, err := parseQuery(segments[0], segments[1], segments[2], s.now())
+	if err != nil {
+		s.writeError(w, format, http.StatusBadRequest, err.Error())
+		return
+	}
+	if message := outOfDomainMessage(q); message != "" {
+		s.writeError(w, format, http.StatusBadRequest, message)
+		return
+	}
+
+	route := r.URL.Query().Get("route") == "true"
+	if route && (q.region != nil || len(q.points) != len(q.times)) {
+		s.writeError(w, format, http.StatusBadRequest, "Route requests require the same number of times and locations")
+		return
+	}
+
+	var body []byte
+	var contentType string
+	switch {
+	case format == string(meteomatics.FormatCSV.FormatString()):
+		body, contentType = s.csv(q, route), meteomatics.FormatCSV.ContentType()
+	case format == string(meteomatics.FormatJSON.FormatString()):
+		body, err = s.json(q, route)
+		contentType = meteomatics.FormatJSON.ContentType()
+	case format == "png" || strings.HasPrefix(format, "png_"):
+		if q.region == nil {
+			s.writeError(w, format, http.StatusBadRequest, "PNG requests require a region")
+			return
+		}
+		body, err = s.png(q)
+		contentType = meteomatics.FormatPNG.ContentType()
+	default:
+		s.writeError(w, format, http.StatusBadRequest, "Unsupported format "+format)
+		return
+	}
+	if err != nil {
+		s.writeError(w, format, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(body)
+}
+
+// authorized returns whether r is authorized.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.username == "" && s.password == "" {
+		return true
+	}
+	if username, password, ok := r.BasicAuth(); ok {
+		return username == s.username && password == s.password
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[r.URL.Query().Get("access_token")]
+}
+
+// serveToken issues an access token.
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	if s.username != "" || s.password != "" {
+		if username, password, ok := r.BasicAuth(); !ok || username != s.username || password != s.password {
+			s.writeError(w, "json", http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		s.writeError(w, "json", http.StatusInternalServerError, err.Error())
+		return
+	}
+	token := hex.EncodeToString(b[:])
+	s.mu.Lock()
+	s.tokens[token] = true
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = fmt.Fprintf(w, `{"access_token":%q,"token_type":"bearer"}`, token)
+}
+
+// writeError writes an error response in the style of the API.
+func (s *Server) writeError(w http.ResponseWriter, format string, statusCode int, message string) {
+	if format == string(meteomatics.FormatJSON.FormatString()) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_ = json.NewEncoder(w).Encode(&jsonError{
+			Version:       "3.0",
+			User:          s.username,
+			DateGenerated: s.now(),
+			Status:        "error",
+			Message:       message,
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write([]byte(message + "\n"))
+}
+
+// csv returns q's response in CSV format.
+func (s *Server) csv(q *query, route bool) []byte {
+	b := &bytes.Buffer{}
+	switch {
+	case route:
+		fmt.Fprintf(b, "lat;lon;validdate;%s\n", strings.Join(q.parameters, ";"))
+		for i, p := range q.points {
+			fmt.Fprintf(b, "%s;%s;%s", formatFloat(p.lat), formatFloat(p.lon), formatTime(q.times[i]))
+			for _, parameter := range q.parameters {
+				fmt.Fprintf(b, ";%s", formatFloat(Value(parameter, p.lat, p.lon, q.times[i])))
+			}
+			b.WriteByte('\n')
+		}
+	case q.region != nil:
+		for _, t := range q.times {
+			for _, parameter := range q.parameters {
+				fmt.Fprintf(b, "validdate;%s\n", t.Format("2006-01-02 15:04:05"))
+				fmt.Fprintf(b, "parameter;%s\n", parameter)
+				b.WriteString("data")
+				for _, lon := range q.region.lons {
+					fmt.Fprintf(b, ";%s", formatFloat(lon))
+				}
+				b.WriteByte('\n')
+				for _, lat := range q.region.lats {
+					b.WriteString(formatFloat(lat))
+					for _, lon := range q.region.lons {
+						fmt.Fprintf(b, ";%s", formatFloat(Value(parameter, lat, lon, t)))
+					}
+					b.WriteByte('\n')
+				}
+			}
+		}
+	default:
+		var prefix func(point) string
+		switch {
+		case len(q.points) == 1:
+			b.WriteString("validdate")
+			prefix = func(point) string { return "" }
+		case allStations(q.points):
+			b.WriteString("station_id;validdate")
+			prefix = func(p point) string { return p.stationID + ";" }
+		default:
+			b.WriteString("lat;lon;validdate")
+			prefix = func(p point) string { return formatFloat(p.lat) + ";" + formatFloat(p.lon) + ";" }
+		}
+		fmt.Fprintf(b, ";%s\n", strings.Join(q.parameters, ";"))
+		for _, p := range q.points {
+			for _, t := range q.times {
+				fmt.Fprintf(b, "%s%s", prefix(p), formatTime(t))
+				for _, parameter := range q.parameters {
+					fmt.Fprintf(b, ";%s", formatFloat(Value(parameter, p.lat, p.lon, t)))
+				}
+				b.WriteByte('\n')
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+// json returns q's response in JSON format.
+func (s *Server) json(q *query, route bool) ([]byte, error) {
+	if route {
+		jrr := &meteomatics.JSONRouteResponse{
+			Version:       "3.0",
+			User:          s.username,
+			DateGenerated: s.now(),
+			Status:        "OK",
+		}
+		for i, p := range q.points {
+			data := meteomatics.JSONRouteData{
+				Lat:  p.lat,
+				Lon:  p.lon,
+				Date: q.times[i],
+			}
+			for _, parameter := range q.parameters {
+				data.Parameters = append(data.Parameters, meteomatics.JSONRouteParameter{
+					Parameter: meteomatics.ParameterString(parameter),
+					Value:     Value(parameter, p.lat, p.lon, q.times[i]),
+				})
+			}
+			jrr.Data = append(jrr.Data, data)
+		}
+		return json.Marshal(jrr)
+	}
+
+	points := q.points
+	if q.region != nil {
+		points = make([]point, 0, len(q.region.lats)*len(q.region.lons))
+		for _, lat := range q.region.lats {
+			for _, lon := range q.region.lons {
+				points = append(points, point{
+					lat: lat,
+					lon: lon,
+				})
+			}
+		}
+	}
+	jr := &meteomatics.JSONResponse{
+		Version:       "3.0",
+		User:          s.username,
+		DateGenerated: s.now(),
+		Status:        "OK",
+	}
+	for _, parameter := range q.parameters {
+		data := meteomatics.JSONData{
+			Parameter: meteomatics.ParameterString(parameter),
+		}
+		for _, p := range points {
+			coordinates := meteomatics.JSONCoordinates{
+				Lat:       p.lat,
+				Lon:       p.lon,
+				StationID: p.stationID,
+			}
+			for _, t := range q.times {
+				coordinates.Dates = append(coordinates.Dates, meteomatics.JSONDate{
+					Date:  t,
+					Value: Value(parameter, p.lat, p.lon, t),
+				})
+			}
+			data.Coordinates = append(data.Coordinates, coordinates)
+		}
+		jr.Data = append(jr.Data, data)
+	}
+	return json.Marshal(jr)
+}
+
+// png returns the first parameter at the first time of q as a grayscale PNG
+// image, scaled between the minimum and maximum values.
+func (s *Server) png(q *query) ([]byte, error) {
+	if len(q.parameters) != 1 || len(q.times) != 1 {
+		return nil, errPNGRequest
+	}
+	values := make([][]float64, len(q.region.lats))
+	minValue, maxValue := math.Inf(1), math.Inf(-1)
+	for i, lat := range q.region.lats {
+		values[i] = make([]float64, len(q.region.lons))
+		for j, lon := range q.region.lons {
+			value := Value(q.parameters[0], lat, lon, q.times[0])
+			values[i][j] = value
+			minValue = math.Min(minValue, value)
+			maxValue = math.Max(maxValue, value)
+		}
+	}
+	img := image.NewGray(image.Rect(0, 0, len(q.region.lons), len(q.region.lats)))
+	for i := range values {
+		for j, value := range values[i] {
+			var y uint8
+			if maxValue > minValue {
+				y = uint8(math.Round(255 * (value - minValue) / (maxValue - minValue)))
+			}
+			img.SetGray(j, i, color.Gray{Y: y})
+		}
+	}
+	b := &bytes.Buffer{}
+	if err := png.Encode(b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// outOfDomainMessage returns an error message in the style of the API if any
+// location in q is outside the world, or the empty string otherwise.
+func outOfDomainMessage(q *query) string {
+	minLat, minLon, maxLat, maxLon := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	extend := func(lat, lon float64) {
+		minLat, maxLat = math.Min(minLat, lat), math.Max(maxLat, lat)
+		minLon, maxLon = math.Min(minLon, lon), math.Max(maxLon, lon)
+	}
+	for _, p := range q.points {
+		extend(p.lat, p.lon)
+	}
+	if q.region != nil {
+		extend(q.region.lats[0], q.region.lons[0])
+		extend(q.region.lats[len(q.region.lats)-1], q.region.lons[len(q.region.lons)-1])
+	}
+	if -90 <= minLat && maxLat <= 90 && -180 <= minLon && maxLon <= 180 {
+		return ""
+	}
+	return fmt.Sprintf("Not enough data outside temporal and/or spatial domain  models: (SYNTHETIC)"+
+		"(Model synthetic not available at queried location. Available domain 90,-180_-90,180. Queried domain: %s,%s_%s,%s.)",
+		formatFloat(maxLat), formatFloat(minLon), formatFloat(minLat), formatFloat(maxLon))
+}
+
+// allStations returns whether all points are stations.
+func allStations(points []point) bool {
+	for _, p := range points {
+		if p.stationID == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func formatFloat(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func hash(s string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum32()
+}
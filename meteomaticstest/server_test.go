@@ -0,0 +1,375 @@
+package meteomaticstest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/png"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/twpayne/go-meteomatics"
+	"github.com/twpayne/go-meteomatics/meteomaticstest"
+)
+
+func TestServerRequestCSV(t *testing.T) {
+	s := meteomaticstest.NewServer()
+	defer s.Close()
+
+	start := time.Date(2016, 1, 20, 13, 35, 0, 0, time.UTC)
+	r, err := s.Client().RequestCSV(
+		context.Background(),
+		meteomatics.TimePeriod{
+			Start:    start,
+			Duration: 24 * time.Hour,
+			Step:     3 * time.Hour,
+		},
+		meteomatics.ParameterSlice{
+			meteomatics.Parameter{
+				Name:  meteomatics.ParameterTemperature,
+				Level: meteomatics.LevelMeters(2),
+				Units: meteomatics.UnitsCelsius,
+			},
+			meteomatics.Parameter{
+				Name:     meteomatics.ParameterPrecipitation,
+				Interval: meteomatics.Interval1H,
+				Units:    meteomatics.UnitsMillimeters,
+			},
+		},
+		meteomatics.Point{
+			Lat: 47.423336,
+			Lon: 9.377225,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []meteomatics.ParameterString{"t_2m:C", "precip_1h:mm"}, r.Parameters)
+	require.Len(t, r.Rows, 9)
+	for i, row := range r.Rows {
+		validDate := start.Add(time.Duration(i) * 3 * time.Hour)
+		assert.Equal(t, validDate, row.ValidDate)
+		assert.Equal(t, []float64{
+			meteomaticstest.Value("t_2m:C", 47.423336, 9.377225, validDate),
+			meteomaticstest.Value("precip_1h:mm", 47.423336, 9.377225, validDate),
+		}, row.Values)
+	}
+}
+
+func TestServerRequestCSVRelativeTime(t *testing.T) {
+	now := time.Date(2019, 6, 10, 12, 0, 0, 0, time.UTC)
+	s := meteomaticstest.NewServer(meteomaticstest.WithNow(func() time.Time { return now }))
+	defer s.Close()
+
+	r, err := s.Client().RequestCSV(
+		context.Background(),
+		meteomatics.TimeSlice{
+			meteomatics.TimeYesterday,
+			meteomatics.NowOffset(-30 * time.Minute),
+			meteomatics.TimeRange{
+				Start: now,
+				End:   now.Add(2 * time.Hour),
+				Step:  time.Hour,
+			},
+		},
+		meteomatics.ParameterString("t_2m:C"),
+		meteomatics.Point{},
+		nil,
+	)
+	require.NoError(t, err)
+	var validDates []time.Time
+	for _, row := range r.Rows {
+		validDates = append(validDates, row.ValidDate)
+	}
+	assert.Equal(t, []time.Time{
+		time.Date(2019, 6, 9, 0, 0, 0, 0, time.UTC),
+		time.Date(2019, 6, 10, 11, 30, 0, 0, time.UTC),
+		time.Date(2019, 6, 10, 12, 0, 0, 0, time.UTC),
+		time.Date(2019, 6, 10, 13, 0, 0, 0, time.UTC),
+		time.Date(2019, 6, 10, 14, 0, 0, 0, time.UTC),
+	}, validDates)
+}
+
+func TestServerRequestCSVRegion(t *testing.T) {
+	s := meteomaticstest.NewServer()
+	defer s.Close()
+
+	validDate := time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC)
+	r, err := s.Client().RequestCSVRegion(
+		context.Background(),
+		meteomatics.Time(validDate),
+		meteomatics.ParameterString("t_2m:C"),
+		meteomatics.RectangleN{
+			Min: meteomatics.Point{
+				Lat: -90,
+				Lon: -180,
+			},
+			Max: meteomatics.Point{
+				Lat: 90,
+				Lon: 180,
+			},
+			NLat: 10,
+			NLon: 10,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, validDate, r.ValidDate)
+	assert.Equal(t, meteomatics.ParameterString("t_2m:C"), r.Parameter)
+	assert.Equal(t, []float64{-180, -140, -100, -60, -20, 20, 60, 100, 140, 180}, r.Lons)
+	assert.Equal(t, []float64{90, 70, 50, 30, 10, -10, -30, -50, -70, -90}, r.Lats)
+	assert.Equal(t, meteomaticstest.Value("t_2m:C", 70, -140, validDate), r.Values[1][1])
+}
+
+func TestServerRequestCSVRoute(t *testing.T) {
+	s := meteomaticstest.NewServer()
+	defer s.Close()
+
+	start := time.Date(2018, 10, 19, 12, 0, 0, 0, time.UTC)
+	r, err := s.Client().RequestCSVRoute(
+		context.Background(),
+		meteomatics.TimePeriod{
+			Start:    start,
+			Duration: time.Hour,
+			Step:     30 * time.Minute,
+		},
+		meteomatics.ParameterString("t_2m:C"),
+		meteomatics.Line{
+			Start: meteomatics.Point{Lat: 47, Lon: 9},
+			End:   meteomatics.Point{Lat: 45, Lon: 7},
+			N:     3,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	require.Len(t, r.Rows, 3)
+	assert.Equal(t, 46.0, r.Rows[1].Lat)
+	assert.Equal(t, 8.0, r.Rows[1].Lon)
+	assert.Equal(t, start.Add(30*time.Minute), r.Rows[1].ValidDate)
+	assert.Equal(t, []float64{meteomaticstest.Value("t_2m:C", 46, 8, start.Add(30*time.Minute))}, r.Rows[1].Values)
+}
+
+func TestServerRequestCSVLocations(t *testing.T) {
+	s := meteomaticstest.NewServer()
+	defer s.Close()
+
+	start := time.Date(2018, 10, 19, 12, 0, 0, 0, time.UTC)
+	timePeriod := meteomatics.TimePeriod{
+		Start:    start,
+		Duration: time.Hour,
+		Step:     time.Hour,
+	}
+
+	r, err := s.Client().RequestCSVLocations(
+		context.Background(),
+		timePeriod,
+		meteomatics.ParameterString("t_2m:C"),
+		meteomatics.PointList{
+			{Lat: 47, Lon: 9},
+			{Lat: 46, Lon: 8},
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []meteomatics.ParameterString{"t_2m:C"}, r.Parameters)
+	require.Len(t, r.Rows, 4)
+	assert.Equal(t, 46.0, r.Rows[3].Lat)
+	assert.Equal(t, 8.0, r.Rows[3].Lon)
+	assert.Equal(t, "", r.Rows[3].StationID)
+	assert.Equal(t, start.Add(time.Hour), r.Rows[3].ValidDate)
+	assert.Equal(t, []float64{meteomaticstest.Value("t_2m:C", 46, 8, start.Add(time.Hour))}, r.Rows[3].Values)
+
+	r, err = s.Client().RequestCSVLocations(
+		context.Background(),
+		timePeriod,
+		meteomatics.ParameterString("t_2m:C"),
+		meteomatics.LocationSlice{
+			meteomatics.Postal{CountryCode: "CH", ZIPCode: "9000"},
+			meteomatics.LocationString("wmo_06660"),
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	require.Len(t, r.Rows, 4)
+	assert.Equal(t, "postal_CH9000", r.Rows[0].StationID)
+	assert.Equal(t, "wmo_06660", r.Rows[2].StationID)
+	assert.Equal(t, start, r.Rows[2].ValidDate)
+	assert.Len(t, r.Rows[2].Values, 1)
+}
+
+func TestServerRequestJSON(t *testing.T) {
+	s := meteomaticstest.NewServer()
+	defer s.Close()
+
+	r, err := s.Client().RequestJSON(
+		context.Background(),
+		meteomatics.TimeNow,
+		meteomatics.ParameterString("t_2m:C"),
+		meteomatics.LocationSlice{
+			meteomatics.Point{Lat: 50, Lon: 10},
+			meteomatics.Postal{CountryCode: "CH", ZIPCode: "9000"},
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "OK", r.Status)
+	require.Len(t, r.Data, 1)
+	require.Len(t, r.Data[0].Coordinates, 2)
+	assert.Equal(t, 50.0, r.Data[0].Coordinates[0].Lat)
+	assert.Equal(t, "", r.Data[0].Coordinates[0].StationID)
+	assert.Equal(t, "postal_CH9000", r.Data[0].Coordinates[1].StationID)
+	require.Len(t, r.Data[0].Coordinates[1].Dates, 1)
+}
+
+func TestServerRequestJSONStations(t *testing.T) {
+	s := meteomaticstest.NewServer()
+	defer s.Close()
+
+	stations := meteomatics.LocationSlice{
+		meteomatics.WMOStation("06660"),
+		meteomatics.METARStation("LSZH"),
+		meteomatics.ProviderStation{Provider: "mch", ID: "SMA"},
+	}
+	r, err := s.Client().RequestJSON(
+		context.Background(),
+		meteomatics.TimeNow,
+		meteomatics.ParameterString("t_2m:C"),
+		stations,
+		&meteomatics.RequestOptions{
+			Source: meteomatics.ModelMixObs,
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, r.Data, 1)
+	require.Len(t, r.Data[0].Coordinates, len(stations))
+	for i, coordinates := range r.Data[0].Coordinates {
+		station, err := coordinates.Station()
+		require.NoError(t, err)
+		assert.Equal(t, stations[i], station)
+	}
+}
+
+func TestServerRequestJSONRoute(t *testing.T) {
+	s := meteomaticstest.NewServer()
+	defer s.Close()
+
+	r, err := s.Client().RequestJSONRoute(
+		context.Background(),
+		meteomatics.TimeSlice{
+			meteomatics.TimeNow,
+			meteomatics.NowOffset(time.Hour),
+			meteomatics.NowOffset(2 * time.Hour),
+		},
+		meteomatics.ParameterString("t_2m:C"),
+		meteomatics.Polyline{
+			Start: meteomatics.Point{Lat: 50, Lon: 10},
+			Segments: []meteomatics.PolylineSegment{
+				{End: meteomatics.Point{Lat: 50, Lon: 20}, N: 2},
+				{End: meteomatics.Point{Lat: 60, Lon: 20}, N: 2},
+			},
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	require.Len(t, r.Data, 3)
+	assert.Equal(t, 60.0, r.Data[2].Lat)
+	assert.Equal(t, 20.0, r.Data[2].Lon)
+}
+
+func TestServerRequestPNG(t *testing.T) {
+	s := meteomaticstest.NewServer()
+	defer s.Close()
+
+	data, err := s.Client().Request(
+		context.Background(),
+		meteomatics.TimeNow,
+		meteomatics.ParameterString("t_2m:C"),
+		meteomatics.RectangleRes{
+			Min:    meteomatics.Point{Lat: 40, Lon: 10},
+			Max:    meteomatics.Point{Lat: 50, Lon: 20},
+			ResLat: 0.5,
+			ResLon: 1,
+		},
+		meteomatics.FormatPNGJet,
+		nil,
+	)
+	require.NoError(t, err)
+	i, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 11, i.Bounds().Dx())
+	assert.Equal(t, 21, i.Bounds().Dy())
+}
+
+func TestServerErrors(t *testing.T) {
+	s := meteomaticstest.NewServer()
+	defer s.Close()
+
+	for _, tc := range []struct {
+		name               string
+		ps                 meteomatics.ParameterStringer
+		ls                 meteomatics.LocationStringer
+		fs                 meteomatics.FormatStringer
+		expectedStatusCode int
+		expectedSentinel   error
+	}{
+		{
+			name:               "out_of_domain",
+			ps:                 meteomatics.ParameterString("t_2m:C"),
+			ls:                 meteomatics.Point{Lat: 0, Lon: 190},
+			fs:                 meteomatics.FormatJSON,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedSentinel:   meteomatics.ErrOutOfDomain,
+		},
+		{
+			name:               "invalid_parameter",
+			ps:                 meteomatics.ParameterString("t_2m"),
+			ls:                 meteomatics.Point{},
+			fs:                 meteomatics.FormatCSV,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedSentinel:   meteomatics.ErrInvalidParameter,
+		},
+		{
+			name:               "invalid_location",
+			ps:                 meteomatics.ParameterString("t_2m:C"),
+			ls:                 meteomatics.LocationString("invalid"),
+			fs:                 meteomatics.FormatCSV,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "png_without_region",
+			ps:                 meteomatics.ParameterString("t_2m:C"),
+			ls:                 meteomatics.Point{},
+			fs:                 meteomatics.FormatPNG,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.Client().Request(context.Background(), meteomatics.TimeNow, tc.ps, tc.ls, tc.fs, nil)
+			var e *meteomatics.Error
+			require.True(t, errors.As(err, &e))
+			assert.Equal(t, tc.expectedStatusCode, e.Response.StatusCode)
+			if tc.expectedSentinel != nil {
+				assert.True(t, errors.Is(err, tc.expectedSentinel))
+			}
+		})
+	}
+}
+
+func TestServerCredentials(t *testing.T) {
+	s := meteomaticstest.NewServer(meteomaticstest.WithCredentials("username", "password"))
+	defer s.Close()
+
+	request := func(c *meteomatics.Client) error {
+		_, err := c.RequestCSV(context.Background(), meteomatics.TimeNow, meteomatics.ParameterString("t_2m:C"), meteomatics.Point{}, nil)
+		return err
+	}
+
+	assert.True(t, errors.Is(request(s.Client()), meteomatics.ErrUnauthorized))
+	assert.True(t, errors.Is(request(s.Client(meteomatics.WithBasicAuth("username", "wrong"))), meteomatics.ErrUnauthorized))
+	assert.NoError(t, request(s.Client(meteomatics.WithBasicAuth("username", "password"))))
+	assert.True(t, errors.Is(request(s.Client(meteomatics.WithTokenAuth("username", "wrong"))), meteomatics.ErrUnauthorized))
+	assert.NoError(t, request(s.Client(meteomatics.WithTokenAuth("username", "password"))))
+}
@@ -0,0 +1,76 @@
+package meteomatics
+
+import (
+	"math"
+	"strconv"
+)
+
+// Sentinel values used by the API to encode missing data.
+const (
+	InvalidValue       = -999
+	NotApplicableValue = -666
+)
+
+// DefaultMissingValues are the sentinel values used by the API to encode
+// missing data.
+//
+//nolint:gochecknoglobals
+var DefaultMissingValues = []float64{InvalidValue, NotApplicableValue}
+
+// missingValues is a set of sentinel values that represent missing data.
+type missingValues []float64
+
+// WithMissingValues sets the sentinel values that are replaced by NaN in
+// parsed CSV, JSON, and XML responses. Empty CSV cells and null JSON values
+// are always replaced by NaN. See also RequestOptions.FillWithInvalid.
+func WithMissingValues(values ...float64) ClientOption {
+	return func(c *Client) {
+		c.missingValues = append(missingValues(nil), values...)
+	}
+}
+
+// parseValue parses a value, returning NaN if s is empty.
+func parseValue(s string) (float64, error) {
+	if s == "" {
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// replace returns NaN if x is in m, and x otherwise.
+func (m missingValues) replace(x float64) float64 {
+	for _, value := range m {
+		if x == value {
+			return math.NaN()
+		}
+	}
+	return x
+}
+
+// replaceJSONResponse replaces the missing values in jr with NaN.
+func (m missingValues) replaceJSONResponse(jr *JSONResponse) {
+	if len(m) == 0 {
+		return
+	}
+	for i := range jr.Data {
+		for j := range jr.Data[i].Coordinates {
+			dates := jr.Data[i].Coordinates[j].Dates
+			for k := range dates {
+				dates[k].Value = m.replace(dates[k].Value)
+			}
+		}
+	}
+}
+
+// replaceJSONRouteResponse replaces the missing values in jrr with NaN.
+func (m missingValues) replaceJSONRouteResponse(jrr *JSONRouteResponse) {
+	if len(m) == 0 {
+		return
+	}
+	for i := range jrr.Data {
+		parameters := jrr.Data[i].Parameters
+		for j := range parameters {
+			parameters[j].Value = m.replace(parameters[j].Value)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package meteomatics
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientMissingValuesCSV(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "fill_with_invalid", r.URL.Query().Get("on_invalid"))
+		_, _ = w.Write([]byte("validdate;t_2m:C;wind_speed_10m:ms\n" +
+			"2016-01-20T13:35:00Z;-999;\n" +
+			"2016-01-20T14:35:00Z;1.5;-666\n"))
+	}))
+	defer s.Close()
+
+	for _, tc := range []struct {
+		name          string
+		options       []ClientOption
+		expectedValue float64
+	}{
+		{
+			name:          "none",
+			expectedValue: -666,
+		},
+		{
+			name:          "default",
+			options:       []ClientOption{WithMissingValues(DefaultMissingValues...)},
+			expectedValue: math.NaN(),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewClient(append(tc.options, WithBaseURL(s.URL))...).RequestCSV(
+				context.Background(),
+				Time(time.Date(2016, 1, 20, 13, 35, 0, 0, time.UTC)),
+				ParameterSlice{
+					ParameterString("t_2m:C"),
+					ParameterString("wind_speed_10m:ms"),
+				},
+				Point{
+					Lat: 47.423336,
+					Lon: 9.377225,
+				},
+				&RequestOptions{
+					FillWithInvalid: true,
+				},
+			)
+			require.NoError(t, err)
+			require.Len(t, r.Rows, 2)
+			assert.True(t, math.IsNaN(r.Rows[0].Values[1]))
+			assert.Equal(t, 1.5, r.Rows[1].Values[0])
+			if math.IsNaN(tc.expectedValue) {
+				assert.True(t, math.IsNaN(r.Rows[0].Values[0]))
+				assert.True(t, math.IsNaN(r.Rows[1].Values[1]))
+			} else {
+				assert.Equal(t, float64(InvalidValue), r.Rows[0].Values[0])
+				assert.Equal(t, tc.expectedValue, r.Rows[1].Values[1])
+			}
+		})
+	}
+}
+
+func TestClientMissingValuesJSON(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"version":"3.0","user":"test","dateGenerated":"2020-01-01T00:00:00Z","status":"OK","data":[` +
+			`{"parameter":"t_2m:C","coordinates":[{"lat":47.423336,"lon":9.377225,"dates":[` +
+			`{"date":"2016-01-20T13:35:00Z","value":-666},` +
+			`{"date":"2016-01-20T14:35:00Z","value":null},` +
+			`{"date":"2016-01-20T15:35:00Z","value":1.5}]}]}]}`))
+	}))
+	defer s.Close()
+
+	r, err := NewClient(WithBaseURL(s.URL), WithMissingValues(DefaultMissingValues...)).RequestJSON(
+		context.Background(),
+		Time(time.Date(2016, 1, 20, 13, 35, 0, 0, time.UTC)),
+		ParameterString("t_2m:C"),
+		Point{
+			Lat: 47.423336,
+			Lon: 9.377225,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	dates := r.Data[0].Coordinates[0].Dates
+	require.Len(t, dates, 3)
+	assert.True(t, math.IsNaN(dates[0].Value))
+	assert.True(t, math.IsNaN(dates[1].Value))
+	assert.Equal(t, 1.5, dates[2].Value)
+}
@@ -0,0 +1,97 @@
+package meteomatics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// A Model is a model identifier.
+type Model string
+
+// Models.
+const (
+	ModelMix             Model = "mix"
+	ModelECMWFIFS        Model = "ecmwf-ifs"
+	ModelECMWFERA5       Model = "ecmwf-era5"
+	ModelECMWFERAInterim Model = "ecmwf-era-interim"
+	ModelNCEPGFS         Model = "ncep-gfs"
+	ModelMMSwiss1k       Model = "mm-swiss1k"
+	ModelUKMOEuro4       Model = "ukmo-euro4"
+)
+
+// ModelMixObs is a mix of observations at station locations.
+const ModelMixObs Model = "mix-obs"
+
+// Ensemble models.
+const (
+	ModelECMWFVAREPS Model = "ecmwf-vareps"
+	ModelECMWFMMSF   Model = "ecmwf-mmsf"
+	ModelECMWFENS    Model = "ecmwf-ens"
+	ModelNCEPGEFS    Model = "ncep-gefs"
+)
+
+// An AvailableTimeRange is the time range for which a model provides a
+// parameter.
+type AvailableTimeRange struct {
+	Parameter ParameterString
+	Start     time.Time
+	End       time.Time
+}
+
+// timeRangeJSONResponse is a JSON response to a get_time_range request.
+type timeRangeJSONResponse struct {
+	Status string `json:"status"`
+	Data   []struct {
+		Parameter ParameterString `json:"parameter"`
+		MinDate   time.Time       `json:"min_date"`
+		MaxDate   time.Time       `json:"max_date"`
+	} `json:"data"`
+}
+
+// GetAvailableTimeRange returns the time ranges for which model provides the
+// parameters ps.
+func (c *Client) GetAvailableTimeRange(ctx context.Context, model Model, ps ParameterStringer) ([]AvailableTimeRange, error) {
+	v := url.Values{}
+	v.Set("model", string(model))
+	v.Set("parameters", string(ps.ParameterString()))
+	data, err := c.get(ctx, c.baseURL+"/get_time_range?"+v.Encode(), FormatJSON.ContentType())
+	if err != nil {
+		return nil, err
+	}
+
+	var trjr timeRangeJSONResponse
+	if err := json.Unmarshal(data, &trjr); err != nil {
+		return nil, err
+	}
+	if trjr.Status != "OK" {
+		return nil, parseAPIStatus(trjr.Status)
+	}
+	availableTimeRanges := make([]AvailableTimeRange, 0, len(trjr.Data))
+	for _, d := range trjr.Data {
+		availableTimeRanges = append(availableTimeRanges, AvailableTimeRange{
+			Parameter: d.Parameter,
+			Start:     d.MinDate,
+			End:       d.MaxDate,
+		})
+	}
+	return availableTimeRanges, nil
+}
+
+// Contains returns whether r contains all times between start and end.
+func (r AvailableTimeRange) Contains(start, end time.Time) bool {
+	return !start.Before(r.Start) && !end.After(r.End)
+}
+
+// Check returns an error wrapping ErrOutOfDomain if r does not contain all
+// times between start and end, allowing out-of-range requests to fail before
+// they are sent.
+func (r AvailableTimeRange) Check(start, end time.Time) error {
+	if r.Contains(start, end) {
+		return nil
+	}
+	return fmt.Errorf("%s: %s--%s outside %s--%s: %w", r.Parameter,
+		formatTime(start), formatTime(end), formatTime(r.Start), formatTime(r.End), ErrOutOfDomain)
+}
@@ -0,0 +1,59 @@
+package meteomatics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientGetAvailableTimeRange(t *testing.T) {
+	s := newTestServer(
+		t,
+		"/get_time_range?model=ecmwf-ifs&parameters=t_2m%3AC",
+		"testdata/time_range.json",
+	)
+	defer s.Close()
+
+	availableTimeRanges, err := NewClient(WithBaseURL(s.URL)).GetAvailableTimeRange(
+		context.Background(),
+		ModelECMWFIFS,
+		Parameter{
+			Name:  ParameterTemperature,
+			Level: LevelMeters(2),
+			Units: UnitsCelsius,
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []AvailableTimeRange{
+		{
+			Parameter: "t_2m:C",
+			Start:     time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC),
+			End:       time.Date(2019, 6, 20, 0, 0, 0, 0, time.UTC),
+		},
+	}, availableTimeRanges)
+
+	r := availableTimeRanges[0]
+	assert.NoError(t, r.Check(time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2019, 6, 20, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, errors.Is(r.Check(time.Date(2019, 6, 10, 0, 0, 0, 0, time.UTC), time.Date(2019, 6, 21, 0, 0, 0, 0, time.UTC)), ErrOutOfDomain))
+	assert.True(t, errors.Is(r.Check(time.Date(2019, 5, 31, 0, 0, 0, 0, time.UTC), time.Date(2019, 6, 10, 0, 0, 0, 0, time.UTC)), ErrOutOfDomain))
+}
+
+func TestClientGetAvailableTimeRangeError(t *testing.T) {
+	s := newTestServer(
+		t,
+		"/get_time_range?model=ecmwf-era-interim&parameters=t_2m%3AC",
+		"testdata/out_of_range_error.json",
+	)
+	defer s.Close()
+
+	_, err := NewClient(WithBaseURL(s.URL)).GetAvailableTimeRange(
+		context.Background(),
+		ModelECMWFERAInterim,
+		ParameterString("t_2m:C"),
+	)
+	assert.True(t, errors.Is(err, ErrOutOfDomain))
+}
@@ -0,0 +1,647 @@
+package meteomatics
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+// NetCDF errors.
+var (
+	ErrNetCDF4Unsupported = errors.New("unsupported NetCDF-4/HDF5 feature")
+	errNetCDFParse        = errors.New("netcdf parse error")
+)
+
+// NetCDF classic format constants.
+const (
+	netCDFAbsent    = 0x00
+	netCDFDimension = 0x0a
+	netCDFVariable  = 0x0b
+	netCDFAttribute = 0x0c
+	netCDFStreaming = 0xffffffff
+)
+
+// netCDFMinListElementSize is the minimum size of an element of a dim_list,
+// att_list, or var_list, each of which starts with a name and is followed by
+// at least one four-byte word.
+const netCDFMinListElementSize = 8
+
+// NetCDF classic format types.
+const (
+	netCDFByte   = 1
+	netCDFChar   = 2
+	netCDFShort  = 3
+	netCDFInt    = 4
+	netCDFFloat  = 5
+	netCDFDouble = 6
+	netCDFUByte  = 7
+	netCDFUShort = 8
+	netCDFUInt   = 9
+	netCDFInt64  = 10
+	netCDFUInt64 = 11
+)
+
+//nolint:gochecknoglobals
+var (
+	netCDFMagic = []byte("CDF")
+	hdf5Magic   = []byte("\x89HDF\r\n\x1a\n")
+)
+
+// A NetCDFDimension is a NetCDF dimension.
+type NetCDFDimension struct {
+	Name      string
+	Len       int
+	Unlimited bool
+}
+
+// A NetCDFVariable is a NetCDF variable. Attributes' values are strings for
+// character attributes, []strings for NetCDF-4 string attributes with more
+// than one value, and []float64s otherwise. Values are stored in
+// row-major order, converted to float64, with any scale_factor and add_offset
+// applied and any _FillValue replaced by NaN.
+type NetCDFVariable struct {
+	Name       string
+	Dimensions []string
+	Attributes map[string]interface{}
+	Values     []float64
+}
+
+// A NetCDFFile is a parsed NetCDF file.
+type NetCDFFile struct {
+	Dimensions []NetCDFDimension
+	Attributes map[string]interface{}
+	Variables  []*NetCDFVariable
+}
+
+// A NetCDFResponse is a response to a NetCDF request. Lats, Lons, and Times
+// are the values of the lat, lon, and time coordinate variables, if present.
+// Data contains the remaining variables, one for each parameter.
+type NetCDFResponse struct {
+	Dimensions []NetCDFDimension
+	Attributes map[string]interface{}
+	Lats       []float64
+	Lons       []float64
+	Times      []time.Time
+	Data       []*NetCDFVariable
+}
+
+// A netCDFReader reads the header of a NetCDF classic format file.
+type netCDFReader struct {
+	data    []byte
+	offset  int
+	version byte
+}
+
+// RequestNetCDF requests a forecast in NetCDF format. If the response uses a
+// NetCDF-4 feature that is not supported then the returned error wraps
+// ErrNetCDF4Unsupported and the caller can fall back to Client.Request.
+func (c *Client) RequestNetCDF(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*NetCDFResponse, error) {
+	data, err := c.Request(ctx, ts, ps, ls, FormatNetCDF, options)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ParseNetCDF(data)
+	if err != nil {
+		return nil, err
+	}
+	return f.Response()
+}
+
+// ParseNetCDF parses a NetCDF file in the classic or 64-bit offset formats,
+// the CDF-5 format, or the subset of the NetCDF-4 format that is returned by
+// the API.
+func ParseNetCDF(data []byte) (*NetCDFFile, error) {
+	switch {
+	case bytes.HasPrefix(data, hdf5Magic):
+		return parseNetCDF4(data)
+	case len(data) < 4 || !bytes.HasPrefix(data, netCDFMagic):
+		return nil, errNetCDFParse
+	}
+	r := &netCDFReader{
+		data:    data,
+		offset:  4,
+		version: data[3],
+	}
+	switch r.version {
+	case 1, 2, 5:
+	default:
+		return nil, errNetCDFParse
+	}
+
+	numRecs, err := r.numRecs()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &NetCDFFile{}
+	if f.Dimensions, err = r.dimensions(); err != nil {
+		return nil, err
+	}
+	if f.Attributes, err = r.attributes(); err != nil {
+		return nil, err
+	}
+
+	n, err := r.listHeader(netCDFVariable)
+	if err != nil {
+		return nil, err
+	}
+	type variableHeader struct {
+		variable *NetCDFVariable
+		ncType   int
+		shape    []int
+		record   bool
+		vsize    int
+		begin    int
+	}
+	headers := make([]variableHeader, 0, n)
+	recSize := 0
+	for i := 0; i < n; i++ {
+		name, err := r.name()
+		if err != nil {
+			return nil, err
+		}
+		nDims, err := r.size()
+		if err != nil {
+			return nil, err
+		}
+		if nDims > (len(r.data)-r.offset)/4 {
+			return nil, errNetCDFParse
+		}
+		h := variableHeader{
+			variable: &NetCDFVariable{
+				Name:       name,
+				Dimensions: make([]string, 0, nDims),
+			},
+			shape: make([]int, 0, nDims),
+		}
+		for j := 0; j < nDims; j++ {
+			dimID, err := r.size()
+			if err != nil {
+				return nil, err
+			}
+			if dimID >= len(f.Dimensions) {
+				return nil, errNetCDFParse
+			}
+			dim := f.Dimensions[dimID]
+			if dim.Unlimited {
+				if j != 0 {
+					return nil, errNetCDFParse
+				}
+				h.record = true
+			}
+			h.variable.Dimensions = append(h.variable.Dimensions, dim.Name)
+			h.shape = append(h.shape, dim.Len)
+		}
+		if h.variable.Attributes, err = r.attributes(); err != nil {
+			return nil, err
+		}
+		if h.ncType, err = r.int32(); err != nil {
+			return nil, err
+		}
+		if h.vsize, err = r.size(); err != nil {
+			return nil, err
+		}
+		if h.begin, err = r.offsetValue(); err != nil {
+			return nil, err
+		}
+		if h.record {
+			recSize += h.vsize
+		}
+		headers = append(headers, h)
+	}
+
+	var recordHeaders []variableHeader
+	for _, h := range headers {
+		if netCDFTypeSize(h.ncType) == 0 {
+			return nil, errNetCDFParse
+		}
+		if h.record {
+			recordHeaders = append(recordHeaders, h)
+		}
+	}
+	if len(recordHeaders) == 1 {
+		// The record size of a file with a single record variable is not
+		// padded.
+		h := recordHeaders[0]
+		n, ok := product(h.shape[1:])
+		if !ok || n > math.MaxInt32/netCDFTypeSize(h.ncType) {
+			return nil, errNetCDFParse
+		}
+		recSize = netCDFTypeSize(h.ncType) * n
+	}
+	if numRecs == netCDFStreaming {
+		numRecs = 0
+		if len(recordHeaders) > 0 && recSize > 0 {
+			numRecs = (len(data) - recordHeaders[0].begin) / recSize
+		}
+	}
+	for i := range f.Dimensions {
+		if f.Dimensions[i].Unlimited {
+			f.Dimensions[i].Len = numRecs
+		}
+	}
+
+	f.Variables = make([]*NetCDFVariable, 0, len(headers))
+	for _, h := range headers {
+		var values []float64
+		if h.record {
+			h.shape[0] = numRecs
+			n, ok := product(h.shape[1:])
+			if !ok || n > math.MaxInt32/netCDFTypeSize(h.ncType) || h.begin < 0 || h.begin > len(data) {
+				return nil, errNetCDFParse
+			}
+			// Check that the last record is within data before allocating
+			// values.
+			if size := n * netCDFTypeSize(h.ncType); numRecs > 0 && size > 0 &&
+				(size > len(data)-h.begin || recSize <= 0 || numRecs-1 > (len(data)-h.begin-size)/recSize) {
+				return nil, errNetCDFParse
+			}
+			values = make([]float64, 0, numRecs*n)
+			for rec := 0; rec < numRecs; rec++ {
+				recValues, err := decodeNetCDFValues(data, h.begin+rec*recSize, h.ncType, n)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, recValues...)
+			}
+		} else {
+			n, ok := product(h.shape)
+			if !ok {
+				return nil, errNetCDFParse
+			}
+			if values, err = decodeNetCDFValues(data, h.begin, h.ncType, n); err != nil {
+				return nil, err
+			}
+		}
+		h.variable.Values = unpackNetCDFValues(values, h.variable.Attributes)
+		f.Variables = append(f.Variables, h.variable)
+	}
+
+	return f, nil
+}
+
+// Response returns f as a NetCDFResponse, separating the coordinate variables
+// from the data variables.
+func (f *NetCDFFile) Response() (*NetCDFResponse, error) {
+	nr := &NetCDFResponse{
+		Dimensions: f.Dimensions,
+		Attributes: f.Attributes,
+	}
+	for _, v := range f.Variables {
+		switch {
+		case !v.isCoordinate():
+			nr.Data = append(nr.Data, v)
+		case v.Name == "lat" || v.Name == "latitude":
+			nr.Lats = v.Values
+		case v.Name == "lon" || v.Name == "longitude":
+			nr.Lons = v.Values
+		case v.Name == "time":
+			times, err := v.Times()
+			if err != nil {
+				return nil, err
+			}
+			nr.Times = times
+		}
+	}
+	return nr, nil
+}
+
+// Variable returns the variable in f called name, or nil if there is no such
+// variable.
+func (f *NetCDFFile) Variable(name string) *NetCDFVariable {
+	for _, v := range f.Variables {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Times returns v's values as times, interpreted using v's units attribute,
+// for example "hours since 1970-01-01 00:00:00".
+func (v *NetCDFVariable) Times() ([]time.Time, error) {
+	units, ok := v.Attributes["units"].(string)
+	if !ok {
+		return nil, errNetCDFParse
+	}
+	fields := strings.SplitN(units, " since ", 2)
+	if len(fields) != 2 {
+		return nil, errNetCDFParse
+	}
+	var unit time.Duration
+	switch strings.TrimSpace(strings.ToLower(fields[0])) {
+	case "seconds", "second", "s":
+		unit = time.Second
+	case "minutes", "minute", "min":
+		unit = time.Minute
+	case "hours", "hour", "h":
+		unit = time.Hour
+	case "days", "day", "d":
+		unit = 24 * time.Hour
+	default:
+		return nil, errNetCDFParse
+	}
+	epoch, err := parseNetCDFEpoch(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	times := make([]time.Time, 0, len(v.Values))
+	for _, value := range v.Values {
+		times = append(times, epoch.Add(time.Duration(math.Round(value*float64(unit)))))
+	}
+	return times, nil
+}
+
+// isCoordinate returns whether v is a coordinate variable, i.e. a
+// one-dimensional variable with the same name as its dimension.
+func (v *NetCDFVariable) isCoordinate() bool {
+	return len(v.Dimensions) == 1 && v.Dimensions[0] == v.Name
+}
+
+// dimensions reads a dim_list.
+func (r *netCDFReader) dimensions() ([]NetCDFDimension, error) {
+	n, err := r.listHeader(netCDFDimension)
+	if err != nil {
+		return nil, err
+	}
+	dimensions := make([]NetCDFDimension, 0, n)
+	for i := 0; i < n; i++ {
+		name, err := r.name()
+		if err != nil {
+			return nil, err
+		}
+		length, err := r.size()
+		if err != nil {
+			return nil, err
+		}
+		dimensions = append(dimensions, NetCDFDimension{
+			Name:      name,
+			Len:       length,
+			Unlimited: length == 0,
+		})
+	}
+	return dimensions, nil
+}
+
+// attributes reads an att_list.
+func (r *netCDFReader) attributes() (map[string]interface{}, error) {
+	n, err := r.listHeader(netCDFAttribute)
+	if err != nil {
+		return nil, err
+	}
+	attributes := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		name, err := r.name()
+		if err != nil {
+			return nil, err
+		}
+		ncType, err := r.int32()
+		if err != nil {
+			return nil, err
+		}
+		nElems, err := r.size()
+		if err != nil {
+			return nil, err
+		}
+		typeSize := netCDFTypeSize(ncType)
+		if typeSize == 0 || nElems > (len(r.data)-r.offset)/typeSize {
+			return nil, errNetCDFParse
+		}
+		size := nElems * typeSize
+		if ncType == netCDFChar {
+			attributes[name] = strings.TrimRight(string(r.data[r.offset:r.offset+size]), "\x00")
+		} else {
+			values, err := decodeNetCDFValues(r.data, r.offset, ncType, nElems)
+			if err != nil {
+				return nil, err
+			}
+			attributes[name] = values
+		}
+		r.offset += pad4(size)
+	}
+	return attributes, nil
+}
+
+// listHeader reads the tag and number of elements of a list, which must
+// either have tag or be absent.
+func (r *netCDFReader) listHeader(tag int) (int, error) {
+	t, err := r.int32()
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.size()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case t == netCDFAbsent && n == 0:
+		return 0, nil
+	case t == tag && n <= (len(r.data)-r.offset)/netCDFMinListElementSize:
+		return n, nil
+	default:
+		return 0, errNetCDFParse
+	}
+}
+
+// name reads a name.
+func (r *netCDFReader) name() (string, error) {
+	n, err := r.size()
+	if err != nil {
+		return "", err
+	}
+	if n > len(r.data)-r.offset {
+		return "", errNetCDFParse
+	}
+	name := string(r.data[r.offset : r.offset+n])
+	r.offset += pad4(n)
+	return name, nil
+}
+
+// numRecs reads the number of records, which is netCDFStreaming if it is
+// indeterminate.
+func (r *netCDFReader) numRecs() (int, error) {
+	size := 4
+	if r.version == 5 {
+		size = 8
+	}
+	if r.offset+size > len(r.data) {
+		return 0, errNetCDFParse
+	}
+	if bytes.Equal(r.data[r.offset:r.offset+size], bytes.Repeat([]byte{0xff}, size)) {
+		r.offset += size
+		return netCDFStreaming, nil
+	}
+	return r.size()
+}
+
+// size reads a non-negative count, which is 32 bits except in the CDF-5
+// format.
+func (r *netCDFReader) size() (int, error) {
+	if r.version == 5 {
+		return r.int64()
+	}
+	if r.offset+4 > len(r.data) {
+		return 0, errNetCDFParse
+	}
+	n := binary.BigEndian.Uint32(r.data[r.offset:])
+	r.offset += 4
+	if n > math.MaxInt32 {
+		return 0, errNetCDFParse
+	}
+	return int(n), nil
+}
+
+// offsetValue reads a file offset, which is 32 bits in the CDF-1 format and
+// 64 bits otherwise.
+func (r *netCDFReader) offsetValue() (int, error) {
+	if r.version == 1 {
+		return r.int32()
+	}
+	return r.int64()
+}
+
+func (r *netCDFReader) int32() (int, error) {
+	if r.offset+4 > len(r.data) {
+		return 0, errNetCDFParse
+	}
+	n := int32(binary.BigEndian.Uint32(r.data[r.offset:]))
+	r.offset += 4
+	if n < 0 {
+		return 0, errNetCDFParse
+	}
+	return int(n), nil
+}
+
+func (r *netCDFReader) int64() (int, error) {
+	if r.offset+8 > len(r.data) {
+		return 0, errNetCDFParse
+	}
+	n := int64(binary.BigEndian.Uint64(r.data[r.offset:]))
+	r.offset += 8
+	if n < 0 || n > math.MaxInt32 {
+		return 0, errNetCDFParse
+	}
+	return int(n), nil
+}
+
+// decodeNetCDFValues decodes n values of ncType at offset in data.
+func decodeNetCDFValues(data []byte, offset, ncType, n int) ([]float64, error) {
+	typeSize := netCDFTypeSize(ncType)
+	if typeSize == 0 || offset < 0 || offset > len(data) || n < 0 || n > (len(data)-offset)/typeSize {
+		return nil, errNetCDFParse
+	}
+	values := make([]float64, n)
+	for i := range values {
+		b := data[offset+i*typeSize:]
+		switch ncType {
+		case netCDFByte:
+			values[i] = float64(int8(b[0]))
+		case netCDFChar, netCDFUByte:
+			values[i] = float64(b[0])
+		case netCDFShort:
+			values[i] = float64(int16(binary.BigEndian.Uint16(b)))
+		case netCDFUShort:
+			values[i] = float64(binary.BigEndian.Uint16(b))
+		case netCDFInt:
+			values[i] = float64(int32(binary.BigEndian.Uint32(b)))
+		case netCDFUInt:
+			values[i] = float64(binary.BigEndian.Uint32(b))
+		case netCDFFloat:
+			values[i] = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+		case netCDFDouble:
+			values[i] = math.Float64frombits(binary.BigEndian.Uint64(b))
+		case netCDFInt64:
+			values[i] = float64(int64(binary.BigEndian.Uint64(b)))
+		case netCDFUInt64:
+			values[i] = float64(binary.BigEndian.Uint64(b))
+		}
+	}
+	return values, nil
+}
+
+// unpackNetCDFValues replaces values equal to the _FillValue attribute with
+// NaN and applies the scale_factor and add_offset attributes.
+func unpackNetCDFValues(values []float64, attributes map[string]interface{}) []float64 {
+	fillValue, hasFillValue := firstNetCDFValue(attributes, "_FillValue")
+	scaleFactor, hasScaleFactor := firstNetCDFValue(attributes, "scale_factor")
+	addOffset, _ := firstNetCDFValue(attributes, "add_offset")
+	if !hasScaleFactor {
+		scaleFactor = 1
+	}
+	for i, value := range values {
+		if hasFillValue && value == fillValue {
+			values[i] = math.NaN()
+		} else {
+			values[i] = value*scaleFactor + addOffset
+		}
+	}
+	return values
+}
+
+// firstNetCDFValue returns the first value of the numeric attribute name.
+func firstNetCDFValue(attributes map[string]interface{}, name string) (float64, bool) {
+	values, ok := attributes[name].([]float64)
+	if !ok || len(values) == 0 {
+		return 0, false
+	}
+	return values[0], true
+}
+
+// parseNetCDFEpoch parses the reference time of a NetCDF time units
+// attribute.
+func parseNetCDFEpoch(s string) (time.Time, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), " UTC")
+	for _, layout := range []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04:05Z07:00",
+		"2006-01-02 15:04:05 -07:00",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, errNetCDFParse
+}
+
+// netCDFTypeSize returns the size in bytes of ncType, or zero if ncType is
+// unknown.
+func netCDFTypeSize(ncType int) int {
+	switch ncType {
+	case netCDFByte, netCDFChar, netCDFUByte:
+		return 1
+	case netCDFShort, netCDFUShort:
+		return 2
+	case netCDFInt, netCDFFloat, netCDFUInt:
+		return 4
+	case netCDFDouble, netCDFInt64, netCDFUInt64:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// pad4 returns n rounded up to a multiple of four.
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// product returns the product of xs. It returns false if any of xs is
+// negative or if the product exceeds math.MaxInt32.
+func product(xs []int) (int, bool) {
+	p := 1
+	for _, x := range xs {
+		if x < 0 || (x != 0 && p > math.MaxInt32/x) {
+			return 0, false
+		}
+		p *= x
+	}
+	return p, true
+}
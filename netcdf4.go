@@ -0,0 +1,1799 @@
+package meteomatics
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// HDF5 object header message types.
+const (
+	hdf5MessageDataspace      = 0x01
+	hdf5MessageLinkInfo       = 0x02
+	hdf5MessageDatatype       = 0x03
+	hdf5MessageLink           = 0x06
+	hdf5MessageLayout         = 0x08
+	hdf5MessageFilterPipeline = 0x0b
+	hdf5MessageAttribute      = 0x0c
+	hdf5MessageContinuation   = 0x10
+	hdf5MessageSymbolTable    = 0x11
+	hdf5MessageAttributeInfo  = 0x15
+)
+
+// HDF5 datatype classes.
+const (
+	hdf5FixedPoint     = 0
+	hdf5FloatingPoint  = 1
+	hdf5String         = 3
+	hdf5Reference      = 7
+	hdf5VariableLength = 9
+)
+
+// HDF5 data layout classes.
+const (
+	hdf5LayoutCompact    = 0
+	hdf5LayoutContiguous = 1
+	hdf5LayoutChunked    = 2
+)
+
+// HDF5 chunk index types. hdf5ChunkIndexBTree1 is the index used by layout
+// messages before version 4.
+const (
+	hdf5ChunkIndexBTree1      = 0
+	hdf5ChunkIndexSingleChunk = 1
+	hdf5ChunkIndexImplicit    = 2
+)
+
+// HDF5 filters.
+const (
+	hdf5FilterDeflate    = 1
+	hdf5FilterShuffle    = 2
+	hdf5FilterFletcher32 = 3
+)
+
+// hdf5MaxCompressionRatio is the maximum compression ratio of deflate, and so
+// bounds the size of the uncompressed data in an HDF5 file.
+const hdf5MaxCompressionRatio = 1032
+
+//nolint:gochecknoglobals
+var (
+	// netCDF4DimensionOnly is the prefix of the NAME attribute of datasets
+	// that define a dimension without a variable.
+	netCDF4DimensionOnly = "This is a netCDF dimension but not a netCDF variable"
+
+	// netCDF4HiddenAttributes are the HDF5 attributes that NetCDF-4 uses to
+	// encode its data model.
+	netCDF4HiddenAttributes = map[string]bool{
+		"CLASS":               true,
+		"DIMENSION_LIST":      true,
+		"NAME":                true,
+		"REFERENCE_LIST":      true,
+		"_IsNetcdf4":          true,
+		"_NCProperties":       true,
+		"_Netcdf4Coordinates": true,
+		"_Netcdf4Dimid":       true,
+		"_nc3_strict":         true,
+	}
+)
+
+// An hdf5File is an HDF5 file.
+type hdf5File struct {
+	data       []byte
+	base       uint64
+	offsetSize int
+	lengthSize int
+}
+
+// An hdf5Reader reads little-endian values from data. Errors are sticky: after
+// the first error every read returns a zero value.
+type hdf5Reader struct {
+	f      *hdf5File
+	data   []byte
+	offset int
+	err    error
+}
+
+// An hdf5Message is an HDF5 object header message.
+type hdf5Message struct {
+	messageType int
+	flags       int
+	data        []byte
+}
+
+// An hdf5Datatype is an HDF5 datatype.
+type hdf5Datatype struct {
+	class      int
+	size       int
+	bigEndian  bool
+	signed     bool
+	padding    int
+	vlenString bool
+	base       *hdf5Datatype
+}
+
+// An hdf5Dataspace is an HDF5 dataspace.
+type hdf5Dataspace struct {
+	dims      []int
+	unlimited []bool
+	null      bool
+}
+
+// An hdf5Layout is an HDF5 data layout.
+type hdf5Layout struct {
+	class        int
+	data         []byte
+	address      uint64
+	size         uint64
+	chunkDims    []int
+	chunkIndex   int
+	filteredSize uint64
+	filterMask   uint32
+}
+
+// An hdf5Filter is a filter in an HDF5 filter pipeline.
+type hdf5Filter struct {
+	id int
+}
+
+// An hdf5Attribute is an HDF5 attribute.
+type hdf5Attribute struct {
+	name      string
+	datatype  *hdf5Datatype
+	dataspace *hdf5Dataspace
+	data      []byte
+}
+
+// An hdf5Link is a hard link from a group to an object.
+type hdf5Link struct {
+	name          string
+	address       uint64
+	creationOrder uint64
+}
+
+// An hdf5FractalHeap is an HDF5 fractal heap.
+type hdf5FractalHeap struct {
+	f                  *hdf5File
+	idLength           int
+	tableWidth         uint64
+	startBlockSize     uint64
+	maxDirectBlockSize uint64
+	maxDirectRows      int
+	rootAddress        uint64
+	rootRows           int
+	offsetSize         int
+	lengthSize         int
+}
+
+// An hdf5Chunk is a stored chunk of a chunked dataset.
+type hdf5Chunk struct {
+	offsets    []uint64
+	address    uint64
+	size       uint64
+	filterMask uint32
+}
+
+// A netCDF4Dataset is a dataset in the root group of a NetCDF-4 file.
+type netCDF4Dataset struct {
+	name          string
+	address       uint64
+	messages      []hdf5Message
+	datatype      *hdf5Datatype
+	dataspace     *hdf5Dataspace
+	attributes    []*hdf5Attribute
+	dimensionOnly bool
+}
+
+// parseNetCDF4 parses the subset of NetCDF-4 files that is written by the
+// API: variables in the root group with numeric types, stored in compact,
+// contiguous, or chunked layouts and compressed with the deflate, shuffle,
+// and fletcher32 filters.
+func parseNetCDF4(data []byte) (*NetCDFFile, error) {
+	f, rootAddress, err := newHDF5File(data)
+	if err != nil {
+		return nil, err
+	}
+	rootMessages, err := f.objectHeader(rootAddress)
+	if err != nil {
+		return nil, err
+	}
+	links, err := f.links(rootMessages)
+	if err != nil {
+		return nil, err
+	}
+	rootAttributes, err := f.attributes(rootMessages)
+	if err != nil {
+		return nil, err
+	}
+	nc := &NetCDFFile{}
+	if nc.Attributes, err = f.netCDFAttributes(rootAttributes); err != nil {
+		return nil, err
+	}
+
+	var datasets []*netCDF4Dataset
+	dimensionNames := make(map[uint64]string)
+	dimensionIDs := make(map[string]int)
+	var dimensions []NetCDFDimension
+	for _, link := range links {
+		messages, err := f.objectHeader(link.address)
+		if err != nil {
+			return nil, err
+		}
+		d := &netCDF4Dataset{
+			name:     link.name,
+			address:  link.address,
+			messages: messages,
+		}
+		for _, m := range messages {
+			switch m.messageType {
+			case hdf5MessageDataspace:
+				if d.dataspace, err = f.dataspace(m.data); err != nil {
+					return nil, err
+				}
+			case hdf5MessageDatatype:
+				r := f.reader(m.data)
+				d.datatype = r.datatype()
+				if r.err != nil {
+					return nil, r.err
+				}
+			}
+		}
+		if d.dataspace == nil || d.datatype == nil {
+			// Skip groups and named datatypes.
+			continue
+		}
+		if d.attributes, err = f.attributes(messages); err != nil {
+			return nil, err
+		}
+		class, _ := f.stringAttribute(d.attributes, "CLASS")
+		if class == "DIMENSION_SCALE" {
+			if len(d.dataspace.dims) != 1 {
+				return nil, errNetCDFParse
+			}
+			name, _ := f.stringAttribute(d.attributes, "NAME")
+			d.dimensionOnly = strings.HasPrefix(name, netCDF4DimensionOnly)
+			dimensionNames[d.address] = d.name
+			dimensionIDs[d.name] = len(dimensions)
+			if dimID, ok := f.intAttribute(d.attributes, "_Netcdf4Dimid"); ok {
+				dimensionIDs[d.name] = dimID
+			}
+			dimensions = append(dimensions, NetCDFDimension{
+				Name:      d.name,
+				Len:       d.dataspace.dims[0],
+				Unlimited: d.dataspace.unlimited[0],
+			})
+		}
+		datasets = append(datasets, d)
+	}
+	sort.SliceStable(dimensions, func(i, j int) bool {
+		return dimensionIDs[dimensions[i].Name] < dimensionIDs[dimensions[j].Name]
+	})
+	nc.Dimensions = dimensions
+
+	for _, d := range datasets {
+		if d.dimensionOnly {
+			continue
+		}
+		v := &NetCDFVariable{
+			Name: d.name,
+		}
+		if v.Attributes, err = f.netCDFAttributes(d.attributes); err != nil {
+			return nil, err
+		}
+		if v.Dimensions, err = f.netCDFDimensions(d, dimensionNames, nc.Dimensions); err != nil {
+			return nil, err
+		}
+		values, err := f.values(d)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.name, err)
+		}
+		v.Values = unpackNetCDFValues(values, v.Attributes)
+		nc.Variables = append(nc.Variables, v)
+	}
+
+	return nc, nil
+}
+
+// netCDFAttributes returns the NetCDF attributes in attributes. Attributes
+// with types that cannot be represented are ignored.
+func (f *hdf5File) netCDFAttributes(attributes []*hdf5Attribute) (map[string]interface{}, error) {
+	netCDFAttributes := make(map[string]interface{}, len(attributes))
+	for _, a := range attributes {
+		if netCDF4HiddenAttributes[a.name] {
+			continue
+		}
+		value, err := f.attributeValue(a)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			netCDFAttributes[a.name] = value
+		}
+	}
+	return netCDFAttributes, nil
+}
+
+// netCDFDimensions returns the names of the dimensions of d.
+func (f *hdf5File) netCDFDimensions(d *netCDF4Dataset, dimensionNames map[uint64]string, dimensions []NetCDFDimension) ([]string, error) {
+	rank := len(d.dataspace.dims)
+	if _, ok := dimensionNames[d.address]; ok {
+		return []string{d.name}, nil
+	}
+	names := make([]string, 0, rank)
+	for _, a := range d.attributes {
+		if a.name != "DIMENSION_LIST" {
+			continue
+		}
+		dt := a.datatype
+		if dt.class != hdf5VariableLength || dt.vlenString || dt.base.class != hdf5Reference || dt.base.size != f.offsetSize ||
+			dt.size < 8+f.offsetSize || a.dataspace.count() != rank || rank > len(a.data)/dt.size {
+			return nil, errNetCDFParse
+		}
+		for i := 0; i < rank; i++ {
+			references, err := f.variableLengthData(a.data[i*dt.size:(i+1)*dt.size], dt.base.size)
+			if err != nil {
+				return nil, err
+			}
+			if len(references) < f.offsetSize {
+				return nil, errNetCDFParse
+			}
+			name, ok := dimensionNames[f.reader(references).address()]
+			if !ok {
+				return nil, errNetCDFParse
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	}
+	if rank == 0 {
+		return nil, nil
+	}
+	dimIDs, ok := f.intsAttribute(d.attributes, "_Netcdf4Coordinates")
+	if !ok || len(dimIDs) != rank {
+		return nil, fmt.Errorf("%s: dimensions without dimension scales: %w", d.name, ErrNetCDF4Unsupported)
+	}
+	for _, dimID := range dimIDs {
+		if dimID < 0 || dimID >= len(dimensions) {
+			return nil, errNetCDFParse
+		}
+		names = append(names, dimensions[dimID].Name)
+	}
+	return names, nil
+}
+
+// stringAttribute returns the value of the string attribute name.
+func (f *hdf5File) stringAttribute(attributes []*hdf5Attribute, name string) (string, bool) {
+	for _, a := range attributes {
+		if a.name == name {
+			value, err := f.attributeValue(a)
+			if s, ok := value.(string); ok && err == nil {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
+
+// intAttribute returns the value of the scalar integer attribute name.
+func (f *hdf5File) intAttribute(attributes []*hdf5Attribute, name string) (int, bool) {
+	values, ok := f.intsAttribute(attributes, name)
+	if !ok || len(values) != 1 {
+		return 0, false
+	}
+	return values[0], true
+}
+
+// intsAttribute returns the values of the integer attribute name.
+func (f *hdf5File) intsAttribute(attributes []*hdf5Attribute, name string) ([]int, bool) {
+	for _, a := range attributes {
+		if a.name != name || a.datatype.class != hdf5FixedPoint {
+			continue
+		}
+		value, err := f.attributeValue(a)
+		values, ok := value.([]float64)
+		if err != nil || !ok {
+			return nil, false
+		}
+		ints := make([]int, 0, len(values))
+		for _, v := range values {
+			if v < 0 || v > math.MaxInt32 {
+				return nil, false
+			}
+			ints = append(ints, int(v))
+		}
+		return ints, true
+	}
+	return nil, false
+}
+
+// newHDF5File parses the superblock of the HDF5 file in data and returns the
+// address of the root group's object header.
+func newHDF5File(data []byte) (*hdf5File, uint64, error) {
+	f := &hdf5File{
+		data: data,
+	}
+	if len(data) < len(hdf5Magic)+1 {
+		return nil, 0, errNetCDFParse
+	}
+	r := f.reader(data[len(hdf5Magic):])
+	var rootAddress uint64
+	switch version := r.uint8(); version {
+	case 0, 1:
+		r.skip(4) // Versions of free-space storage, root group symbol table entry, and shared header message format.
+		f.offsetSize = r.uint8()
+		f.lengthSize = r.uint8()
+		r.skip(1) // Reserved.
+		r.skip(8) // Group leaf and internal node K and file consistency flags.
+		if version == 1 {
+			r.skip(4) // Indexed storage internal node K and reserved.
+		}
+		if !validHDF5Size(f.offsetSize) || !validHDF5Size(f.lengthSize) {
+			return nil, 0, errNetCDFParse
+		}
+		f.base = r.address()
+		r.skip(3 * f.offsetSize) // Free-space info, end of file, and driver information block addresses.
+		r.skip(f.offsetSize)     // Link name offset.
+		rootAddress = r.address()
+	case 2, 3:
+		f.offsetSize = r.uint8()
+		f.lengthSize = r.uint8()
+		r.skip(1) // File consistency flags.
+		if !validHDF5Size(f.offsetSize) || !validHDF5Size(f.lengthSize) {
+			return nil, 0, errNetCDFParse
+		}
+		f.base = r.address()
+		r.skip(2 * f.offsetSize) // Superblock extension and end of file addresses.
+		rootAddress = r.address()
+	default:
+		return nil, 0, fmt.Errorf("superblock version %d: %w", version, ErrNetCDF4Unsupported)
+	}
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	return f, rootAddress, nil
+}
+
+// objectHeader returns the messages in the object header at address,
+// following continuation messages.
+func (f *hdf5File) objectHeader(address uint64) ([]hdf5Message, error) {
+	r := f.readerAt(address)
+	if r.err != nil {
+		return nil, r.err
+	}
+	version := 1
+	creationOrderTracked := false
+	var chunk []byte
+	if bytes.HasPrefix(r.data[r.offset:], []byte("OHDR")) {
+		r.skip(4)
+		version = r.uint8()
+		if version != 2 {
+			return nil, fmt.Errorf("object header version %d: %w", version, ErrNetCDF4Unsupported)
+		}
+		flags := r.uint8()
+		creationOrderTracked = flags&0x04 != 0
+		if flags&0x20 != 0 {
+			r.skip(16) // Access, modification, change, and birth times.
+		}
+		if flags&0x10 != 0 {
+			r.skip(4) // Maximum compact and minimum dense attributes.
+		}
+		chunk = r.bytes(r.int(1 << (flags & 0x03)))
+	} else {
+		if v := r.uint8(); v != 1 {
+			return nil, fmt.Errorf("object header version %d: %w", v, ErrNetCDF4Unsupported)
+		}
+		r.skip(7) // Reserved, number of header messages, and object reference count.
+		size := r.int(4)
+		r.skip(4) // Reserved.
+		chunk = r.bytes(size)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	var messages []hdf5Message
+	visited := map[uint64]bool{address: true}
+	for chunks := [][]byte{chunk}; len(chunks) > 0; chunks = chunks[1:] {
+		cr := f.reader(chunks[0])
+		headerSize := 8
+		if version == 2 {
+			headerSize = 4
+			if creationOrderTracked {
+				headerSize = 6
+			}
+		}
+		for len(cr.data)-cr.offset >= headerSize {
+			var m hdf5Message
+			var size int
+			if version == 2 {
+				m.messageType = cr.uint8()
+				size = cr.int(2)
+				m.flags = cr.uint8()
+				if creationOrderTracked {
+					cr.skip(2)
+				}
+			} else {
+				m.messageType = cr.int(2)
+				size = cr.int(2)
+				m.flags = cr.uint8()
+				cr.skip(3)
+			}
+			m.data = cr.bytes(size)
+			if cr.err != nil {
+				return nil, cr.err
+			}
+			if m.messageType != hdf5MessageContinuation {
+				messages = append(messages, m)
+				continue
+			}
+			mr := f.reader(m.data)
+			continuationAddress, continuationLength := mr.address(), mr.length()
+			if mr.err != nil {
+				return nil, mr.err
+			}
+			if visited[continuationAddress] {
+				return nil, errNetCDFParse
+			}
+			visited[continuationAddress] = true
+			car := f.readerAt(continuationAddress)
+			if continuationLength > math.MaxInt32 {
+				return nil, errNetCDFParse
+			}
+			continuation := car.bytes(int(continuationLength))
+			if car.err != nil {
+				return nil, car.err
+			}
+			if version == 2 {
+				if len(continuation) < 8 || !bytes.HasPrefix(continuation, []byte("OCHK")) {
+					return nil, errNetCDFParse
+				}
+				continuation = continuation[4 : len(continuation)-4]
+			}
+			chunks = append(chunks, continuation)
+		}
+	}
+	for _, m := range messages {
+		if m.flags&0x02 != 0 {
+			return nil, fmt.Errorf("shared message type %d: %w", m.messageType, ErrNetCDF4Unsupported)
+		}
+	}
+	return messages, nil
+}
+
+// links returns the hard links in the group with messages, in creation order
+// if it is tracked and in name order otherwise.
+func (f *hdf5File) links(messages []hdf5Message) ([]hdf5Link, error) {
+	var links []hdf5Link
+	for _, m := range messages {
+		switch m.messageType {
+		case hdf5MessageLink:
+			link, ok, err := f.link(m.data)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				links = append(links, link)
+			}
+		case hdf5MessageLinkInfo:
+			r := f.reader(m.data)
+			r.skip(1) // Version.
+			if flags := r.uint8(); flags&0x01 != 0 {
+				r.skip(8) // Maximum creation index.
+			}
+			heapAddress, nameIndexAddress := r.address(), r.address()
+			if r.err != nil {
+				return nil, r.err
+			}
+			if f.undefined(heapAddress) {
+				continue
+			}
+			heap, err := f.fractalHeap(heapAddress)
+			if err != nil {
+				return nil, err
+			}
+			records, err := f.btree2Records(nameIndexAddress)
+			if err != nil {
+				return nil, err
+			}
+			for _, record := range records {
+				if len(record) < 4 {
+					return nil, errNetCDFParse
+				}
+				data, err := heap.object(record[4:])
+				if err != nil {
+					return nil, err
+				}
+				link, ok, err := f.link(data)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					links = append(links, link)
+				}
+			}
+		case hdf5MessageSymbolTable:
+			symbolTableLinks, err := f.symbolTableLinks(m.data)
+			if err != nil {
+				return nil, err
+			}
+			links = append(links, symbolTableLinks...)
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].creationOrder != links[j].creationOrder {
+			return links[i].creationOrder < links[j].creationOrder
+		}
+		return links[i].name < links[j].name
+	})
+	return links, nil
+}
+
+// link parses a link message. It returns false if the link is not a hard
+// link.
+func (f *hdf5File) link(data []byte) (hdf5Link, bool, error) {
+	r := f.reader(data)
+	if version := r.uint8(); version != 1 {
+		return hdf5Link{}, false, errNetCDFParse
+	}
+	flags := r.uint8()
+	linkType := 0
+	if flags&0x08 != 0 {
+		linkType = r.uint8()
+	}
+	var link hdf5Link
+	if flags&0x04 != 0 {
+		link.creationOrder = r.uint(8)
+	}
+	if flags&0x10 != 0 {
+		r.skip(1) // Link name character set.
+	}
+	link.name = string(r.bytes(r.int(1 << (flags & 0x03))))
+	if linkType == 0 {
+		link.address = r.address()
+	}
+	if r.err != nil {
+		return hdf5Link{}, false, r.err
+	}
+	return link, linkType == 0, nil
+}
+
+// symbolTableLinks returns the links in the group with the symbol table
+// message data.
+func (f *hdf5File) symbolTableLinks(data []byte) ([]hdf5Link, error) {
+	r := f.reader(data)
+	btreeAddress, heapAddress := r.address(), r.address()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	hr := f.readerAt(heapAddress)
+	hr.signature("HEAP")
+	hr.skip(4) // Version and reserved.
+	heapSize := hr.length()
+	hr.skip(f.lengthSize) // Offset to head of free-list.
+	heapDataAddress := hr.address()
+	if hr.err != nil {
+		return nil, hr.err
+	}
+	if heapSize > math.MaxInt32 {
+		return nil, errNetCDFParse
+	}
+	hdr := f.readerAt(heapDataAddress)
+	heap := hdr.bytes(int(heapSize))
+	if hdr.err != nil {
+		return nil, hdr.err
+	}
+
+	var links []hdf5Link
+	if err := f.btree1(btreeAddress, 0, f.lengthSize, func(_ []byte, address uint64) error {
+		sr := f.readerAt(address)
+		sr.signature("SNOD")
+		sr.skip(2) // Version and reserved.
+		n := sr.int(2)
+		for i := 0; i < n && sr.err == nil; i++ {
+			nameOffset := sr.address()
+			objectAddress := sr.address()
+			sr.skip(24) // Cache type, reserved, and scratch-pad.
+			if nameOffset >= uint64(len(heap)) {
+				return errNetCDFParse
+			}
+			name := heap[nameOffset:]
+			if j := bytes.IndexByte(name, 0); j >= 0 {
+				name = name[:j]
+			}
+			links = append(links, hdf5Link{
+				name:    string(name),
+				address: objectAddress,
+			})
+		}
+		return sr.err
+	}); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// attributes returns the attributes of the object with messages.
+func (f *hdf5File) attributes(messages []hdf5Message) ([]*hdf5Attribute, error) {
+	var attributes []*hdf5Attribute
+	for _, m := range messages {
+		switch m.messageType {
+		case hdf5MessageAttribute:
+			a, err := f.attribute(m.data)
+			if err != nil {
+				return nil, err
+			}
+			attributes = append(attributes, a)
+		case hdf5MessageAttributeInfo:
+			r := f.reader(m.data)
+			r.skip(1) // Version.
+			if flags := r.uint8(); flags&0x01 != 0 {
+				r.skip(2) // Maximum creation index.
+			}
+			heapAddress, nameIndexAddress := r.address(), r.address()
+			if r.err != nil {
+				return nil, r.err
+			}
+			if f.undefined(heapAddress) {
+				continue
+			}
+			heap, err := f.fractalHeap(heapAddress)
+			if err != nil {
+				return nil, err
+			}
+			records, err := f.btree2Records(nameIndexAddress)
+			if err != nil {
+				return nil, err
+			}
+			for _, record := range records {
+				if len(record) < 9 {
+					return nil, errNetCDFParse
+				}
+				if record[8]&0x02 != 0 {
+					return nil, fmt.Errorf("shared attribute: %w", ErrNetCDF4Unsupported)
+				}
+				data, err := heap.object(record[:8])
+				if err != nil {
+					return nil, err
+				}
+				a, err := f.attribute(data)
+				if err != nil {
+					return nil, err
+				}
+				attributes = append(attributes, a)
+			}
+		}
+	}
+	return attributes, nil
+}
+
+// attribute parses an attribute message.
+func (f *hdf5File) attribute(data []byte) (*hdf5Attribute, error) {
+	r := f.reader(data)
+	version := r.uint8()
+	flags := r.uint8()
+	nameSize, datatypeSize, dataspaceSize := r.int(2), r.int(2), r.int(2)
+	var nameData, datatypeData, dataspaceData []byte
+	switch version {
+	case 1:
+		nameData = r.bytes(pad8(nameSize))
+		datatypeData = r.bytes(pad8(datatypeSize))
+		dataspaceData = r.bytes(pad8(dataspaceSize))
+	case 2, 3:
+		if flags&0x03 != 0 {
+			return nil, fmt.Errorf("shared attribute datatype or dataspace: %w", ErrNetCDF4Unsupported)
+		}
+		if version == 3 {
+			r.skip(1) // Name character set encoding.
+		}
+		nameData = r.bytes(nameSize)
+		datatypeData = r.bytes(datatypeSize)
+		dataspaceData = r.bytes(dataspaceSize)
+	default:
+		return nil, fmt.Errorf("attribute version %d: %w", version, ErrNetCDF4Unsupported)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if nameSize > len(nameData) {
+		return nil, errNetCDFParse
+	}
+	a := &hdf5Attribute{
+		name: strings.TrimRight(string(nameData[:nameSize]), "\x00"),
+		data: r.data[r.offset:],
+	}
+	dr := f.reader(datatypeData)
+	a.datatype = dr.datatype()
+	if dr.err != nil {
+		return nil, dr.err
+	}
+	var err error
+	if a.dataspace, err = f.dataspace(dataspaceData); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// attributeValue returns the value of a, which is a string for attributes
+// with a single string, a []string for attributes with several strings, a
+// []float64 for numeric attributes, and nil otherwise.
+func (f *hdf5File) attributeValue(a *hdf5Attribute) (interface{}, error) {
+	dt := a.datatype
+	if dt.class != hdf5String && !(dt.class == hdf5VariableLength && dt.vlenString) && !dt.numeric() {
+		return nil, nil
+	}
+	n := a.dataspace.count()
+	if dt.size <= 0 || n > len(a.data)/dt.size {
+		return nil, errNetCDFParse
+	}
+	var strs []string
+	switch {
+	case dt.class == hdf5String:
+		for i := 0; i < n; i++ {
+			strs = append(strs, dt.trimString(a.data[i*dt.size:(i+1)*dt.size]))
+		}
+	case dt.class == hdf5VariableLength && dt.vlenString:
+		for i := 0; i < n; i++ {
+			s, err := f.variableLengthData(a.data[i*dt.size:(i+1)*dt.size], 1)
+			if err != nil {
+				return nil, err
+			}
+			strs = append(strs, dt.trimString(s))
+		}
+	default:
+		return decodeHDF5Values(a.data, dt, n)
+	}
+	if len(strs) == 1 {
+		return strs[0], nil
+	}
+	return strs, nil
+}
+
+// values returns the values of d.
+func (f *hdf5File) values(d *netCDF4Dataset) ([]float64, error) {
+	dt := d.datatype
+	if !dt.numeric() {
+		return nil, fmt.Errorf("datatype class %d size %d: %w", dt.class, dt.size, ErrNetCDF4Unsupported)
+	}
+	n := d.dataspace.count()
+	if n < 0 || n > math.MaxInt32/dt.size {
+		return nil, errNetCDFParse
+	}
+	size := n * dt.size
+	var layout *hdf5Layout
+	var filters []hdf5Filter
+	for _, m := range d.messages {
+		var err error
+		switch m.messageType {
+		case hdf5MessageLayout:
+			layout, err = f.layout(m.data)
+		case hdf5MessageFilterPipeline:
+			filters, err = f.filterPipeline(m.data)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	// A null dataspace has no elements, and so cannot have a data layout.
+	if layout == nil || d.dataspace.null {
+		return nil, errNetCDFParse
+	}
+
+	switch layout.class {
+	case hdf5LayoutCompact:
+		return decodeHDF5Values(layout.data, dt, n)
+	case hdf5LayoutContiguous:
+		if f.undefined(layout.address) {
+			return nanHDF5Values(f, size, n)
+		}
+		r := f.readerAt(layout.address)
+		data := r.bytes(size)
+		if r.err != nil {
+			return nil, r.err
+		}
+		return decodeHDF5Values(data, dt, n)
+	}
+
+	dims := d.dataspace.dims
+	if len(layout.chunkDims) != len(dims) {
+		return nil, errNetCDFParse
+	}
+	chunkElems, ok := product(layout.chunkDims)
+	if !ok || chunkElems == 0 || chunkElems > math.MaxInt32/dt.size {
+		return nil, errNetCDFParse
+	}
+	chunkSize := chunkElems * dt.size
+	values, err := nanHDF5Values(f, size, n)
+	if err != nil {
+		return nil, err
+	}
+	if nDims, ok := product(dims); !ok || len(values) != nDims {
+		return nil, errNetCDFParse
+	}
+	chunks, err := f.chunks(layout, dims, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range chunks {
+		if c.size > uint64(len(f.data)) || uint64(chunkSize/hdf5MaxCompressionRatio) > c.size {
+			return nil, errNetCDFParse
+		}
+		r := f.readerAt(c.address)
+		data := r.bytes(int(c.size))
+		if r.err != nil {
+			return nil, r.err
+		}
+		if data, err = unfilterHDF5Chunk(data, filters, c.filterMask, chunkSize, dt.size); err != nil {
+			return nil, err
+		}
+		copyHDF5Chunk(values, dims, data, c.offsets, layout.chunkDims, dt)
+	}
+	return values, nil
+}
+
+// chunks returns the stored chunks of a chunked dataset with layout and
+// dims.
+func (f *hdf5File) chunks(layout *hdf5Layout, dims []int, chunkSize int) ([]hdf5Chunk, error) {
+	if f.undefined(layout.address) {
+		return nil, nil
+	}
+	rank := len(dims)
+	switch layout.chunkIndex {
+	case hdf5ChunkIndexBTree1:
+		var chunks []hdf5Chunk
+		keySize := 8 + 8*(rank+1)
+		if err := f.btree1(layout.address, 1, keySize, func(key []byte, address uint64) error {
+			r := f.reader(key)
+			c := hdf5Chunk{
+				size:       uint64(r.int(4)),
+				filterMask: uint32(r.uint(4)),
+				offsets:    make([]uint64, rank),
+				address:    address,
+			}
+			for i := range c.offsets {
+				c.offsets[i] = r.uint(8)
+			}
+			chunks = append(chunks, c)
+			return r.err
+		}); err != nil {
+			return nil, err
+		}
+		return chunks, nil
+	case hdf5ChunkIndexSingleChunk:
+		size := layout.filteredSize
+		if size == 0 {
+			size = uint64(chunkSize)
+		}
+		return []hdf5Chunk{
+			{
+				offsets:    make([]uint64, rank),
+				address:    layout.address,
+				size:       size,
+				filterMask: layout.filterMask,
+			},
+		}, nil
+	case hdf5ChunkIndexImplicit:
+		nChunks := 1
+		chunksPerDim := make([]int, rank)
+		for i, dim := range dims {
+			chunksPerDim[i] = (dim + layout.chunkDims[i] - 1) / layout.chunkDims[i]
+			nChunks *= chunksPerDim[i]
+			if nChunks > len(f.data)/chunkSize {
+				return nil, errNetCDFParse
+			}
+		}
+		chunks := make([]hdf5Chunk, 0, nChunks)
+		for i := 0; i < nChunks; i++ {
+			c := hdf5Chunk{
+				offsets: make([]uint64, rank),
+				address: layout.address + uint64(i*chunkSize),
+				size:    uint64(chunkSize),
+			}
+			for j, k := rank-1, i; j >= 0; j-- {
+				c.offsets[j] = uint64(k % chunksPerDim[j] * layout.chunkDims[j])
+				k /= chunksPerDim[j]
+			}
+			chunks = append(chunks, c)
+		}
+		return chunks, nil
+	default:
+		return nil, fmt.Errorf("chunk index type %d: %w", layout.chunkIndex, ErrNetCDF4Unsupported)
+	}
+}
+
+// dataspace parses a dataspace message.
+func (f *hdf5File) dataspace(data []byte) (*hdf5Dataspace, error) {
+	r := f.reader(data)
+	version := r.uint8()
+	rank := r.uint8()
+	flags := r.uint8()
+	ds := &hdf5Dataspace{}
+	switch version {
+	case 1:
+		r.skip(5) // Reserved.
+	case 2:
+		ds.null = r.uint8() == 2
+		if ds.null && rank != 0 {
+			return nil, errNetCDFParse
+		}
+	default:
+		return nil, fmt.Errorf("dataspace version %d: %w", version, ErrNetCDF4Unsupported)
+	}
+	for i := 0; i < rank && r.err == nil; i++ {
+		dim := r.length()
+		if dim > math.MaxInt32 {
+			return nil, errNetCDFParse
+		}
+		ds.dims = append(ds.dims, int(dim))
+	}
+	ds.unlimited = make([]bool, len(ds.dims))
+	if flags&0x01 != 0 {
+		for i := range ds.unlimited {
+			ds.unlimited[i] = f.undefinedLength(r.length())
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if _, ok := product(ds.dims); !ok {
+		return nil, errNetCDFParse
+	}
+	return ds, nil
+}
+
+// count returns the number of elements in ds.
+func (ds *hdf5Dataspace) count() int {
+	if ds.null {
+		return 0
+	}
+	n, _ := product(ds.dims)
+	return n
+}
+
+// layout parses a data layout message.
+func (f *hdf5File) layout(data []byte) (*hdf5Layout, error) {
+	r := f.reader(data)
+	version := r.uint8()
+	if version != 3 && version != 4 {
+		return nil, fmt.Errorf("data layout version %d: %w", version, ErrNetCDF4Unsupported)
+	}
+	l := &hdf5Layout{
+		class: r.uint8(),
+	}
+	switch l.class {
+	case hdf5LayoutCompact:
+		l.data = r.bytes(r.int(2))
+	case hdf5LayoutContiguous:
+		l.address = r.address()
+		l.size = r.length()
+	case hdf5LayoutChunked:
+		var dimSize int
+		if version == 3 {
+			dimSize = 4
+			rank := r.uint8() - 1
+			l.address = r.address()
+			l.chunkDims = r.chunkDims(rank, dimSize)
+			r.skip(4) // Dataset element size.
+			break
+		}
+		flags := r.uint8()
+		rank := r.uint8() - 1
+		dimSize = r.uint8()
+		if dimSize < 1 || dimSize > 8 {
+			return nil, errNetCDFParse
+		}
+		l.chunkDims = r.chunkDims(rank, dimSize)
+		r.skip(dimSize) // Dataset element size.
+		switch l.chunkIndex = r.uint8(); l.chunkIndex {
+		case hdf5ChunkIndexSingleChunk:
+			if flags&0x02 != 0 {
+				l.filteredSize = r.length()
+				l.filterMask = uint32(r.uint(4))
+			}
+			l.address = r.address()
+		case hdf5ChunkIndexImplicit:
+			l.address = r.address()
+		default:
+			return nil, fmt.Errorf("chunk index type %d: %w", l.chunkIndex, ErrNetCDF4Unsupported)
+		}
+	default:
+		return nil, fmt.Errorf("data layout class %d: %w", l.class, ErrNetCDF4Unsupported)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return l, nil
+}
+
+// filterPipeline parses a filter pipeline message.
+func (f *hdf5File) filterPipeline(data []byte) ([]hdf5Filter, error) {
+	r := f.reader(data)
+	version := r.uint8()
+	n := r.uint8()
+	if version == 1 {
+		r.skip(6) // Reserved.
+	} else if version != 2 {
+		return nil, fmt.Errorf("filter pipeline version %d: %w", version, ErrNetCDF4Unsupported)
+	}
+	filters := make([]hdf5Filter, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		filter := hdf5Filter{
+			id: r.int(2),
+		}
+		nameLength := 0
+		if version == 1 || filter.id >= 256 {
+			nameLength = r.int(2)
+		}
+		r.skip(2) // Flags.
+		numClientDataValues := r.int(2)
+		if version == 1 {
+			nameLength = pad8(nameLength)
+		}
+		r.skip(nameLength)
+		r.skip(4 * numClientDataValues)
+		if version == 1 && numClientDataValues%2 == 1 {
+			r.skip(4) // Padding.
+		}
+		switch filter.id {
+		case hdf5FilterDeflate, hdf5FilterShuffle, hdf5FilterFletcher32:
+		default:
+			return nil, fmt.Errorf("filter %d: %w", filter.id, ErrNetCDF4Unsupported)
+		}
+		filters = append(filters, filter)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return filters, nil
+}
+
+// btree1 calls fn with the key and child address of each entry in the leaves
+// of the version 1 B-tree of nodeType at address.
+func (f *hdf5File) btree1(address uint64, nodeType, keySize int, fn func([]byte, uint64) error) error {
+	visited := make(map[uint64]bool)
+	var walk func(uint64, int) error
+	walk = func(address uint64, expectedLevel int) error {
+		if visited[address] {
+			return errNetCDFParse
+		}
+		visited[address] = true
+		r := f.readerAt(address)
+		r.signature("TREE")
+		if t := r.uint8(); t != nodeType {
+			return errNetCDFParse
+		}
+		level := r.uint8()
+		if expectedLevel >= 0 && level != expectedLevel {
+			return errNetCDFParse
+		}
+		n := r.int(2)
+		r.skip(2 * f.offsetSize) // Left and right sibling addresses.
+		for i := 0; i < n && r.err == nil; i++ {
+			key := r.bytes(keySize)
+			child := r.address()
+			if r.err != nil {
+				break
+			}
+			var err error
+			if level > 0 {
+				err = walk(child, level-1)
+			} else {
+				err = fn(key, child)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return r.err
+	}
+	return walk(address, -1)
+}
+
+// btree2Records returns the records in the version 2 B-tree at address.
+func (f *hdf5File) btree2Records(address uint64) ([][]byte, error) {
+	r := f.readerAt(address)
+	r.signature("BTHD")
+	r.skip(2) // Version and type.
+	nodeSize := r.int(4)
+	recordSize := r.int(2)
+	depth := r.int(2)
+	r.skip(2) // Split and merge percents.
+	rootAddress := r.address()
+	rootRecords := r.int(2)
+	if r.err != nil {
+		return nil, r.err
+	}
+	const prefixSize = 10 // Signature, version, type, and checksum.
+	if recordSize == 0 || nodeSize <= prefixSize || depth > 32 {
+		return nil, errNetCDFParse
+	}
+
+	// Compute the sizes of the child node pointers in internal nodes at each
+	// depth.
+	maxRecords := uint64((nodeSize - prefixSize) / recordSize)
+	maxRecordsSize := hdf5EncodedSize(maxRecords)
+	cumulativeMaxRecords := []uint64{maxRecords}
+	cumulativeMaxRecordsSizes := []int{0}
+	for d := 1; d <= depth; d++ {
+		pointerSize := f.offsetSize + maxRecordsSize + cumulativeMaxRecordsSizes[d-1]
+		if nodeSize <= prefixSize+pointerSize {
+			return nil, errNetCDFParse
+		}
+		n := uint64((nodeSize - (prefixSize + pointerSize)) / (recordSize + pointerSize))
+		cumulative := (n+1)*cumulativeMaxRecords[d-1] + n
+		if cumulative < cumulativeMaxRecords[d-1] {
+			return nil, errNetCDFParse
+		}
+		cumulativeMaxRecords = append(cumulativeMaxRecords, cumulative)
+		cumulativeMaxRecordsSizes = append(cumulativeMaxRecordsSizes, hdf5EncodedSize(cumulative))
+	}
+
+	var records [][]byte
+	visited := make(map[uint64]bool)
+	var walk func(uint64, int, int) error
+	walk = func(address uint64, n, depth int) error {
+		if visited[address] {
+			return errNetCDFParse
+		}
+		visited[address] = true
+		r := f.readerAt(address)
+		if depth == 0 {
+			r.signature("BTLF")
+		} else {
+			r.signature("BTIN")
+		}
+		r.skip(2) // Version and type.
+		for i := 0; i < n && r.err == nil; i++ {
+			records = append(records, r.bytes(recordSize))
+		}
+		if depth > 0 {
+			for i := 0; i <= n && r.err == nil; i++ {
+				child := r.address()
+				childRecords := r.int(maxRecordsSize)
+				if depth > 1 {
+					r.skip(cumulativeMaxRecordsSizes[depth-1])
+				}
+				if r.err != nil {
+					break
+				}
+				if err := walk(child, childRecords, depth-1); err != nil {
+					return err
+				}
+			}
+		}
+		return r.err
+	}
+	if f.undefined(rootAddress) {
+		return nil, nil
+	}
+	if err := walk(rootAddress, rootRecords, depth); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// fractalHeap parses the fractal heap header at address.
+func (f *hdf5File) fractalHeap(address uint64) (*hdf5FractalHeap, error) {
+	r := f.readerAt(address)
+	r.signature("FRHP")
+	r.skip(1) // Version.
+	h := &hdf5FractalHeap{
+		f:        f,
+		idLength: r.int(2),
+	}
+	filtersLength := r.int(2)
+	r.skip(1) // Flags.
+	maxManagedObjectSize := r.uint(4)
+	r.skip(f.lengthSize + f.offsetSize + f.lengthSize + f.offsetSize) // Huge objects and free space.
+	r.skip(8 * f.lengthSize)                                          // Statistics.
+	h.tableWidth = uint64(r.int(2))
+	h.startBlockSize = r.length()
+	h.maxDirectBlockSize = r.length()
+	maxHeapSize := r.int(2)
+	r.skip(2) // Starting number of rows in root indirect block.
+	h.rootAddress = r.address()
+	h.rootRows = r.int(2)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if filtersLength != 0 {
+		return nil, fmt.Errorf("filtered fractal heap: %w", ErrNetCDF4Unsupported)
+	}
+	if !isPowerOfTwo(h.tableWidth) || !isPowerOfTwo(h.startBlockSize) || !isPowerOfTwo(h.maxDirectBlockSize) ||
+		h.maxDirectBlockSize < h.startBlockSize || maxHeapSize < 1 || maxHeapSize > 64 || h.rootRows > 64 {
+		return nil, errNetCDFParse
+	}
+	h.maxDirectRows = bits.TrailingZeros64(h.maxDirectBlockSize) - bits.TrailingZeros64(h.startBlockSize) + 2
+	h.offsetSize = (maxHeapSize + 7) / 8
+	h.lengthSize = (bits.TrailingZeros64(h.maxDirectBlockSize) + 7) / 8
+	if n := hdf5EncodedSize(maxManagedObjectSize); n < h.lengthSize {
+		h.lengthSize = n
+	}
+	return h, nil
+}
+
+// object returns the object in h with id.
+func (h *hdf5FractalHeap) object(id []byte) ([]byte, error) {
+	if len(id) == 0 {
+		return nil, errNetCDFParse
+	}
+	switch idType := id[0] >> 4 & 0x03; idType {
+	case 0:
+		r := h.f.reader(id[1:])
+		offset := r.uint(h.offsetSize)
+		length := r.uint(h.lengthSize)
+		if r.err != nil {
+			return nil, r.err
+		}
+		if h.rootRows == 0 {
+			return h.directBlockObject(h.rootAddress, h.startBlockSize, offset, length)
+		}
+		return h.indirectBlockObject(h.rootAddress, h.rootRows, offset, length, 0)
+	case 2:
+		n, data := int(id[0]&0x0f)+1, id[1:]
+		if h.idLength > 18 {
+			if len(id) < 2 {
+				return nil, errNetCDFParse
+			}
+			n, data = int(id[0]&0x0f)<<8|int(id[1])+1, id[2:]
+		}
+		if n > len(data) {
+			return nil, errNetCDFParse
+		}
+		return data[:n], nil
+	default:
+		return nil, fmt.Errorf("fractal heap ID type %d: %w", idType, ErrNetCDF4Unsupported)
+	}
+}
+
+// directBlockObject returns the object at offset with length in the direct
+// block at address with size.
+func (h *hdf5FractalHeap) directBlockObject(address, size, offset, length uint64) ([]byte, error) {
+	r := h.f.readerAt(address)
+	start := r.offset
+	r.signature("FHDB")
+	r.skip(1 + h.f.offsetSize) // Version and heap header address.
+	blockOffset := r.uint(h.offsetSize)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if offset < blockOffset || offset-blockOffset > size || length > size-(offset-blockOffset) {
+		return nil, errNetCDFParse
+	}
+	r.offset = start
+	r.skip(int(offset - blockOffset))
+	data := r.bytes(int(length))
+	if r.err != nil {
+		return nil, r.err
+	}
+	return data, nil
+}
+
+// indirectBlockObject returns the object at offset with length in the
+// indirect block at address with rows.
+func (h *hdf5FractalHeap) indirectBlockObject(address uint64, rows int, offset, length uint64, depth int) ([]byte, error) {
+	if depth > 64 {
+		return nil, errNetCDFParse
+	}
+	r := h.f.readerAt(address)
+	r.signature("FHIB")
+	r.skip(1 + h.f.offsetSize) // Version and heap header address.
+	blockOffset := r.uint(h.offsetSize)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if offset < blockOffset {
+		return nil, errNetCDFParse
+	}
+	relativeOffset := offset - blockOffset
+
+	// Find the row and column of the child block that contains the object.
+	row, column := -1, uint64(0)
+	for i, rowOffset := 0, uint64(0); i < rows && i < 64; i++ {
+		blockSize := h.rowBlockSize(i)
+		rowSize := h.tableWidth * blockSize
+		if blockSize == 0 || rowSize/h.tableWidth != blockSize {
+			break
+		}
+		if relativeOffset < rowOffset+rowSize {
+			row, column = i, (relativeOffset-rowOffset)/blockSize
+			break
+		}
+		rowOffset += rowSize
+	}
+	if row < 0 {
+		return nil, errNetCDFParse
+	}
+	directRows := rows
+	if directRows > h.maxDirectRows {
+		directRows = h.maxDirectRows
+	}
+	entry := uint64(row)*h.tableWidth + column
+	r.skip(int(entry) * h.f.offsetSize)
+	childAddress := r.address()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if h.f.undefined(childAddress) {
+		return nil, errNetCDFParse
+	}
+	blockSize := h.rowBlockSize(row)
+	if row < directRows {
+		return h.directBlockObject(childAddress, blockSize, offset, length)
+	}
+	childRows := bits.TrailingZeros64(blockSize) - bits.TrailingZeros64(h.startBlockSize*h.tableWidth) + 1
+	return h.indirectBlockObject(childAddress, childRows, offset, length, depth+1)
+}
+
+// rowBlockSize returns the size of the blocks in row of h's doubling table.
+func (h *hdf5FractalHeap) rowBlockSize(row int) uint64 {
+	if row == 0 {
+		return h.startBlockSize
+	}
+	return h.startBlockSize << uint(row-1)
+}
+
+// variableLengthData returns the data of the variable-length element, which
+// is stored in a global heap, with elements of elementSize.
+func (f *hdf5File) variableLengthData(element []byte, elementSize int) ([]byte, error) {
+	r := f.reader(element)
+	n := r.int(4)
+	collectionAddress := r.address()
+	index := r.uint(4)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if n == 0 {
+		return nil, nil
+	}
+
+	cr := f.readerAt(collectionAddress)
+	cr.signature("GCOL")
+	cr.skip(4) // Version and reserved.
+	collectionSize := cr.length()
+	if cr.err != nil {
+		return nil, cr.err
+	}
+	headerSize := uint64(8 + f.lengthSize)
+	if collectionSize < headerSize || collectionSize > math.MaxInt32 {
+		return nil, errNetCDFParse
+	}
+	cr.offset -= int(headerSize)
+	collection := cr.bytes(int(collectionSize))
+	if cr.err != nil {
+		return nil, cr.err
+	}
+	or := f.reader(collection[headerSize:])
+	for or.err == nil && len(or.data)-or.offset >= 8+f.lengthSize {
+		objectIndex := or.uint(2)
+		or.skip(6) // Reference count and reserved.
+		objectSize := or.length()
+		if objectIndex == 0 || objectSize > math.MaxInt32 {
+			break
+		}
+		data := or.bytes(pad8(int(objectSize)))
+		if objectIndex == index {
+			if n > len(data)/elementSize {
+				return nil, errNetCDFParse
+			}
+			return data[:n*elementSize], nil
+		}
+	}
+	return nil, errNetCDFParse
+}
+
+// datatype reads a datatype.
+func (r *hdf5Reader) datatype() *hdf5Datatype {
+	classAndVersion := r.uint8()
+	classBits := r.uint(3)
+	dt := &hdf5Datatype{
+		class: classAndVersion & 0x0f,
+		size:  r.int(4),
+	}
+	switch dt.class {
+	case hdf5FixedPoint:
+		dt.bigEndian = classBits&0x01 != 0
+		dt.signed = classBits&0x08 != 0
+		r.skip(4) // Bit offset and precision.
+	case hdf5FloatingPoint:
+		dt.bigEndian = classBits&0x01 != 0
+		if classBits&0x40 != 0 {
+			// VAX byte order is not supported.
+			dt.class = -1
+		}
+		r.skip(12) // Bit offset, precision, and exponent and mantissa locations, sizes, and bias.
+	case hdf5String:
+		dt.padding = int(classBits & 0x0f)
+	case hdf5VariableLength:
+		dt.vlenString = classBits&0x0f == 1
+		dt.padding = int(classBits >> 4 & 0x0f)
+		dt.base = r.datatype()
+	}
+	return dt
+}
+
+// numeric returns whether dt can be decoded to a float64.
+func (dt *hdf5Datatype) numeric() bool {
+	switch dt.class {
+	case hdf5FixedPoint:
+		return dt.size == 1 || dt.size == 2 || dt.size == 4 || dt.size == 8
+	case hdf5FloatingPoint:
+		return dt.size == 4 || dt.size == 8
+	case hdf5String:
+		return dt.size == 1
+	default:
+		return false
+	}
+}
+
+// decode decodes the value in b.
+func (dt *hdf5Datatype) decode(b []byte) float64 {
+	var u uint64
+	if dt.bigEndian {
+		for i := 0; i < dt.size; i++ {
+			u = u<<8 | uint64(b[i])
+		}
+	} else {
+		for i := dt.size - 1; i >= 0; i-- {
+			u = u<<8 | uint64(b[i])
+		}
+	}
+	switch {
+	case dt.class == hdf5FloatingPoint && dt.size == 4:
+		return float64(math.Float32frombits(uint32(u)))
+	case dt.class == hdf5FloatingPoint:
+		return math.Float64frombits(u)
+	case dt.signed:
+		shift := uint(64 - 8*dt.size)
+		return float64(int64(u<<shift) >> shift)
+	default:
+		return float64(u)
+	}
+}
+
+// trimString returns b as a string with dt's padding removed.
+func (dt *hdf5Datatype) trimString(b []byte) string {
+	if dt.padding == 2 {
+		return strings.TrimRight(string(b), " ")
+	}
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// decodeHDF5Values decodes n values of dt in data.
+func decodeHDF5Values(data []byte, dt *hdf5Datatype, n int) ([]float64, error) {
+	if n > len(data)/dt.size {
+		return nil, errNetCDFParse
+	}
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = dt.decode(data[i*dt.size:])
+	}
+	return values, nil
+}
+
+// nanHDF5Values returns n NaNs, the values of unwritten data, if the size of
+// the uncompressed data is plausible for f.
+func nanHDF5Values(f *hdf5File, size, n int) ([]float64, error) {
+	if size/hdf5MaxCompressionRatio > len(f.data) {
+		return nil, errNetCDFParse
+	}
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.NaN()
+	}
+	return values, nil
+}
+
+// unfilterHDF5Chunk reverses filters on data, which has chunkSize bytes when
+// unfiltered.
+func unfilterHDF5Chunk(data []byte, filters []hdf5Filter, filterMask uint32, chunkSize, elementSize int) ([]byte, error) {
+	for i := len(filters) - 1; i >= 0; i-- {
+		if i < 32 && filterMask&(1<<uint(i)) != 0 {
+			continue
+		}
+		switch filters[i].id {
+		case hdf5FilterDeflate:
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			// Allow for a fletcher32 checksum.
+			if data, err = ioutil.ReadAll(io.LimitReader(zr, int64(chunkSize)+4)); err != nil {
+				return nil, err
+			}
+		case hdf5FilterShuffle:
+			unshuffled := make([]byte, len(data))
+			n := len(data) / elementSize
+			for j := 0; j < n; j++ {
+				for k := 0; k < elementSize; k++ {
+					unshuffled[j*elementSize+k] = data[k*n+j]
+				}
+			}
+			copy(unshuffled[n*elementSize:], data[n*elementSize:])
+			data = unshuffled
+		case hdf5FilterFletcher32:
+			if len(data) < 4 {
+				return nil, errNetCDFParse
+			}
+			data = data[:len(data)-4]
+		}
+	}
+	if len(data) < chunkSize {
+		return nil, errNetCDFParse
+	}
+	return data[:chunkSize], nil
+}
+
+// copyHDF5Chunk copies the values in the chunk data with chunkDims at offsets
+// into values, which has dims.
+func copyHDF5Chunk(values []float64, dims []int, data []byte, offsets []uint64, chunkDims []int, dt *hdf5Datatype) {
+	for i, offset := range offsets {
+		if offset >= uint64(dims[i]) {
+			return
+		}
+	}
+	rank := len(dims)
+	index := make([]int, rank)
+	for i := 0; i < len(data)/dt.size; i++ {
+		valueIndex, inside := 0, true
+		for j := 0; j < rank; j++ {
+			k := int(offsets[j]) + index[j]
+			if k >= dims[j] {
+				inside = false
+				break
+			}
+			valueIndex = valueIndex*dims[j] + k
+		}
+		if inside {
+			values[valueIndex] = dt.decode(data[i*dt.size:])
+		}
+		for j := rank - 1; j >= 0; j-- {
+			index[j]++
+			if index[j] < chunkDims[j] {
+				break
+			}
+			index[j] = 0
+		}
+	}
+}
+
+// reader returns a new hdf5Reader that reads data.
+func (f *hdf5File) reader(data []byte) *hdf5Reader {
+	return &hdf5Reader{
+		f:    f,
+		data: data,
+	}
+}
+
+// readerAt returns a new hdf5Reader that reads f from address.
+func (f *hdf5File) readerAt(address uint64) *hdf5Reader {
+	r := f.reader(f.data)
+	if f.undefined(address) || address > uint64(len(f.data)) || f.base > uint64(len(f.data))-address {
+		r.err = errNetCDFParse
+		return r
+	}
+	r.offset = int(f.base + address)
+	return r
+}
+
+// undefined returns whether address is the undefined address.
+func (f *hdf5File) undefined(address uint64) bool {
+	return address == math.MaxUint64>>uint(64-8*f.offsetSize)
+}
+
+// undefinedLength returns whether length is the undefined length, which is
+// used for unlimited dimensions.
+func (f *hdf5File) undefinedLength(length uint64) bool {
+	return length == math.MaxUint64>>uint(64-8*f.lengthSize)
+}
+
+// bytes reads n bytes.
+func (r *hdf5Reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data)-r.offset {
+		r.err = errNetCDFParse
+		return nil
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b
+}
+
+// skip skips n bytes.
+func (r *hdf5Reader) skip(n int) {
+	r.bytes(n)
+}
+
+// signature reads the signature s.
+func (r *hdf5Reader) signature(s string) {
+	if b := r.bytes(len(s)); r.err == nil && string(b) != s {
+		r.err = errNetCDFParse
+	}
+}
+
+// uint reads an n-byte unsigned integer.
+func (r *hdf5Reader) uint(n int) uint64 {
+	if n > 8 {
+		r.err = errNetCDFParse
+		return 0
+	}
+	b := r.bytes(n)
+	var u uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		u = u<<8 | uint64(b[i])
+	}
+	return u
+}
+
+// int reads an n-byte unsigned integer that must not exceed math.MaxInt32.
+func (r *hdf5Reader) int(n int) int {
+	u := r.uint(n)
+	if u > math.MaxInt32 {
+		if r.err == nil {
+			r.err = errNetCDFParse
+		}
+		return 0
+	}
+	return int(u)
+}
+
+// uint8 reads a byte.
+func (r *hdf5Reader) uint8() int {
+	return int(r.uint(1))
+}
+
+// address reads an address.
+func (r *hdf5Reader) address() uint64 {
+	return r.uint(r.f.offsetSize)
+}
+
+// length reads a length.
+func (r *hdf5Reader) length() uint64 {
+	return r.uint(r.f.lengthSize)
+}
+
+// chunkDims reads rank chunk dimensions, each of size bytes.
+func (r *hdf5Reader) chunkDims(rank, size int) []int {
+	if rank < 0 {
+		r.err = errNetCDFParse
+		return nil
+	}
+	chunkDims := make([]int, 0, rank)
+	for i := 0; i < rank && r.err == nil; i++ {
+		chunkDims = append(chunkDims, r.int(size))
+	}
+	return chunkDims
+}
+
+// hdf5EncodedSize returns the number of bytes needed to encode n.
+func hdf5EncodedSize(n uint64) int {
+	if n == 0 {
+		return 1
+	}
+	return (bits.Len64(n)-1)/8 + 1
+}
+
+// isPowerOfTwo returns whether n is a power of two.
+func isPowerOfTwo(n uint64) bool {
+	return n != 0 && n&(n-1) == 0
+}
+
+// validHDF5Size returns whether n is a valid size of offsets or lengths.
+func validHDF5Size(n int) bool {
+	return n == 2 || n == 4 || n == 8
+}
+
+// pad8 returns n rounded up to a multiple of eight.
+func pad8(n int) int {
+	return (n + 7) &^ 7
+}
@@ -0,0 +1,898 @@
+package meteomatics
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testHDF5Undefined is the undefined address and the unlimited length.
+const testHDF5Undefined = math.MaxUint64
+
+// A testNetCDF4Options controls the HDF5 features used by encodeTestNetCDF4.
+type testNetCDF4Options struct {
+	// superblockVersion is 0 or 2. Version 0 files use version 1 object
+	// headers and a symbol table for the root group, version 2 files use
+	// version 2 object headers and links.
+	superblockVersion int
+	// dense stores the root group's links and attributes in fractal heaps.
+	dense bool
+	// dimensionOnly adds a dimension without a variable.
+	dimensionOnly bool
+	// filterID replaces the deflate filter.
+	filterID int
+	// windSpeedDims replaces the dimensions of the wind_speed_10m dataspace.
+	windSpeedDims []int
+	// nullTimeDims, if non-nil, replaces the chunked time dataspace with a
+	// null dataspace with the given dimensions. It requires superblock version
+	// 2.
+	nullTimeDims []int
+}
+
+// A testHDF5Message is a message to be encoded in an HDF5 object header.
+type testHDF5Message struct {
+	messageType int
+	data        []byte
+}
+
+// A testHDF5Writer writes an HDF5 file with 8-byte offsets and lengths.
+// Checksums are not written.
+type testHDF5Writer struct {
+	t    *testing.T
+	data []byte
+}
+
+// write writes b, padded to a multiple of eight bytes, and returns its
+// address.
+func (w *testHDF5Writer) write(b []byte) uint64 {
+	address := uint64(len(w.data))
+	w.data = append(w.data, testHDF5Pad8(b)...)
+	return address
+}
+
+// objectHeaderV1 writes a version 1 object header containing messages. If
+// split is positive then the messages from split onwards are written in a
+// continuation block.
+func (w *testHDF5Writer) objectHeaderV1(messages []testHDF5Message, split int) uint64 {
+	encode := func(messages []testHDF5Message) []byte {
+		var b []byte
+		for _, m := range messages {
+			data := testHDF5Pad8(m.data)
+			b = append(b, testHDF5Cat(testHDF5Uint(2, uint64(m.messageType)), testHDF5Uint(2, uint64(len(data))), make([]byte, 4), data)...)
+		}
+		return b
+	}
+	if split > 0 && split < len(messages) {
+		continuation := encode(messages[split:])
+		address := w.write(continuation)
+		messages = append(messages[:split:split], testHDF5Message{
+			messageType: hdf5MessageContinuation,
+			data:        testHDF5Cat(testHDF5Uint(8, address), testHDF5Uint(8, uint64(len(continuation)))),
+		})
+	}
+	body := encode(messages)
+	return w.write(testHDF5Cat(
+		[]byte{1, 0},
+		testHDF5Uint(2, uint64(len(messages))),
+		testHDF5Uint(4, 1),
+		testHDF5Uint(4, uint64(len(body))),
+		make([]byte, 4),
+		body,
+	))
+}
+
+// objectHeaderV2 writes a version 2 object header containing messages. If
+// split is positive then the messages from split onwards are written in a
+// continuation block.
+func (w *testHDF5Writer) objectHeaderV2(messages []testHDF5Message, split int) uint64 {
+	encode := func(messages []testHDF5Message) []byte {
+		var b []byte
+		for _, m := range messages {
+			b = append(b, testHDF5Cat([]byte{byte(m.messageType)}, testHDF5Uint(2, uint64(len(m.data))), []byte{0}, m.data)...)
+		}
+		return b
+	}
+	if split > 0 && split < len(messages) {
+		continuation := testHDF5Cat([]byte("OCHK"), encode(messages[split:]), make([]byte, 4))
+		address := w.write(continuation)
+		messages = append(messages[:split:split], testHDF5Message{
+			messageType: hdf5MessageContinuation,
+			data:        testHDF5Cat(testHDF5Uint(8, address), testHDF5Uint(8, uint64(len(continuation)))),
+		})
+	}
+	body := encode(messages)
+	return w.write(testHDF5Cat(
+		[]byte("OHDR"),
+		[]byte{2, 0x22}, // Times stored and four byte chunk size.
+		make([]byte, 16),
+		testHDF5Uint(4, uint64(len(body))),
+		body,
+		make([]byte, 4),
+	))
+}
+
+// globalHeap writes a global heap collection containing objects, which have
+// indexes starting at one.
+func (w *testHDF5Writer) globalHeap(objects [][]byte) uint64 {
+	var body []byte
+	for i, object := range objects {
+		body = append(body, testHDF5Cat(testHDF5Uint(2, uint64(i+1)), testHDF5Uint(2, 1), make([]byte, 4), testHDF5Uint(8, uint64(len(object))), testHDF5Pad8(object))...)
+	}
+	body = append(body, make([]byte, 16)...)
+	return w.write(testHDF5Cat([]byte("GCOL"), []byte{1, 0, 0, 0}, testHDF5Uint(8, uint64(16+len(body))), body))
+}
+
+// fractalHeap writes a fractal heap containing objects and returns its
+// address and the objects' IDs, which are idLength bytes long. If indirect is
+// true then the root block is an indirect block.
+func (w *testHDF5Writer) fractalHeap(objects [][]byte, idLength int, indirect bool) (uint64, [][]byte) {
+	const (
+		tableWidth         = 4
+		startBlockSize     = 512
+		maxDirectBlockSize = 65536
+		maxHeapSize        = 32
+	)
+	blockOffset := uint64(0)
+	if indirect {
+		blockOffset = startBlockSize
+	}
+	block := testHDF5Cat([]byte("FHDB"), []byte{0}, testHDF5Uint(8, 0), testHDF5Uint(4, blockOffset))
+	ids := make([][]byte, 0, len(objects))
+	for _, object := range objects {
+		id := testHDF5Cat([]byte{0}, testHDF5Uint(4, blockOffset+uint64(len(block))), testHDF5Uint(2, uint64(len(object))))
+		ids = append(ids, append(id, make([]byte, idLength-len(id))...))
+		block = append(block, object...)
+	}
+	require.True(w.t, len(block) <= startBlockSize)
+	block = append(block, make([]byte, startBlockSize-len(block))...)
+	rootAddress := w.write(block)
+	rootRows := 0
+	if indirect {
+		entries := make([][]byte, 0, 2*tableWidth)
+		for i := 0; i < 2*tableWidth; i++ {
+			address := uint64(testHDF5Undefined)
+			if i == 1 {
+				address = rootAddress
+			}
+			entries = append(entries, testHDF5Uint(8, address))
+		}
+		rootAddress = w.write(testHDF5Cat([]byte("FHIB"), []byte{0}, testHDF5Uint(8, 0), testHDF5Uint(4, 0), testHDF5Cat(entries...), make([]byte, 4)))
+		rootRows = 2
+	}
+	return w.write(testHDF5Cat(
+		[]byte("FRHP"),
+		[]byte{0},
+		testHDF5Uint(2, uint64(idLength)),
+		testHDF5Uint(2, 0), // I/O filters' encoded length.
+		[]byte{0},          // Flags.
+		testHDF5Uint(4, 4096),
+		testHDF5Uint(8, 0),
+		testHDF5Uint(8, testHDF5Undefined),
+		testHDF5Uint(8, 0),
+		testHDF5Uint(8, testHDF5Undefined),
+		make([]byte, 8*8), // Statistics.
+		testHDF5Uint(2, tableWidth),
+		testHDF5Uint(8, startBlockSize),
+		testHDF5Uint(8, maxDirectBlockSize),
+		testHDF5Uint(2, maxHeapSize),
+		testHDF5Uint(2, uint64(rootRows)),
+		testHDF5Uint(8, rootAddress),
+		testHDF5Uint(2, uint64(rootRows)),
+		make([]byte, 4),
+	)), ids
+}
+
+// btree2 writes a version 2 B-tree of btreeType containing records. If
+// internal is true then the root node is an internal node with two leaves.
+func (w *testHDF5Writer) btree2(btreeType int, records [][]byte, internal bool) uint64 {
+	leaf := func(records [][]byte) uint64 {
+		return w.write(testHDF5Cat([]byte("BTLF"), []byte{0, byte(btreeType)}, testHDF5Cat(records...), make([]byte, 4)))
+	}
+	var rootAddress uint64
+	depth, rootRecords := 0, len(records)
+	if internal {
+		k := len(records) / 2
+		left, right := leaf(records[:k]), leaf(records[k+1:])
+		rootAddress = w.write(testHDF5Cat(
+			[]byte("BTIN"), []byte{0, byte(btreeType)},
+			records[k],
+			testHDF5Uint(8, left), []byte{byte(k)},
+			testHDF5Uint(8, right), []byte{byte(len(records) - k - 1)},
+			make([]byte, 4),
+		))
+		depth, rootRecords = 1, 1
+	} else {
+		rootAddress = leaf(records)
+	}
+	return w.write(testHDF5Cat(
+		[]byte("BTHD"), []byte{0, byte(btreeType)},
+		testHDF5Uint(4, 512),
+		testHDF5Uint(2, uint64(len(records[0]))),
+		testHDF5Uint(2, uint64(depth)),
+		[]byte{100, 40},
+		testHDF5Uint(8, rootAddress),
+		testHDF5Uint(2, uint64(rootRecords)),
+		testHDF5Uint(8, uint64(len(records))),
+		make([]byte, 4),
+	))
+}
+
+// symbolTable writes a symbol table group's B-tree and local heap containing
+// links and returns a symbol table message.
+func (w *testHDF5Writer) symbolTable(links map[string]uint64) testHDF5Message {
+	names := make([]string, 0, len(links))
+	for name := range links {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	heap := make([]byte, 8)
+	var entries []byte
+	nameOffset := uint64(0)
+	for _, name := range names {
+		nameOffset = uint64(len(heap))
+		heap = append(heap, testHDF5Pad8(append([]byte(name), 0))...)
+		entries = append(entries, testHDF5Cat(testHDF5Uint(8, nameOffset), testHDF5Uint(8, links[name]), make([]byte, 24))...)
+	}
+	heapDataAddress := w.write(heap)
+	heapAddress := w.write(testHDF5Cat([]byte("HEAP"), make([]byte, 4), testHDF5Uint(8, uint64(len(heap))), testHDF5Uint(8, testHDF5Undefined), testHDF5Uint(8, heapDataAddress)))
+	symbolTableNodeAddress := w.write(testHDF5Cat([]byte("SNOD"), []byte{1, 0}, testHDF5Uint(2, uint64(len(names))), entries))
+	btreeAddress := w.write(testHDF5Cat(
+		[]byte("TREE"), []byte{0, 0},
+		testHDF5Uint(2, 1),
+		testHDF5Uint(8, testHDF5Undefined),
+		testHDF5Uint(8, testHDF5Undefined),
+		testHDF5Uint(8, 0),
+		testHDF5Uint(8, symbolTableNodeAddress),
+		testHDF5Uint(8, nameOffset),
+	))
+	return testHDF5Message{
+		messageType: hdf5MessageSymbolTable,
+		data:        testHDF5Cat(testHDF5Uint(8, btreeAddress), testHDF5Uint(8, heapAddress)),
+	}
+}
+
+// chunks writes the chunks of values, which have dims and are encoded with
+// encode, in chunks of chunkDims and returns the address of their B-tree. If
+// shuffle is true then chunks are shuffled and, apart from the second chunk,
+// compressed with filterID.
+func (w *testHDF5Writer) chunks(values []float64, dims, chunkDims []int, elementSize int, encode func([]float64) []byte, shuffle bool, filterID int) uint64 {
+	rank := len(dims)
+	var keysAndChildren []byte
+	nChunks := 0
+	offsets := make([]int, rank)
+	for {
+		// Gather the values in the chunk at offsets.
+		chunkElems, _ := product(chunkDims)
+		chunkValues := make([]float64, chunkElems)
+		index := make([]int, rank)
+		for i := range chunkValues {
+			valueIndex := 0
+			for j := 0; j < rank && valueIndex >= 0; j++ {
+				if offsets[j]+index[j] >= dims[j] {
+					valueIndex = -1
+				} else {
+					valueIndex = valueIndex*dims[j] + offsets[j] + index[j]
+				}
+			}
+			if valueIndex >= 0 {
+				chunkValues[i] = values[valueIndex]
+			}
+			for j := rank - 1; j >= 0; j-- {
+				if index[j]++; index[j] < chunkDims[j] {
+					break
+				}
+				index[j] = 0
+			}
+		}
+
+		data := encode(chunkValues)
+		filterMask := uint64(0)
+		if shuffle {
+			shuffled := make([]byte, len(data))
+			n := len(data) / elementSize
+			for i := 0; i < n; i++ {
+				for j := 0; j < elementSize; j++ {
+					shuffled[j*n+i] = data[i*elementSize+j]
+				}
+			}
+			data = shuffled
+			if nChunks == 1 {
+				filterMask = 0x02
+			} else if filterID == hdf5FilterDeflate {
+				b := &bytes.Buffer{}
+				zw := zlib.NewWriter(b)
+				_, err := zw.Write(data)
+				require.NoError(w.t, err)
+				require.NoError(w.t, zw.Close())
+				data = b.Bytes()
+			}
+		}
+		address := w.write(data)
+		keysAndChildren = append(keysAndChildren, testHDF5Cat(testHDF5Uint(4, uint64(len(data))), testHDF5Uint(4, filterMask))...)
+		for _, offset := range offsets {
+			keysAndChildren = append(keysAndChildren, testHDF5Uint(8, uint64(offset))...)
+		}
+		keysAndChildren = append(keysAndChildren, testHDF5Cat(testHDF5Uint(8, 0), testHDF5Uint(8, address))...)
+		nChunks++
+
+		j := rank - 1
+		for ; j >= 0; j-- {
+			if offsets[j] += chunkDims[j]; offsets[j] < dims[j] {
+				break
+			}
+			offsets[j] = 0
+		}
+		if j < 0 {
+			break
+		}
+	}
+	keysAndChildren = append(keysAndChildren, make([]byte, 8)...)
+	for _, dim := range dims {
+		keysAndChildren = append(keysAndChildren, testHDF5Uint(8, uint64(dim))...)
+	}
+	keysAndChildren = append(keysAndChildren, make([]byte, 8)...)
+	return w.write(testHDF5Cat(
+		[]byte("TREE"), []byte{1, 0},
+		testHDF5Uint(2, uint64(nChunks)),
+		testHDF5Uint(8, testHDF5Undefined),
+		testHDF5Uint(8, testHDF5Undefined),
+		keysAndChildren,
+	))
+}
+
+// encodeTestNetCDF4 encodes the contents of newTestNetCDF as a NetCDF-4 file,
+// with an additional title global attribute.
+func encodeTestNetCDF4(t *testing.T, options testNetCDF4Options) []byte {
+	t.Helper()
+
+	v2 := options.superblockVersion >= 2
+	const superblockSize = 96
+	w := &testHDF5Writer{
+		t:    t,
+		data: make([]byte, superblockSize),
+	}
+	if options.filterID == 0 {
+		options.filterID = hdf5FilterDeflate
+	}
+
+	objectHeader := func(messages []testHDF5Message, split int) uint64 {
+		if v2 {
+			return w.objectHeaderV2(messages, split)
+		}
+		return w.objectHeaderV1(messages, split)
+	}
+	dataspace := func(dims []int, unlimited bool) []byte {
+		flags := byte(0)
+		if unlimited {
+			flags = 1
+		}
+		var b []byte
+		if v2 {
+			dataspaceType := byte(1)
+			if len(dims) == 0 {
+				dataspaceType = 0
+			}
+			b = []byte{2, byte(len(dims)), flags, dataspaceType}
+		} else {
+			b = []byte{1, byte(len(dims)), flags, 0, 0, 0, 0, 0}
+		}
+		for _, dim := range dims {
+			b = append(b, testHDF5Uint(8, uint64(dim))...)
+		}
+		if unlimited {
+			for i, dim := range dims {
+				maxDim := uint64(dim)
+				if i == 0 {
+					maxDim = testHDF5Undefined
+				}
+				b = append(b, testHDF5Uint(8, maxDim)...)
+			}
+		}
+		return b
+	}
+	attribute := func(name string, datatype, dataspace, data []byte) testHDF5Message {
+		nameData := append([]byte(name), 0)
+		var b []byte
+		if v2 {
+			b = testHDF5Cat([]byte{3, 0}, testHDF5Uint(2, uint64(len(nameData))), testHDF5Uint(2, uint64(len(datatype))), testHDF5Uint(2, uint64(len(dataspace))), []byte{0},
+				nameData, datatype, dataspace, data)
+		} else {
+			b = testHDF5Cat([]byte{1, 0}, testHDF5Uint(2, uint64(len(nameData))), testHDF5Uint(2, uint64(len(datatype))), testHDF5Uint(2, uint64(len(dataspace))),
+				testHDF5Pad8(nameData), testHDF5Pad8(datatype), testHDF5Pad8(dataspace), data)
+		}
+		return testHDF5Message{
+			messageType: hdf5MessageAttribute,
+			data:        b,
+		}
+	}
+	stringAttribute := func(name, value string) testHDF5Message {
+		return attribute(name, testHDF5StringDatatype(len(value)+1), dataspace(nil, false), append([]byte(value), 0))
+	}
+	datatypeMessage := func(datatype []byte) testHDF5Message {
+		return testHDF5Message{
+			messageType: hdf5MessageDatatype,
+			data:        datatype,
+		}
+	}
+	dataspaceMessage := func(dims []int, unlimited bool) testHDF5Message {
+		return testHDF5Message{
+			messageType: hdf5MessageDataspace,
+			data:        dataspace(dims, unlimited),
+		}
+	}
+	contiguousLayout := func(data []byte) testHDF5Message {
+		address := uint64(testHDF5Undefined)
+		if data != nil {
+			address = w.write(data)
+		}
+		return testHDF5Message{
+			messageType: hdf5MessageLayout,
+			data:        testHDF5Cat([]byte{3, hdf5LayoutContiguous}, testHDF5Uint(8, address), testHDF5Uint(8, uint64(len(data)))),
+		}
+	}
+	chunkedLayout := func(address uint64, chunkDims []int, elementSize int) testHDF5Message {
+		b := testHDF5Cat([]byte{3, hdf5LayoutChunked, byte(len(chunkDims) + 1)}, testHDF5Uint(8, address))
+		for _, chunkDim := range chunkDims {
+			b = append(b, testHDF5Uint(4, uint64(chunkDim))...)
+		}
+		return testHDF5Message{
+			messageType: hdf5MessageLayout,
+			data:        append(b, testHDF5Uint(4, uint64(elementSize))...),
+		}
+	}
+	dimensionScale := func(name, netCDFName string, dimID int) []testHDF5Message {
+		return []testHDF5Message{
+			stringAttribute("CLASS", "DIMENSION_SCALE"),
+			stringAttribute("NAME", netCDFName),
+			attribute("_Netcdf4Dimid", testHDF5FixedPointDatatype(4, true, false), dataspace(nil, false), testHDF5Uint(4, uint64(dimID))),
+		}
+	}
+
+	links := make(map[string]uint64)
+	var linkNames []string
+	link := func(name string, address uint64) {
+		links[name] = address
+		linkNames = append(linkNames, name)
+	}
+
+	link("lat", objectHeader(append([]testHDF5Message{
+		dataspaceMessage([]int{2}, false),
+		datatypeMessage(testHDF5FloatDatatype(8)),
+		contiguousLayout(testHDF5Float64s([]float64{50, 40})),
+		stringAttribute("units", "degrees_north"),
+	}, dimensionScale("lat", "lat", 1)...), 0))
+
+	lon := testHDF5Float64s([]float64{10, 15, 20})
+	link("lon", objectHeader(append([]testHDF5Message{
+		dataspaceMessage([]int{3}, false),
+		datatypeMessage(testHDF5FloatDatatype(8)),
+		{
+			messageType: hdf5MessageLayout,
+			data:        testHDF5Cat([]byte{3, hdf5LayoutCompact}, testHDF5Uint(2, uint64(len(lon))), lon),
+		},
+		stringAttribute("units", "degrees_east"),
+	}, dimensionScale("lon", "lon", 2)...), 0))
+
+	timeDataspace := dataspaceMessage([]int{2}, true)
+	if options.nullTimeDims != nil {
+		timeDataspace = dataspaceMessage(options.nullTimeDims, false)
+		timeDataspace.data[3] = 2 // Null dataspace.
+	}
+	link("time", objectHeader(append([]testHDF5Message{
+		timeDataspace,
+		datatypeMessage(testHDF5FloatDatatype(8)),
+		chunkedLayout(w.chunks([]float64{12, 18}, []int{2}, []int{1}, 8, testHDF5Float64s, false, 0), []int{1}, 8),
+		stringAttribute("units", "hours since 2016-12-19 00:00:00"),
+	}, dimensionScale("time", "time", 0)...), 0))
+
+	if options.dimensionOnly {
+		link("bnds", objectHeader(append([]testHDF5Message{
+			dataspaceMessage([]int{2}, false),
+			datatypeMessage(testHDF5FloatDatatype(4)),
+			contiguousLayout(nil),
+		}, dimensionScale("bnds", netCDF4DimensionOnly+".          2", 3)...), 0))
+	}
+
+	globalHeapAddress := w.globalHeap([][]byte{
+		testHDF5Uint(8, links["time"]),
+		testHDF5Uint(8, links["lat"]),
+		testHDF5Uint(8, links["lon"]),
+		[]byte("Meteomatics"),
+	})
+	dimensionList := attribute(
+		"DIMENSION_LIST",
+		testHDF5Cat([]byte{0x19, 0, 0, 0}, testHDF5Uint(4, 16), []byte{0x17, 0, 0, 0}, testHDF5Uint(4, 8)),
+		dataspace([]int{3}, false),
+		testHDF5Cat(
+			testHDF5Uint(4, 1), testHDF5Uint(8, globalHeapAddress), testHDF5Uint(4, 1),
+			testHDF5Uint(4, 1), testHDF5Uint(8, globalHeapAddress), testHDF5Uint(4, 2),
+			testHDF5Uint(4, 1), testHDF5Uint(8, globalHeapAddress), testHDF5Uint(4, 3),
+		),
+	)
+
+	var filterPipeline []byte
+	if v2 {
+		filterPipeline = testHDF5Cat(
+			[]byte{2, 2},
+			testHDF5Uint(2, hdf5FilterShuffle), testHDF5Uint(2, 1), testHDF5Uint(2, 1), testHDF5Uint(4, 4),
+			testHDF5Uint(2, uint64(options.filterID)),
+		)
+		if options.filterID >= 256 {
+			filterPipeline = append(filterPipeline, testHDF5Uint(2, 0)...)
+		}
+		filterPipeline = append(filterPipeline, testHDF5Cat(testHDF5Uint(2, 0), testHDF5Uint(2, 1), testHDF5Uint(4, 6))...)
+	} else {
+		filterPipeline = testHDF5Cat(
+			[]byte{1, 2, 0, 0, 0, 0, 0, 0},
+			testHDF5Uint(2, hdf5FilterShuffle), testHDF5Uint(2, 0), testHDF5Uint(2, 1), testHDF5Uint(2, 1), testHDF5Uint(4, 4), make([]byte, 4),
+			testHDF5Uint(2, uint64(options.filterID)), testHDF5Uint(2, 0), testHDF5Uint(2, 0), testHDF5Uint(2, 1), testHDF5Uint(4, 6), make([]byte, 4),
+		)
+	}
+	link("t_2m", objectHeader([]testHDF5Message{
+		dataspaceMessage([]int{2, 2, 3}, true),
+		datatypeMessage(testHDF5FloatDatatype(4)),
+		chunkedLayout(w.chunks(
+			[]float64{1.5, 2.5, 3.5, 4.5, 5.5, -999, -1.5, -2.5, -3.5, -4.5, -5.5, -6.5},
+			[]int{2, 2, 3}, []int{1, 2, 2}, 4, testHDF5Float32s, true, options.filterID,
+		), []int{1, 2, 2}, 4),
+		{
+			messageType: hdf5MessageFilterPipeline,
+			data:        filterPipeline,
+		},
+		stringAttribute("units", "C"),
+		attribute("_FillValue", testHDF5FloatDatatype(4), dataspace([]int{1}, false), testHDF5Float32s([]float64{-999})),
+		dimensionList,
+	}, 3))
+
+	windSpeedDims := options.windSpeedDims
+	if windSpeedDims == nil {
+		windSpeedDims = []int{2, 2, 3}
+	}
+	windSpeed := make([]byte, 0, 24)
+	for _, value := range []float64{14, 16, 18, 20, 22, 24, 0, 1, 2, 3, 4, 5} {
+		windSpeed = append(windSpeed, byte(uint16(value)>>8), byte(value))
+	}
+	link("wind_speed_10m", objectHeader([]testHDF5Message{
+		dataspaceMessage(windSpeedDims, true),
+		datatypeMessage(testHDF5FixedPointDatatype(2, true, true)),
+		contiguousLayout(windSpeed),
+		attribute("scale_factor", testHDF5FloatDatatype(8), dataspace([]int{1}, false), testHDF5Float64s([]float64{0.5})),
+		dimensionList,
+	}, 0))
+
+	globalAttributes := []testHDF5Message{
+		stringAttribute("Conventions", "CF-1.6"),
+		stringAttribute("_NCProperties", "version=2,netcdf=4.7.4,hdf5=1.10.6"),
+		attribute(
+			"title",
+			testHDF5Cat([]byte{0x19, 1, 0, 0}, testHDF5Uint(4, 16), testHDF5FixedPointDatatype(1, false, false)),
+			dataspace(nil, false),
+			testHDF5Cat(testHDF5Uint(4, 11), testHDF5Uint(8, globalHeapAddress), testHDF5Uint(4, 4)),
+		),
+		attribute("compound", testHDF5Cat([]byte{0x16, 0, 0, 0}, testHDF5Uint(4, 4)), dataspace(nil, false), make([]byte, 4)),
+	}
+
+	var rootMessages []testHDF5Message
+	switch {
+	case !v2:
+		rootMessages = append([]testHDF5Message{w.symbolTable(links)}, globalAttributes...)
+	case options.dense:
+		linkMessages := make([][]byte, 0, len(linkNames))
+		for i, name := range linkNames {
+			linkMessages = append(linkMessages, testHDF5LinkMessage(name, links[name], i))
+		}
+		linkHeapAddress, linkIDs := w.fractalHeap(linkMessages, 7, false)
+		linkRecords := make([][]byte, 0, len(linkIDs))
+		for _, id := range linkIDs {
+			linkRecords = append(linkRecords, testHDF5Cat(make([]byte, 4), id))
+		}
+		attributeMessages := make([][]byte, 0, len(globalAttributes))
+		for _, a := range globalAttributes {
+			attributeMessages = append(attributeMessages, a.data)
+		}
+		attributeHeapAddress, attributeIDs := w.fractalHeap(attributeMessages, 8, true)
+		attributeRecords := make([][]byte, 0, len(attributeIDs))
+		for i, id := range attributeIDs {
+			attributeRecords = append(attributeRecords, testHDF5Cat(id, []byte{0}, testHDF5Uint(4, uint64(i)), make([]byte, 4)))
+		}
+		rootMessages = []testHDF5Message{
+			{
+				messageType: hdf5MessageLinkInfo,
+				data: testHDF5Cat(
+					[]byte{0, 1},
+					testHDF5Uint(8, uint64(len(linkNames))),
+					testHDF5Uint(8, linkHeapAddress),
+					testHDF5Uint(8, w.btree2(5, linkRecords, true)),
+				),
+			},
+			{
+				messageType: hdf5MessageAttributeInfo,
+				data: testHDF5Cat(
+					[]byte{0, 0},
+					testHDF5Uint(8, attributeHeapAddress),
+					testHDF5Uint(8, w.btree2(8, attributeRecords, false)),
+				),
+			},
+		}
+	default:
+		rootMessages = []testHDF5Message{
+			{
+				messageType: hdf5MessageLinkInfo,
+				data:        testHDF5Cat([]byte{0, 0}, testHDF5Uint(8, testHDF5Undefined), testHDF5Uint(8, testHDF5Undefined)),
+			},
+		}
+		// Write the links in reverse order to check that they are sorted by
+		// creation order.
+		for i := len(linkNames) - 1; i >= 0; i-- {
+			rootMessages = append(rootMessages, testHDF5Message{
+				messageType: hdf5MessageLink,
+				data:        testHDF5LinkMessage(linkNames[i], links[linkNames[i]], i),
+			})
+		}
+		rootMessages = append(rootMessages, globalAttributes...)
+	}
+	rootAddress := objectHeader(rootMessages, 0)
+
+	var superblock []byte
+	if v2 {
+		superblock = testHDF5Cat(
+			hdf5Magic,
+			[]byte{2, 8, 8, 0},
+			testHDF5Uint(8, 0),
+			testHDF5Uint(8, testHDF5Undefined),
+			testHDF5Uint(8, uint64(len(w.data))),
+			testHDF5Uint(8, rootAddress),
+			make([]byte, 4),
+		)
+	} else {
+		superblock = testHDF5Cat(
+			hdf5Magic,
+			[]byte{0, 0, 0, 0, 0, 8, 8, 0},
+			testHDF5Uint(2, 4),
+			testHDF5Uint(2, 16),
+			make([]byte, 4),
+			testHDF5Uint(8, 0),
+			testHDF5Uint(8, testHDF5Undefined),
+			testHDF5Uint(8, uint64(len(w.data))),
+			testHDF5Uint(8, testHDF5Undefined),
+			testHDF5Uint(8, 0),
+			testHDF5Uint(8, rootAddress),
+			make([]byte, 24),
+		)
+	}
+	require.True(t, len(superblock) <= superblockSize)
+	copy(w.data, superblock)
+	return w.data
+}
+
+func TestParseNetCDF4(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		options            testNetCDF4Options
+		expectedDimensions []NetCDFDimension
+	}{
+		{
+			name: "superblock_0",
+			options: testNetCDF4Options{
+				superblockVersion: 0,
+			},
+		},
+		{
+			name: "superblock_2",
+			options: testNetCDF4Options{
+				superblockVersion: 2,
+			},
+		},
+		{
+			name: "superblock_2_dense",
+			options: testNetCDF4Options{
+				superblockVersion: 2,
+				dense:             true,
+			},
+		},
+		{
+			name: "dimension_only",
+			options: testNetCDF4Options{
+				superblockVersion: 2,
+				dimensionOnly:     true,
+			},
+			expectedDimensions: []NetCDFDimension{
+				{Name: "time", Len: 2, Unlimited: true},
+				{Name: "lat", Len: 2},
+				{Name: "lon", Len: 3},
+				{Name: "bnds", Len: 2},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := ParseNetCDF(encodeTestNetCDF4(t, tc.options))
+			require.NoError(t, err)
+			expectedDimensions := tc.expectedDimensions
+			if expectedDimensions == nil {
+				expectedDimensions = []NetCDFDimension{
+					{Name: "time", Len: 2, Unlimited: true},
+					{Name: "lat", Len: 2},
+					{Name: "lon", Len: 3},
+				}
+			}
+			assert.Equal(t, expectedDimensions, f.Dimensions)
+			assert.Equal(t, map[string]interface{}{
+				"Conventions": "CF-1.6",
+				"title":       "Meteomatics",
+			}, f.Attributes)
+			assertTestNetCDFVariables(t, f)
+			if tc.options.superblockVersion >= 2 {
+				names := make([]string, 0, len(f.Variables))
+				for _, v := range f.Variables {
+					names = append(names, v.Name)
+				}
+				assert.Equal(t, []string{"lat", "lon", "time", "t_2m", "wind_speed_10m"}, names)
+			}
+		})
+	}
+}
+
+func TestParseNetCDF4Errors(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		data        []byte
+		expectedErr error
+	}{
+		{
+			name:        "truncated_superblock",
+			data:        []byte("\x89HDF\r\n\x1a\n\x00\x00\x00\x00"),
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name:        "superblock_version",
+			data:        []byte("\x89HDF\r\n\x1a\n\x04\x00\x00\x00"),
+			expectedErr: ErrNetCDF4Unsupported,
+		},
+		{
+			name: "filter",
+			data: encodeTestNetCDF4(t, testNetCDF4Options{
+				superblockVersion: 2,
+				filterID:          32015,
+			}),
+			expectedErr: ErrNetCDF4Unsupported,
+		},
+		{
+			name: "dataspace_overflow",
+			data: encodeTestNetCDF4(t, testNetCDF4Options{
+				superblockVersion: 2,
+				windSpeedDims:     []int{1 << 16, 1 << 16, 3},
+			}),
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name: "dataspace_too_large",
+			data: encodeTestNetCDF4(t, testNetCDF4Options{
+				superblockVersion: 2,
+				windSpeedDims:     []int{1 << 10, 1 << 10, 3},
+			}),
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name: "null_dataspace_with_dims",
+			data: encodeTestNetCDF4(t, testNetCDF4Options{
+				superblockVersion: 2,
+				nullTimeDims:      []int{2},
+			}),
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name: "null_dataspace_with_layout",
+			data: encodeTestNetCDF4(t, testNetCDF4Options{
+				superblockVersion: 2,
+				nullTimeDims:      []int{},
+			}),
+			expectedErr: errNetCDFParse,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseNetCDF(tc.data)
+			assert.True(t, errors.Is(err, tc.expectedErr), err)
+		})
+	}
+}
+
+func TestParseNetCDF4Malformed(t *testing.T) {
+	for _, options := range []testNetCDF4Options{
+		{superblockVersion: 0},
+		{superblockVersion: 2, dense: true},
+	} {
+		data := encodeTestNetCDF4(t, options)
+		// The last eight bytes might be padding.
+		for n := 0; n < len(data)-8; n += 7 {
+			_, err := ParseNetCDF(data[:n])
+			assert.Error(t, err)
+		}
+		corrupted := make([]byte, len(data))
+		for i := range data {
+			for _, b := range []byte{0x00, 0x7f, 0xff} {
+				copy(corrupted, data)
+				corrupted[i] = b
+				assert.NotPanics(t, func() {
+					_, _ = ParseNetCDF(corrupted)
+				})
+			}
+		}
+	}
+}
+
+// testHDF5Cat returns the concatenation of parts.
+func testHDF5Cat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// testHDF5Pad8 returns a copy of b padded with zeros to a multiple of eight
+// bytes.
+func testHDF5Pad8(b []byte) []byte {
+	padded := make([]byte, pad8(len(b)))
+	copy(padded, b)
+	return padded
+}
+
+// testHDF5Uint returns v as an n-byte little-endian unsigned integer.
+func testHDF5Uint(n int, v uint64) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(v >> (8 * uint(i)))
+	}
+	return b
+}
+
+// testHDF5Float32s returns values encoded as little-endian float32s.
+func testHDF5Float32s(values []float64) []byte {
+	b := make([]byte, 0, 4*len(values))
+	for _, value := range values {
+		b = append(b, testHDF5Uint(4, uint64(math.Float32bits(float32(value))))...)
+	}
+	return b
+}
+
+// testHDF5Float64s returns values encoded as little-endian float64s.
+func testHDF5Float64s(values []float64) []byte {
+	b := make([]byte, 0, 8*len(values))
+	for _, value := range values {
+		b = append(b, testHDF5Uint(8, math.Float64bits(value))...)
+	}
+	return b
+}
+
+// testHDF5FixedPointDatatype returns a fixed-point datatype.
+func testHDF5FixedPointDatatype(size int, signed, bigEndian bool) []byte {
+	classBits := byte(0)
+	if bigEndian {
+		classBits |= 0x01
+	}
+	if signed {
+		classBits |= 0x08
+	}
+	return testHDF5Cat([]byte{0x10, classBits, 0, 0}, testHDF5Uint(4, uint64(size)), testHDF5Uint(2, 0), testHDF5Uint(2, uint64(8*size)))
+}
+
+// testHDF5FloatDatatype returns a little-endian IEEE floating-point datatype.
+func testHDF5FloatDatatype(size int) []byte {
+	if size == 4 {
+		return testHDF5Cat([]byte{0x11, 0x20, 31, 0}, testHDF5Uint(4, 4), testHDF5Uint(2, 0), testHDF5Uint(2, 32), []byte{23, 8, 0, 23}, testHDF5Uint(4, 127))
+	}
+	return testHDF5Cat([]byte{0x11, 0x20, 63, 0}, testHDF5Uint(4, 8), testHDF5Uint(2, 0), testHDF5Uint(2, 64), []byte{52, 11, 0, 52}, testHDF5Uint(4, 1023))
+}
+
+// testHDF5StringDatatype returns a null-terminated fixed-length string
+// datatype.
+func testHDF5StringDatatype(size int) []byte {
+	return testHDF5Cat([]byte{0x13, 0, 0, 0}, testHDF5Uint(4, uint64(size)))
+}
+
+// testHDF5LinkMessage returns a hard link message with creation order.
+func testHDF5LinkMessage(name string, address uint64, creationOrder int) []byte {
+	return testHDF5Cat([]byte{1, 0x04}, testHDF5Uint(8, uint64(creationOrder)), []byte{byte(len(name))}, []byte(name), testHDF5Uint(8, address))
+}
@@ -0,0 +1,412 @@
+package meteomatics
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A testNetCDFVariable is a variable to be encoded by encodeTestNetCDF.
+type testNetCDFVariable struct {
+	name       string
+	dimIDs     []int
+	attributes []testNetCDFAttribute
+	ncType     int
+	values     []float64
+}
+
+// A testNetCDFAttribute is an attribute to be encoded by encodeTestNetCDF.
+type testNetCDFAttribute struct {
+	name   string
+	ncType int
+	value  interface{}
+}
+
+// encodeTestNetCDF encodes a NetCDF CDF-1 file. Dimensions with length zero
+// are unlimited. Record variables' values contain all records.
+func encodeTestNetCDF(t *testing.T, dimNames []string, dimLens []int, numRecs int, gatts []testNetCDFAttribute, vars []testNetCDFVariable) []byte {
+	t.Helper()
+
+	writeInt := func(b *bytes.Buffer, n int) {
+		require.NoError(t, binary.Write(b, binary.BigEndian, int32(n)))
+	}
+	writePadding := func(b *bytes.Buffer) {
+		for b.Len()%4 != 0 {
+			b.WriteByte(0)
+		}
+	}
+	writeName := func(b *bytes.Buffer, name string) {
+		writeInt(b, len(name))
+		b.WriteString(name)
+		writePadding(b)
+	}
+	writeValues := func(b *bytes.Buffer, ncType int, values []float64) {
+		for _, value := range values {
+			switch ncType {
+			case netCDFShort:
+				require.NoError(t, binary.Write(b, binary.BigEndian, int16(value)))
+			case netCDFInt:
+				require.NoError(t, binary.Write(b, binary.BigEndian, int32(value)))
+			case netCDFFloat:
+				require.NoError(t, binary.Write(b, binary.BigEndian, float32(value)))
+			case netCDFDouble:
+				require.NoError(t, binary.Write(b, binary.BigEndian, value))
+			default:
+				t.Fatalf("%d: unsupported type", ncType)
+			}
+		}
+	}
+	writeAttributes := func(b *bytes.Buffer, atts []testNetCDFAttribute) {
+		if len(atts) == 0 {
+			writeInt(b, netCDFAbsent)
+			writeInt(b, 0)
+			return
+		}
+		writeInt(b, netCDFAttribute)
+		writeInt(b, len(atts))
+		for _, att := range atts {
+			writeName(b, att.name)
+			writeInt(b, att.ncType)
+			switch value := att.value.(type) {
+			case string:
+				writeInt(b, len(value))
+				b.WriteString(value)
+			case []float64:
+				writeInt(b, len(value))
+				writeValues(b, att.ncType, value)
+			}
+			writePadding(b)
+		}
+	}
+
+	isRecord := func(v testNetCDFVariable) bool {
+		return len(v.dimIDs) > 0 && dimLens[v.dimIDs[0]] == 0
+	}
+	vsize := func(v testNetCDFVariable) int {
+		n := netCDFTypeSize(v.ncType)
+		for i, dimID := range v.dimIDs {
+			if i != 0 || !isRecord(v) {
+				n *= dimLens[dimID]
+			}
+		}
+		return pad4(n)
+	}
+
+	header := func(begins []int) []byte {
+		b := &bytes.Buffer{}
+		b.WriteString("CDF\x01")
+		writeInt(b, numRecs)
+		writeInt(b, netCDFDimension)
+		writeInt(b, len(dimNames))
+		for i, name := range dimNames {
+			writeName(b, name)
+			writeInt(b, dimLens[i])
+		}
+		writeAttributes(b, gatts)
+		writeInt(b, netCDFVariable)
+		writeInt(b, len(vars))
+		for i, v := range vars {
+			writeName(b, v.name)
+			writeInt(b, len(v.dimIDs))
+			for _, dimID := range v.dimIDs {
+				writeInt(b, dimID)
+			}
+			writeAttributes(b, v.attributes)
+			writeInt(b, v.ncType)
+			writeInt(b, vsize(v))
+			writeInt(b, begins[i])
+		}
+		return b.Bytes()
+	}
+
+	begins := make([]int, len(vars))
+	offset := len(header(begins))
+	recSize := 0
+	for i, v := range vars {
+		if !isRecord(v) {
+			begins[i] = offset
+			offset += vsize(v)
+		}
+	}
+	for i, v := range vars {
+		if isRecord(v) {
+			begins[i] = offset + recSize
+			recSize += vsize(v)
+		}
+	}
+
+	b := bytes.NewBuffer(header(begins))
+	for _, v := range vars {
+		if !isRecord(v) {
+			writeValues(b, v.ncType, v.values)
+			writePadding(b)
+		}
+	}
+	for rec := 0; rec < numRecs; rec++ {
+		for _, v := range vars {
+			if isRecord(v) {
+				n := len(v.values) / numRecs
+				writeValues(b, v.ncType, v.values[rec*n:(rec+1)*n])
+				writePadding(b)
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+func newTestNetCDF(t *testing.T) []byte {
+	return encodeTestNetCDF(
+		t,
+		[]string{"time", "lat", "lon"},
+		[]int{0, 2, 3},
+		2,
+		[]testNetCDFAttribute{
+			{name: "Conventions", ncType: netCDFChar, value: "CF-1.6"},
+		},
+		[]testNetCDFVariable{
+			{
+				name:   "lat",
+				dimIDs: []int{1},
+				attributes: []testNetCDFAttribute{
+					{name: "units", ncType: netCDFChar, value: "degrees_north"},
+				},
+				ncType: netCDFDouble,
+				values: []float64{50, 40},
+			},
+			{
+				name:   "lon",
+				dimIDs: []int{2},
+				attributes: []testNetCDFAttribute{
+					{name: "units", ncType: netCDFChar, value: "degrees_east"},
+				},
+				ncType: netCDFDouble,
+				values: []float64{10, 15, 20},
+			},
+			{
+				name:   "time",
+				dimIDs: []int{0},
+				attributes: []testNetCDFAttribute{
+					{name: "units", ncType: netCDFChar, value: "hours since 2016-12-19 00:00:00"},
+				},
+				ncType: netCDFDouble,
+				values: []float64{12, 18},
+			},
+			{
+				name:   "t_2m",
+				dimIDs: []int{0, 1, 2},
+				attributes: []testNetCDFAttribute{
+					{name: "units", ncType: netCDFChar, value: "C"},
+					{name: "_FillValue", ncType: netCDFFloat, value: []float64{-999}},
+				},
+				ncType: netCDFFloat,
+				values: []float64{1.5, 2.5, 3.5, 4.5, 5.5, -999, -1.5, -2.5, -3.5, -4.5, -5.5, -6.5},
+			},
+			{
+				name:   "wind_speed_10m",
+				dimIDs: []int{0, 1, 2},
+				attributes: []testNetCDFAttribute{
+					{name: "scale_factor", ncType: netCDFDouble, value: []float64{0.5}},
+				},
+				ncType: netCDFShort,
+				values: []float64{14, 16, 18, 20, 22, 24, 0, 1, 2, 3, 4, 5},
+			},
+		},
+	)
+}
+
+func TestParseNetCDF(t *testing.T) {
+	f, err := ParseNetCDF(newTestNetCDF(t))
+	require.NoError(t, err)
+	assert.Equal(t, []NetCDFDimension{
+		{Name: "time", Len: 2, Unlimited: true},
+		{Name: "lat", Len: 2},
+		{Name: "lon", Len: 3},
+	}, f.Dimensions)
+	assert.Equal(t, map[string]interface{}{"Conventions": "CF-1.6"}, f.Attributes)
+	assertTestNetCDFVariables(t, f)
+}
+
+// assertTestNetCDFVariables asserts that f contains the variables of
+// newTestNetCDF.
+func assertTestNetCDFVariables(t *testing.T, f *NetCDFFile) {
+	t.Helper()
+	require.Len(t, f.Variables, 5)
+
+	lat := f.Variable("lat")
+	require.NotNil(t, lat)
+	assert.Equal(t, []string{"lat"}, lat.Dimensions)
+	assert.Equal(t, []float64{50, 40}, lat.Values)
+
+	times, err := f.Variable("time").Times()
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC),
+		time.Date(2016, 12, 19, 18, 0, 0, 0, time.UTC),
+	}, times)
+
+	t2m := f.Variable("t_2m")
+	require.NotNil(t, t2m)
+	assert.Equal(t, []string{"time", "lat", "lon"}, t2m.Dimensions)
+	assert.Equal(t, "C", t2m.Attributes["units"])
+	require.Len(t, t2m.Values, 12)
+	assert.Equal(t, []float64{1.5, 2.5, 3.5, 4.5, 5.5}, t2m.Values[:5])
+	assert.True(t, math.IsNaN(t2m.Values[5]))
+	assert.Equal(t, []float64{-1.5, -2.5, -3.5, -4.5, -5.5, -6.5}, t2m.Values[6:])
+
+	windSpeed := f.Variable("wind_speed_10m")
+	require.NotNil(t, windSpeed)
+	assert.Equal(t, []float64{7, 8, 9, 10, 11, 12, 0, 0.5, 1, 1.5, 2, 2.5}, windSpeed.Values)
+
+	assert.Nil(t, f.Variable("precip_1h"))
+}
+
+func TestParseNetCDFErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		data        []byte
+		expectedErr error
+	}{
+		{
+			name:        "empty",
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name:        "unknown_version",
+			data:        []byte("CDF\x03\x00\x00\x00\x00"),
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name:        "truncated",
+			data:        newTestNetCDF(t)[:100],
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name: "overflow",
+			data: encodeTestNetCDF(t, []string{"x", "y"}, []int{1 << 16, 1 << 16}, 0, nil, []testNetCDFVariable{
+				{name: "v", dimIDs: []int{0, 1}, ncType: netCDFDouble},
+			}),
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name: "too_large",
+			data: encodeTestNetCDF(t, []string{"x", "y"}, []int{1 << 10, 1 << 10}, 0, nil, []testNetCDFVariable{
+				{name: "v", dimIDs: []int{0, 1}, ncType: netCDFDouble},
+			}),
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name: "too_many_records",
+			data: encodeTestNetCDF(t, []string{"time", "x"}, []int{0, 1 << 10}, 1<<20, nil, []testNetCDFVariable{
+				{name: "v", dimIDs: []int{0, 1}, ncType: netCDFDouble},
+			}),
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name:        "huge_num_dims",
+			data:        []byte("CDF\x01\x00\x00\x00\x00\x00\x00\x00\x0a\x7f\xff\xff\xff"),
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name:        "huge_num_attrs",
+			data:        []byte("CDF\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x0c\x7f\xff\xff\xff"),
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name:        "huge_nvars",
+			data:        []byte("CDF\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x0b\x7f\xff\xff\xff"),
+			expectedErr: errNetCDFParse,
+		},
+		{
+			name: "huge_var_num_dims",
+			data: []byte("CDF\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x0b\x00\x00\x00\x01" +
+				"\x00\x00\x00\x01v\x00\x00\x00\x7f\xff\xff\xff\x00\x00\x00\x00"),
+			expectedErr: errNetCDFParse,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseNetCDF(tc.data)
+			assert.True(t, errors.Is(err, tc.expectedErr))
+		})
+	}
+}
+
+func TestClientRequestNetCDF(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "classic",
+			data: newTestNetCDF(t),
+		},
+		{
+			name: "netcdf4",
+			data: encodeTestNetCDF4(t, testNetCDF4Options{
+				superblockVersion: 2,
+				dense:             true,
+			}),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data := tc.data
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/2016-12-19T12:00:00ZPT6H:PT6H/t_2m:C,wind_speed_10m:ms/50,10_40,20:3x2/netcdf", r.URL.Path)
+				_, _ = w.Write(data)
+			}))
+			defer s.Close()
+
+			r, err := NewClient(WithBaseURL(s.URL)).RequestNetCDF(
+				context.Background(),
+				TimePeriod{
+					Start:    time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC),
+					Duration: 6 * time.Hour,
+					Step:     6 * time.Hour,
+				},
+				ParameterSlice{
+					Parameter{
+						Name:  ParameterTemperature,
+						Level: LevelMeters(2),
+						Units: UnitsCelsius,
+					},
+					Parameter{
+						Name:  ParameterWindSpeed,
+						Level: LevelMeters(10),
+						Units: UnitsMetersPerSecond,
+					},
+				},
+				RectangleN{
+					Min: Point{
+						Lat: 40,
+						Lon: 10,
+					},
+					Max: Point{
+						Lat: 50,
+						Lon: 20,
+					},
+					NLon: 3,
+					NLat: 2,
+				},
+				nil,
+			)
+			require.NoError(t, err)
+			assert.Equal(t, []float64{50, 40}, r.Lats)
+			assert.Equal(t, []float64{10, 15, 20}, r.Lons)
+			assert.Equal(t, []time.Time{
+				time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC),
+				time.Date(2016, 12, 19, 18, 0, 0, 0, time.UTC),
+			}, r.Times)
+			require.Len(t, r.Data, 2)
+			assert.Equal(t, "t_2m", r.Data[0].Name)
+			assert.Equal(t, "wind_speed_10m", r.Data[1].Name)
+		})
+	}
+}
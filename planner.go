@@ -0,0 +1,403 @@
+package meteomatics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Request limits.
+const (
+	DefaultMaxParameters = 10
+	DefaultMaxURLLength  = 2048
+)
+
+// Planner errors.
+var (
+	ErrRequestTooLarge = errors.New("request too large")
+	errMergeMismatch   = errors.New("sub-responses do not match")
+	errPlannerRoute    = errors.New("route requests cannot be planned")
+)
+
+// A Planner splits requests that exceed the API's URL length or parameter
+// limits into several smaller requests along the parameter, location, and time
+// axes, performs them concurrently, and merges the results preserving the
+// order of the original request. Only TimeSlices, ParameterSlices,
+// comma-separated ParameterStrings, LocationSlices, and PointLists can be
+// split. Route requests are not supported. URL lengths include any query
+// parameters added by the Client's authentication, such as access tokens.
+type Planner struct {
+	Client         *Client
+	MaxParameters  int // If zero, DefaultMaxParameters is used.
+	MaxURLLength   int // If zero, DefaultMaxURLLength is used.
+	MaxConcurrency int // If zero, the number of concurrent requests is not limited.
+}
+
+// A plan is a set of sub-requests covering a request. Sub-request (i, j, k)
+// requests times[i], parameters[j], and locations[k].
+type plan struct {
+	times      []TimeStringer
+	parameters []ParameterStringer
+	locations  []LocationStringer
+}
+
+// RequestCSV requests a forecast in CSV format, splitting the request if
+// necessary. The location cannot be split because a CSVResponse describes a
+// single location.
+func (p *Planner) RequestCSV(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*CSVResponse, error) {
+	pl, err := p.plan(ctx, ts, ps, ls, FormatCSV, options, false)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*CSVResponse, len(pl.times)*len(pl.parameters))
+	if err := p.run(ctx, len(results), func(ctx context.Context, index int) error {
+		i, j := index/len(pl.parameters), index%len(pl.parameters)
+		cr, err := p.Client.RequestCSV(ctx, pl.times[i], pl.parameters[j], pl.locations[0], options)
+		results[index] = cr
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return mergeCSVResponses(results, len(pl.times), len(pl.parameters))
+}
+
+// RequestJSON requests a forecast in JSON format, splitting the request if
+// necessary.
+func (p *Planner) RequestJSON(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*JSONResponse, error) {
+	pl, err := p.plan(ctx, ts, ps, ls, FormatJSON, options, true)
+	if err != nil {
+		return nil, err
+	}
+
+	nj, nk := len(pl.parameters), len(pl.locations)
+	results := make([]*JSONResponse, len(pl.times)*nj*nk)
+	if err := p.run(ctx, len(results), func(ctx context.Context, index int) error {
+		i, j, k := index/(nj*nk), index/nk%nj, index%nk
+		jr, err := p.Client.RequestJSON(ctx, pl.times[i], pl.parameters[j], pl.locations[k], options)
+		results[index] = jr
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return mergeJSONResponses(results, len(pl.times), nj, nk)
+}
+
+// plan returns the plan for a request.
+func (p *Planner) plan(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, fs FormatStringer, options *RequestOptions, splitLocations bool) (*plan, error) {
+	if options != nil && options.Route {
+		return nil, errPlannerRoute
+	}
+	maxParameters := p.MaxParameters
+	if maxParameters <= 0 {
+		maxParameters = DefaultMaxParameters
+	}
+	maxURLLength := p.MaxURLLength
+	if maxURLLength <= 0 {
+		maxURLLength = DefaultMaxURLLength
+	}
+
+	times := splitTimes(ts)
+	if len(times) == 0 {
+		times = []TimeStringer{ts}
+	}
+	parameters := splitParameters(ps)
+	if len(parameters) == 0 {
+		parameters = []ParameterStringer{ps}
+	}
+	locations := []LocationStringer{ls}
+	if splitLocations {
+		if split := splitLocationStringers(ls); len(split) != 0 {
+			locations = split
+		}
+	}
+
+	// fixedLength is the length of everything in the URL apart from the
+	// time, parameter, and location path segments, including any query
+	// parameters added by pre-request funcs.
+	fixedLength, err := p.Client.authorizedURLLength(ctx, p.Client.requestURL("", ParameterString(""), LocationString(""), fs, options))
+	if err != nil {
+		return nil, err
+	}
+
+	limits := [3]int{len(times), len(parameters), len(locations)}
+	n := [3]int{1, (len(parameters) + maxParameters - 1) / maxParameters, 1}
+	for {
+		lengths := [3]int{
+			maxTimeChunkLength(times, n[0]),
+			maxParameterChunkLength(parameters, n[1]),
+			maxLocationChunkLength(locations, n[2]),
+		}
+		if fixedLength+lengths[0]+lengths[1]+lengths[2] <= maxURLLength {
+			break
+		}
+		axis := -1
+		for i, length := range lengths {
+			if n[i] < limits[i] && (axis == -1 || length > lengths[axis]) {
+				axis = i
+			}
+		}
+		if axis == -1 {
+			return nil, ErrRequestTooLarge
+		}
+		n[axis] *= 2
+		if n[axis] > limits[axis] {
+			n[axis] = limits[axis]
+		}
+	}
+
+	return &plan{
+		times:      chunkTimes(times, n[0]),
+		parameters: chunkParameters(parameters, n[1]),
+		locations:  chunkLocations(locations, n[2]),
+	}, nil
+}
+
+// run calls f for each index in [0, n) concurrently, returning the first error
+// encountered. The context passed to f is canceled when any call returns an
+// error.
+func (p *Planner) run(ctx context.Context, n int, f func(context.Context, int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var semaphore chan struct{}
+	if p.MaxConcurrency > 0 {
+		semaphore = make(chan struct{}, p.MaxConcurrency)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if semaphore != nil {
+				select {
+				case semaphore <- struct{}{}:
+					defer func() { <-semaphore }()
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err := f(ctx, i); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+// mergeCSVResponses merges the results of a plan with ni times and nj
+// parameters.
+func mergeCSVResponses(results []*CSVResponse, ni, nj int) (*CSVResponse, error) {
+	cr := &CSVResponse{}
+	for j := 0; j < nj; j++ {
+		cr.Parameters = append(cr.Parameters, results[j].Parameters...)
+	}
+	for i := 0; i < ni; i++ {
+		first := results[i*nj]
+		for r, row := range first.Rows {
+			values := make([]float64, 0, len(cr.Parameters))
+			for j := 0; j < nj; j++ {
+				result := results[i*nj+j]
+				if len(result.Rows) != len(first.Rows) || !result.Rows[r].ValidDate.Equal(row.ValidDate) {
+					return nil, errMergeMismatch
+				}
+				values = append(values, result.Rows[r].Values...)
+			}
+			cr.Rows = append(cr.Rows, CSVRow{
+				ValidDate: row.ValidDate,
+				Values:    values,
+			})
+		}
+	}
+	return cr, nil
+}
+
+// mergeJSONResponses merges the results of a plan with ni times, nj
+// parameters, and nk locations.
+func mergeJSONResponses(results []*JSONResponse, ni, nj, nk int) (*JSONResponse, error) {
+	result := func(i, j, k int) *JSONResponse {
+		return results[(i*nj+j)*nk+k]
+	}
+
+	first := results[0]
+	jr := &JSONResponse{
+		Version:       first.Version,
+		User:          first.User,
+		DateGenerated: first.DateGenerated,
+		Status:        first.Status,
+	}
+	for j := 0; j < nj; j++ {
+		for d := range result(0, j, 0).Data {
+			data := JSONData{
+				Parameter: result(0, j, 0).Data[d].Parameter,
+			}
+			for k := 0; k < nk; k++ {
+				if len(result(0, j, k).Data) <= d {
+					return nil, errMergeMismatch
+				}
+				for c := range result(0, j, k).Data[d].Coordinates {
+					coordinates := result(0, j, k).Data[d].Coordinates[c]
+					coordinates.Dates = nil
+					for i := 0; i < ni; i++ {
+						r := result(i, j, k)
+						if len(r.Data) <= d || len(r.Data[d].Coordinates) <= c {
+							return nil, errMergeMismatch
+						}
+						coordinates.Dates = append(coordinates.Dates, r.Data[d].Coordinates[c].Dates...)
+					}
+					data.Coordinates = append(data.Coordinates, coordinates)
+				}
+			}
+			jr.Data = append(jr.Data, data)
+		}
+	}
+	return jr, nil
+}
+
+// splitTimes splits ts into its individual times.
+func splitTimes(ts TimeStringer) []TimeStringer {
+	s, ok := ts.(TimeSlice)
+	if !ok {
+		return []TimeStringer{ts}
+	}
+	var times []TimeStringer
+	for _, t := range s {
+		times = append(times, splitTimes(t)...)
+	}
+	return times
+}
+
+// splitParameters splits ps into its individual parameters.
+func splitParameters(ps ParameterStringer) []ParameterStringer {
+	switch ps := ps.(type) {
+	case ParameterSlice:
+		var parameters []ParameterStringer
+		for _, p := range ps {
+			parameters = append(parameters, splitParameters(p)...)
+		}
+		return parameters
+	case ParameterString:
+		var parameters []ParameterStringer
+		for _, p := range strings.Split(string(ps), ",") {
+			parameters = append(parameters, ParameterString(p))
+		}
+		return parameters
+	default:
+		return []ParameterStringer{ps}
+	}
+}
+
+// splitLocationStringers splits ls into its individual locations.
+func splitLocationStringers(ls LocationStringer) []LocationStringer {
+	switch ls := ls.(type) {
+	case LocationSlice:
+		var locations []LocationStringer
+		for _, l := range ls {
+			locations = append(locations, splitLocationStringers(l)...)
+		}
+		return locations
+	case PointList:
+		locations := make([]LocationStringer, 0, len(ls))
+		for _, p := range ls {
+			locations = append(locations, p)
+		}
+		return locations
+	default:
+		return []LocationStringer{ls}
+	}
+}
+
+// chunkBounds returns the bounds of n contiguous chunks of approximately
+// equal size covering length elements.
+func chunkBounds(length, n int) [][2]int {
+	if n > length {
+		n = length
+	}
+	bounds := make([][2]int, 0, n)
+	for i := 0; i < n; i++ {
+		bounds = append(bounds, [2]int{i * length / n, (i + 1) * length / n})
+	}
+	return bounds
+}
+
+func chunkTimes(times []TimeStringer, n int) []TimeStringer {
+	chunks := make([]TimeStringer, 0, n)
+	for _, b := range chunkBounds(len(times), n) {
+		if b[1]-b[0] == 1 {
+			chunks = append(chunks, times[b[0]])
+		} else {
+			chunks = append(chunks, TimeSlice(times[b[0]:b[1]]))
+		}
+	}
+	return chunks
+}
+
+func chunkParameters(parameters []ParameterStringer, n int) []ParameterStringer {
+	chunks := make([]ParameterStringer, 0, n)
+	for _, b := range chunkBounds(len(parameters), n) {
+		if b[1]-b[0] == 1 {
+			chunks = append(chunks, parameters[b[0]])
+		} else {
+			chunks = append(chunks, ParameterSlice(parameters[b[0]:b[1]]))
+		}
+	}
+	return chunks
+}
+
+func chunkLocations(locations []LocationStringer, n int) []LocationStringer {
+	chunks := make([]LocationStringer, 0, n)
+	for _, b := range chunkBounds(len(locations), n) {
+		if b[1]-b[0] == 1 {
+			chunks = append(chunks, locations[b[0]])
+		} else {
+			chunks = append(chunks, LocationSlice(locations[b[0]:b[1]]))
+		}
+	}
+	return chunks
+}
+
+func maxTimeChunkLength(times []TimeStringer, n int) int {
+	maxLength := 0
+	for _, chunk := range chunkTimes(times, n) {
+		if length := len(chunk.TimeString()); length > maxLength {
+			maxLength = length
+		}
+	}
+	return maxLength
+}
+
+func maxParameterChunkLength(parameters []ParameterStringer, n int) int {
+	maxLength := 0
+	for _, chunk := range chunkParameters(parameters, n) {
+		if length := len(chunk.ParameterString()); length > maxLength {
+			maxLength = length
+		}
+	}
+	return maxLength
+}
+
+func maxLocationChunkLength(locations []LocationStringer, n int) int {
+	maxLength := 0
+	for _, chunk := range chunkLocations(locations, n) {
+		if length := len(chunk.LocationString()); length > maxLength {
+			maxLength = length
+		}
+	}
+	return maxLength
+}
@@ -0,0 +1,236 @@
+package meteomatics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPlannerTestServer returns a server that responds to requests for times,
+// parameters named pN, and points with the value N*1000+lat*10+hour, and
+// records the URLs requested.
+func newPlannerTestServer(t *testing.T) (*httptest.Server, *[]string) {
+	var mu sync.Mutex
+	var urls []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		urls = append(urls, "http://"+r.Host+r.URL.RequestURI())
+		mu.Unlock()
+
+		segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		require.Len(t, segments, 4)
+		var times []time.Time
+		for _, s := range strings.Split(segments[0], ",") {
+			tm, err := time.Parse(time.RFC3339, s)
+			require.NoError(t, err)
+			times = append(times, tm)
+		}
+		parameters := strings.Split(segments[1], ",")
+		var points []Point
+		for _, s := range strings.Split(segments[2], "+") {
+			latLon := strings.Split(s, ",")
+			lat, err := strconv.ParseFloat(latLon[0], 64)
+			require.NoError(t, err)
+			lon, err := strconv.ParseFloat(latLon[1], 64)
+			require.NoError(t, err)
+			points = append(points, Point{Lat: lat, Lon: lon})
+		}
+		value := func(parameter string, p Point, tm time.Time) float64 {
+			n, err := strconv.Atoi(strings.TrimPrefix(parameter, "p"))
+			require.NoError(t, err)
+			return float64(n*1000) + p.Lat*10 + float64(tm.Hour())
+		}
+
+		switch segments[3] {
+		case "csv":
+			require.Len(t, points, 1)
+			fmt.Fprintf(w, "validdate;%s\n", strings.Join(parameters, ";"))
+			for _, tm := range times {
+				values := []string{tm.Format(time.RFC3339)}
+				for _, parameter := range parameters {
+					values = append(values, strconv.FormatFloat(value(parameter, points[0], tm), 'f', -1, 64))
+				}
+				fmt.Fprintln(w, strings.Join(values, ";"))
+			}
+		case "json":
+			jr := &JSONResponse{
+				Version: "3.0",
+				Status:  "OK",
+			}
+			for _, parameter := range parameters {
+				data := JSONData{
+					Parameter: ParameterString(parameter),
+				}
+				for _, p := range points {
+					coordinates := JSONCoordinates{
+						Lat: p.Lat,
+						Lon: p.Lon,
+					}
+					for _, tm := range times {
+						coordinates.Dates = append(coordinates.Dates, JSONDate{
+							Date:  tm,
+							Value: value(parameter, p, tm),
+						})
+					}
+					data.Coordinates = append(data.Coordinates, coordinates)
+				}
+				jr.Data = append(jr.Data, data)
+			}
+			require.NoError(t, json.NewEncoder(w).Encode(jr))
+		}
+	}))
+	return s, &urls
+}
+
+func newPlannerTestRequest(nTimes, nParameters, nPoints int) (TimeSlice, ParameterSlice, PointList) {
+	var ts TimeSlice
+	for i := 0; i < nTimes; i++ {
+		ts = append(ts, Time(time.Date(2019, 6, 10, i, 0, 0, 0, time.UTC)))
+	}
+	var ps ParameterSlice
+	for i := 0; i < nParameters; i++ {
+		ps = append(ps, ParameterString("p"+strconv.Itoa(i)))
+	}
+	var pl PointList
+	for i := 0; i < nPoints; i++ {
+		pl = append(pl, Point{Lat: float64(i), Lon: float64(i)})
+	}
+	return ts, ps, pl
+}
+
+func TestPlannerRequestJSON(t *testing.T) {
+	s, urls := newPlannerTestServer(t)
+	defer s.Close()
+
+	ts, ps, pl := newPlannerTestRequest(4, 23, 5)
+	p := &Planner{
+		Client:         NewClient(WithBaseURL(s.URL)),
+		MaxURLLength:   len(s.URL) + 100,
+		MaxConcurrency: 3,
+	}
+	jr, err := p.RequestJSON(context.Background(), ts, ps, pl, nil)
+	require.NoError(t, err)
+
+	assert.True(t, len(*urls) > 3)
+	for _, u := range *urls {
+		assert.True(t, len(u) <= p.MaxURLLength, u)
+	}
+
+	assert.Equal(t, "OK", jr.Status)
+	require.Len(t, jr.Data, 23)
+	for j, data := range jr.Data {
+		assert.Equal(t, ParameterString("p"+strconv.Itoa(j)), data.Parameter)
+		require.Len(t, data.Coordinates, 5)
+		for k, coordinates := range data.Coordinates {
+			assert.Equal(t, float64(k), coordinates.Lat)
+			require.Len(t, coordinates.Dates, 4)
+			for i, date := range coordinates.Dates {
+				assert.Equal(t, time.Date(2019, 6, 10, i, 0, 0, 0, time.UTC), date.Date)
+				assert.Equal(t, float64(j*1000+k*10+i), date.Value)
+			}
+		}
+	}
+}
+
+func TestPlannerRequestCSV(t *testing.T) {
+	s, urls := newPlannerTestServer(t)
+	defer s.Close()
+
+	ts, ps, pl := newPlannerTestRequest(3, 12, 1)
+	p := &Planner{
+		Client: NewClient(WithBaseURL(s.URL)),
+	}
+	cr, err := p.RequestCSV(context.Background(), ts, ps, pl[0], nil)
+	require.NoError(t, err)
+
+	assert.Len(t, *urls, 2)
+	require.Len(t, cr.Parameters, 12)
+	for j, parameter := range cr.Parameters {
+		assert.Equal(t, ParameterString("p"+strconv.Itoa(j)), parameter)
+	}
+	require.Len(t, cr.Rows, 3)
+	for i, row := range cr.Rows {
+		assert.Equal(t, time.Date(2019, 6, 10, i, 0, 0, 0, time.UTC), row.ValidDate)
+		require.Len(t, row.Values, 12)
+		for j, value := range row.Values {
+			assert.Equal(t, float64(j*1000+i), value)
+		}
+	}
+}
+
+func TestPlannerRequestTooLarge(t *testing.T) {
+	s, urls := newPlannerTestServer(t)
+	defer s.Close()
+
+	ts, ps, pl := newPlannerTestRequest(2, 2, 2)
+	p := &Planner{
+		Client:       NewClient(WithBaseURL(s.URL)),
+		MaxURLLength: len(s.URL) + 10,
+	}
+	_, err := p.RequestJSON(context.Background(), ts, ps, pl, nil)
+	assert.True(t, errors.Is(err, ErrRequestTooLarge))
+	assert.Len(t, *urls, 0)
+}
+
+func TestPlannerRequestTokenAuth(t *testing.T) {
+	s, urls := newPlannerTestServer(t)
+	defer s.Close()
+	accessToken := strings.Repeat("x", 64)
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token":%q,"token_type":"bearer"}`, accessToken)
+	}))
+	defer tokenServer.Close()
+
+	ts, ps, pl := newPlannerTestRequest(4, 8, 3)
+	p := &Planner{
+		Client: NewClient(
+			WithBaseURL(s.URL),
+			WithTokenURL(tokenServer.URL),
+			WithTokenAuth("username", "password"),
+		),
+		MaxURLLength: len(s.URL) + len("?access_token=") + len(accessToken) + 100,
+	}
+	_, err := p.RequestJSON(context.Background(), ts, ps, pl, nil)
+	require.NoError(t, err)
+	assert.True(t, len(*urls) > 1)
+	for _, u := range *urls {
+		assert.Contains(t, u, accessToken)
+		assert.True(t, len(u) <= p.MaxURLLength, u)
+	}
+}
+
+func TestPlannerRequestRoute(t *testing.T) {
+	ts, ps, pl := newPlannerTestRequest(1, 1, 2)
+	p := &Planner{
+		Client: NewClient(),
+	}
+	_, err := p.RequestJSON(context.Background(), ts, ps, pl, &RequestOptions{Route: true})
+	assert.Equal(t, errPlannerRoute, err)
+}
+
+func TestPlannerRequestError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	ts, ps, pl := newPlannerTestRequest(1, 25, 1)
+	p := &Planner{
+		Client: NewClient(WithBaseURL(s.URL)),
+	}
+	_, err := p.RequestJSON(context.Background(), ts, ps, pl, nil)
+	var e *Error
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusServiceUnavailable, e.Response.StatusCode)
+}
@@ -0,0 +1,293 @@
+package meteomatics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+var (
+	errPNGLocation = errors.New("PNG requests require a RectangleN or RectangleRes location")
+	errPNGSize     = errors.New("PNG size does not match location")
+)
+
+// A PNGResponse is a response to a PNG request. Pixel (0, 0) is at the
+// north-west corner of Bounds and each pixel is the value at a grid point,
+// spaced ResLat and ResLon apart.
+type PNGResponse struct {
+	Image  image.Image
+	Bounds Domain
+	ResLat float64
+	ResLon float64
+}
+
+// A ColorStop is a color at a position in a ColorMap.
+type ColorStop struct {
+	X     float64
+	Color color.RGBA
+}
+
+// A ColorMap maps positions between 0 and 1 to colors by linear interpolation
+// between ColorStops, which must be sorted by increasing X.
+type ColorMap []ColorStop
+
+// Color maps.
+//
+//nolint:gochecknoglobals
+var (
+	ColorMapGray = ColorMap{
+		{X: 0, Color: color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xff}},
+		{X: 1, Color: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+	}
+	ColorMapJet = ColorMap{
+		{X: 0, Color: color.RGBA{R: 0x00, G: 0x00, B: 0x80, A: 0xff}},
+		{X: 0.125, Color: color.RGBA{R: 0x00, G: 0x00, B: 0xff, A: 0xff}},
+		{X: 0.375, Color: color.RGBA{R: 0x00, G: 0xff, B: 0xff, A: 0xff}},
+		{X: 0.625, Color: color.RGBA{R: 0xff, G: 0xff, B: 0x00, A: 0xff}},
+		{X: 0.875, Color: color.RGBA{R: 0xff, G: 0x00, B: 0x00, A: 0xff}},
+		{X: 1, Color: color.RGBA{R: 0x80, G: 0x00, B: 0x00, A: 0xff}},
+	}
+	ColorMapBlues = evenColorMap(
+		color.RGBA{R: 0xf7, G: 0xfb, B: 0xff, A: 0xff},
+		color.RGBA{R: 0xde, G: 0xeb, B: 0xf7, A: 0xff},
+		color.RGBA{R: 0xc6, G: 0xdb, B: 0xef, A: 0xff},
+		color.RGBA{R: 0x9e, G: 0xca, B: 0xe1, A: 0xff},
+		color.RGBA{R: 0x6b, G: 0xae, B: 0xd6, A: 0xff},
+		color.RGBA{R: 0x42, G: 0x92, B: 0xc6, A: 0xff},
+		color.RGBA{R: 0x21, G: 0x71, B: 0xb5, A: 0xff},
+		color.RGBA{R: 0x08, G: 0x51, B: 0x9c, A: 0xff},
+		color.RGBA{R: 0x08, G: 0x30, B: 0x6b, A: 0xff},
+	)
+	ColorMapReds = evenColorMap(
+		color.RGBA{R: 0xff, G: 0xf5, B: 0xf0, A: 0xff},
+		color.RGBA{R: 0xfe, G: 0xe0, B: 0xd2, A: 0xff},
+		color.RGBA{R: 0xfc, G: 0xbb, B: 0xa1, A: 0xff},
+		color.RGBA{R: 0xfc, G: 0x92, B: 0x72, A: 0xff},
+		color.RGBA{R: 0xfb, G: 0x6a, B: 0x4a, A: 0xff},
+		color.RGBA{R: 0xef, G: 0x3b, B: 0x2c, A: 0xff},
+		color.RGBA{R: 0xcb, G: 0x18, B: 0x1d, A: 0xff},
+		color.RGBA{R: 0xa5, G: 0x0f, B: 0x15, A: 0xff},
+		color.RGBA{R: 0x67, G: 0x00, B: 0x0d, A: 0xff},
+	)
+	ColorMapPlasma = evenColorMap(
+		color.RGBA{R: 0x0d, G: 0x08, B: 0x87, A: 0xff},
+		color.RGBA{R: 0x46, G: 0x03, B: 0x9f, A: 0xff},
+		color.RGBA{R: 0x72, G: 0x01, B: 0xa8, A: 0xff},
+		color.RGBA{R: 0x9c, G: 0x17, B: 0x9e, A: 0xff},
+		color.RGBA{R: 0xbd, G: 0x37, B: 0x86, A: 0xff},
+		color.RGBA{R: 0xd8, G: 0x57, B: 0x6b, A: 0xff},
+		color.RGBA{R: 0xed, G: 0x79, B: 0x53, A: 0xff},
+		color.RGBA{R: 0xfb, G: 0x9f, B: 0x3a, A: 0xff},
+		color.RGBA{R: 0xfd, G: 0xca, B: 0x26, A: 0xff},
+		color.RGBA{R: 0xf0, G: 0xf9, B: 0x21, A: 0xff},
+	)
+	ColorMapSeismic = ColorMap{
+		{X: 0, Color: color.RGBA{R: 0x00, G: 0x00, B: 0x4c, A: 0xff}},
+		{X: 0.25, Color: color.RGBA{R: 0x00, G: 0x00, B: 0xff, A: 0xff}},
+		{X: 0.5, Color: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+		{X: 0.75, Color: color.RGBA{R: 0xff, G: 0x00, B: 0x00, A: 0xff}},
+		{X: 1, Color: color.RGBA{R: 0x80, G: 0x00, B: 0x00, A: 0xff}},
+	}
+)
+
+//nolint:gochecknoglobals
+var formatColorMaps = map[FormatString]ColorMap{
+	FormatPNGBlues.formatString:   ColorMapBlues,
+	FormatPNGGray.formatString:    ColorMapGray,
+	FormatPNGJet.formatString:     ColorMapJet,
+	FormatPNGPlasma.formatString:  ColorMapPlasma,
+	FormatPNGReds.formatString:    ColorMapReds,
+	FormatPNGSeismic.formatString: ColorMapSeismic,
+}
+
+// RequestPNG requests a forecast for a single time and parameter in a PNG
+// format. ls must be a RectangleN or a RectangleRes.
+func (c *Client) RequestPNG(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, fs FormatStringer, options *RequestOptions) (*PNGResponse, error) {
+	var pr *PNGResponse
+	var nLon, nLat int
+	switch l := ls.(type) {
+	case RectangleN:
+		pr = &PNGResponse{
+			Bounds: Domain{
+				Min: l.Min,
+				Max: l.Max,
+			},
+			ResLat: resolution(l.Min.Lat, l.Max.Lat, l.NLat),
+			ResLon: resolution(l.Min.Lon, l.Max.Lon, l.NLon),
+		}
+		nLon, nLat = l.NLon, l.NLat
+	case RectangleRes:
+		pr = &PNGResponse{
+			Bounds: Domain{
+				Min: l.Min,
+				Max: l.Max,
+			},
+			ResLat: l.ResLat,
+			ResLon: l.ResLon,
+		}
+		nLon, nLat = gridPoints(l.Min.Lon, l.Max.Lon, l.ResLon), gridPoints(l.Min.Lat, l.Max.Lat, l.ResLat)
+	default:
+		return nil, errPNGLocation
+	}
+
+	data, err := c.Request(ctx, ts, ps, ls, fs, options)
+	if err != nil {
+		return nil, err
+	}
+	pr.Image, err = png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if size := pr.Image.Bounds().Size(); nLon != 0 && nLat != 0 && (size.X != nLon || size.Y != nLat) {
+		return nil, errPNGSize
+	}
+	return pr, nil
+}
+
+// Point returns the location of the pixel at x, y.
+func (r *PNGResponse) Point(x, y int) Point {
+	b := r.Image.Bounds()
+	return Point{
+		Lat: r.Bounds.Max.Lat - float64(y-b.Min.Y)*r.ResLat,
+		Lon: r.Bounds.Min.Lon + float64(x-b.Min.X)*r.ResLon,
+	}
+}
+
+// Pixel returns the coordinates of the pixel nearest to p, and whether p is
+// within r's image.
+func (r *PNGResponse) Pixel(p Point) (int, int, bool) {
+	b := r.Image.Bounds()
+	x, y := b.Min.X, b.Min.Y
+	if r.ResLon != 0 {
+		x += int(math.Round((p.Lon - r.Bounds.Min.Lon) / r.ResLon))
+	}
+	if r.ResLat != 0 {
+		y += int(math.Round((r.Bounds.Max.Lat - p.Lat) / r.ResLat))
+	}
+	return x, y, image.Pt(x, y).In(b)
+}
+
+// Values returns the approximate values of r's pixels, indexed by lat then
+// lon, assuming that r's image was colored with m scaled between min and
+// max. Transparent pixels have value NaN.
+func (r *PNGResponse) Values(m ColorMap, min, max float64) [][]float64 {
+	b := r.Image.Bounds()
+	values := make([][]float64, 0, b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := make([]float64, 0, b.Dx())
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := r.Image.At(x, y)
+			if _, _, _, a := c.RGBA(); a == 0 {
+				row = append(row, math.NaN())
+				continue
+			}
+			row = append(row, min+m.Inverse(c)*(max-min))
+		}
+		values = append(values, row)
+	}
+	return values
+}
+
+// ColorMapForFormat returns the ColorMap used by fs, if it is known.
+func ColorMapForFormat(fs FormatStringer) (ColorMap, bool) {
+	m, ok := formatColorMaps[fs.FormatString()]
+	return m, ok
+}
+
+// Color returns the color at x, which is clamped between 0 and 1. An empty
+// ColorMap returns transparent black for all x.
+func (m ColorMap) Color(x float64) color.RGBA {
+	if len(m) == 0 {
+		return color.RGBA{}
+	}
+	if x <= m[0].X {
+		return m[0].Color
+	}
+	for i := 1; i < len(m); i++ {
+		if x <= m[i].X {
+			c0, c1 := m[i-1].Color, m[i].Color
+			t := (x - m[i-1].X) / (m[i].X - m[i-1].X)
+			return color.RGBA{
+				R: lerp8(c0.R, c1.R, t),
+				G: lerp8(c0.G, c1.G, t),
+				B: lerp8(c0.B, c1.B, t),
+				A: lerp8(c0.A, c1.A, t),
+			}
+		}
+	}
+	return m[len(m)-1].Color
+}
+
+// Inverse returns the position between 0 and 1 of the color in m nearest to
+// c.
+func (m ColorMap) Inverse(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	bestX, bestDistance := 0.0, math.Inf(1)
+	for i := 0; i < len(m)-1; i++ {
+		// Find the nearest point on the segment between adjacent stops.
+		c0, c1 := m[i].Color, m[i+1].Color
+		d := [3]float64{
+			float64(c1.R) - float64(c0.R),
+			float64(c1.G) - float64(c0.G),
+			float64(c1.B) - float64(c0.B),
+		}
+		v := [3]float64{
+			float64(r>>8) - float64(c0.R),
+			float64(g>>8) - float64(c0.G),
+			float64(b>>8) - float64(c0.B),
+		}
+		t := 0.0
+		if dd := d[0]*d[0] + d[1]*d[1] + d[2]*d[2]; dd != 0 {
+			t = math.Max(0, math.Min(1, (v[0]*d[0]+v[1]*d[1]+v[2]*d[2])/dd))
+		}
+		distance := 0.0
+		for j := range v {
+			e := v[j] - t*d[j]
+			distance += e * e
+		}
+		if distance < bestDistance {
+			bestX, bestDistance = m[i].X+t*(m[i+1].X-m[i].X), distance
+		}
+	}
+	return bestX
+}
+
+// evenColorMap returns a ColorMap with colors evenly spaced.
+func evenColorMap(colors ...color.RGBA) ColorMap {
+	m := make(ColorMap, 0, len(colors))
+	for i, c := range colors {
+		m = append(m, ColorStop{
+			X:     float64(i) / float64(len(colors)-1),
+			Color: c,
+		})
+	}
+	return m
+}
+
+// lerp8 linearly interpolates between a and b.
+func lerp8(a, b uint8, t float64) uint8 {
+	return uint8(math.Round(float64(a) + t*(float64(b)-float64(a))))
+}
+
+// resolution returns the spacing of n grid points between min and max
+// inclusive.
+func resolution(min, max float64, n int) float64 {
+	if n < 2 {
+		return 0
+	}
+	return (max - min) / float64(n-1)
+}
+
+// gridPoints returns the number of grid points with spacing res between min
+// and max inclusive, or 0 if it cannot be determined.
+func gridPoints(min, max, res float64) int {
+	n := math.Floor((max-min)/res+1e-9) + 1
+	if !(1 <= n && n <= math.MaxInt32) {
+		return 0
+	}
+	return int(n)
+}
@@ -0,0 +1,182 @@
+package meteomatics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPNGServer(t *testing.T, expectedPath string, img image.Image) *httptest.Server {
+	b := &bytes.Buffer{}
+	require.NoError(t, png.Encode(b, img))
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, expectedPath, r.URL.Path)
+		_, _ = w.Write(b.Bytes())
+	}))
+}
+
+func TestClientRequestPNG(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for x, c := range []color.NRGBA{
+		{R: 0x00, G: 0x00, B: 0x00, A: 0xff},
+		{R: 0x80, G: 0x80, B: 0x80, A: 0xff},
+		{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	} {
+		img.SetNRGBA(x, 0, c)
+	}
+	s := newTestPNGServer(t, "/2016-12-19T12:00:00Z/t_2m:C/50,10_40,20:3x2/png_gray", img)
+	defer s.Close()
+
+	r, err := NewClient(WithBaseURL(s.URL)).RequestPNG(
+		context.Background(),
+		Time(time.Date(2016, 12, 19, 12, 0, 0, 0, time.UTC)),
+		Parameter{
+			Name:  ParameterTemperature,
+			Level: LevelMeters(2),
+			Units: UnitsCelsius,
+		},
+		RectangleN{
+			Min: Point{
+				Lat: 40,
+				Lon: 10,
+			},
+			Max: Point{
+				Lat: 50,
+				Lon: 20,
+			},
+			NLon: 3,
+			NLat: 2,
+		},
+		FormatPNGGray,
+		nil,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, Domain{Min: Point{Lat: 40, Lon: 10}, Max: Point{Lat: 50, Lon: 20}}, r.Bounds)
+	assert.Equal(t, 10.0, r.ResLat)
+	assert.Equal(t, 5.0, r.ResLon)
+	assert.Equal(t, Point{Lat: 50, Lon: 10}, r.Point(0, 0))
+	assert.Equal(t, Point{Lat: 40, Lon: 20}, r.Point(2, 1))
+
+	x, y, ok := r.Pixel(Point{Lat: 41, Lon: 16})
+	assert.Equal(t, 1, x)
+	assert.Equal(t, 1, y)
+	assert.True(t, ok)
+	_, _, ok = r.Pixel(Point{Lat: 60, Lon: 16})
+	assert.False(t, ok)
+
+	m, ok := ColorMapForFormat(FormatPNGGray)
+	require.True(t, ok)
+	values := r.Values(m, -10, 10)
+	require.Len(t, values, 2)
+	assert.Equal(t, -10.0, values[0][0])
+	assert.InDelta(t, 0, values[0][1], 0.1)
+	assert.Equal(t, 10.0, values[0][2])
+	assert.True(t, math.IsNaN(values[1][0]))
+}
+
+func TestClientRequestPNGErrors(t *testing.T) {
+	s := newTestPNGServer(t, "/now/t_2m:C/50,10_40,20:3x3/png", image.NewGray(image.Rect(0, 0, 3, 2)))
+	defer s.Close()
+	client := NewClient(WithBaseURL(s.URL))
+	parameter := Parameter{
+		Name:  ParameterTemperature,
+		Level: LevelMeters(2),
+		Units: UnitsCelsius,
+	}
+
+	_, err := client.RequestPNG(context.Background(), TimeNow, parameter, Point{Lat: 50, Lon: 10}, FormatPNG, nil)
+	assert.True(t, errors.Is(err, errPNGLocation))
+
+	_, err = client.RequestPNG(context.Background(), TimeNow, parameter, RectangleN{
+		Min: Point{
+			Lat: 40,
+			Lon: 10,
+		},
+		Max: Point{
+			Lat: 50,
+			Lon: 20,
+		},
+		NLon: 3,
+		NLat: 3,
+	}, FormatPNG, nil)
+	assert.True(t, errors.Is(err, errPNGSize))
+}
+
+func TestClientRequestPNGRectangleRes(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		resLat      float64
+		expectedErr error
+	}{
+		{
+			name:   "ok",
+			resLat: 10,
+		},
+		{
+			name:        "wrong_size",
+			resLat:      5,
+			expectedErr: errPNGSize,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ls := RectangleRes{
+				Min: Point{
+					Lat: 40,
+					Lon: 10,
+				},
+				Max: Point{
+					Lat: 50,
+					Lon: 20,
+				},
+				ResLat: tc.resLat,
+				ResLon: 5,
+			}
+			s := newTestPNGServer(t, "/now/t_2m:C/"+string(ls.LocationString())+"/png", image.NewGray(image.Rect(0, 0, 3, 2)))
+			defer s.Close()
+
+			_, err := NewClient(WithBaseURL(s.URL)).RequestPNG(context.Background(), TimeNow, Parameter{
+				Name:  ParameterTemperature,
+				Level: LevelMeters(2),
+				Units: UnitsCelsius,
+			}, ls, FormatPNG, nil)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tc.expectedErr))
+			}
+		})
+	}
+}
+
+func TestColorMapInverse(t *testing.T) {
+	for _, m := range []ColorMap{
+		ColorMapBlues,
+		ColorMapGray,
+		ColorMapJet,
+		ColorMapPlasma,
+		ColorMapReds,
+		ColorMapSeismic,
+	} {
+		for _, x := range []float64{0, 0.1, 0.25, 0.5, 0.9, 1} {
+			assert.InDelta(t, x, m.Inverse(m.Color(x)), 0.02)
+		}
+	}
+	assert.Equal(t, ColorMapJet[0].Color, ColorMapJet.Color(-1))
+	assert.Equal(t, ColorMapJet[len(ColorMapJet)-1].Color, ColorMapJet.Color(2))
+}
+
+func TestColorMapEmpty(t *testing.T) {
+	assert.Equal(t, color.RGBA{}, ColorMap(nil).Color(0.5))
+	assert.Equal(t, 0.0, ColorMap(nil).Inverse(color.White))
+}
@@ -0,0 +1,125 @@
+package meteomatics
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// A rateLimiter is a token bucket rate limiter.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// A releaseOnCloseReadCloser is an io.ReadCloser that calls release when it
+// is closed.
+type releaseOnCloseReadCloser struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// WithRateLimit limits the rate of requests to r requests per second, with
+// bursts of up to burst requests. Requests that would exceed the limit wait
+// until they are permitted or their context is done. If r is not positive
+// then requests are not rate limited.
+func WithRateLimit(r float64, burst int) ClientOption {
+	return func(c *Client) {
+		if r <= 0 {
+			c.rateLimiter = nil
+			return
+		}
+		c.rateLimiter = newRateLimiter(r, burst)
+	}
+}
+
+// WithMaxConcurrentRequests limits the number of concurrent requests to n.
+// Requests beyond the limit wait until an earlier request completes or their
+// context is done. If n is not positive then the number of concurrent
+// requests is not limited.
+func WithMaxConcurrentRequests(n int) ClientOption {
+	return func(c *Client) {
+		if n <= 0 {
+			c.semaphore = nil
+			return
+		}
+		c.semaphore = make(chan struct{}, n)
+	}
+}
+
+func newRateLimiter(r float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   r,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait waits until a request is permitted or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	var d time.Duration
+	if l.tokens < 0 {
+		d = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if d == 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// acquire waits until a request is permitted by c's rate limit and
+// concurrency limit, or until ctx is done. On success, the caller must call
+// the returned function when the request is complete.
+func (c *Client) acquire(ctx context.Context) (func(), error) {
+	release := func() {}
+	if c.semaphore != nil {
+		select {
+		case c.semaphore <- struct{}{}:
+			release = func() { <-c.semaphore }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if c.rateLimiter != nil {
+		if err := c.rateLimiter.wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+func (r *releaseOnCloseReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
@@ -0,0 +1,99 @@
+package meteomatics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientMaxConcurrentRequests(t *testing.T) {
+	var mu sync.Mutex
+	concurrent, maxConcurrent := 0, 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		concurrent++
+		if concurrent > maxConcurrent {
+			maxConcurrent = concurrent
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		concurrent--
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	c := NewClient(
+		WithBaseURL(s.URL),
+		WithMaxConcurrentRequests(2),
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Request(context.Background(), TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 2, maxConcurrent)
+}
+
+func TestClientMaxConcurrentRequestsContext(t *testing.T) {
+	unblock := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	defer close(unblock)
+
+	c := NewClient(
+		WithBaseURL(s.URL),
+		WithMaxConcurrentRequests(1),
+	)
+	go func() {
+		_, _ = c.Request(context.Background(), TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.Request(ctx, TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestClientRateLimit(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	c := NewClient(
+		WithBaseURL(s.URL),
+		WithRateLimit(50, 2),
+	)
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		_, err := c.Request(context.Background(), TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+		require.NoError(t, err)
+	}
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+}
+
+func TestRateLimiterWaitContext(t *testing.T) {
+	l := newRateLimiter(1, 1)
+	require.NoError(t, l.wait(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, l.wait(ctx))
+	assert.True(t, l.tokens > -1)
+}
@@ -0,0 +1,92 @@
+package meteomatics
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// redacted replaces credentials.
+const redacted = "REDACTED"
+
+// sensitiveHeaders are the request headers that can contain credentials.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+}
+
+// sensitiveQueryKeys are the query parameters that can contain credentials.
+var sensitiveQueryKeys = []string{
+	"access_token",
+	"password",
+	"username",
+}
+
+// RedactURL returns a copy of u with any credentials redacted. The time,
+// parameter, location, and format path segments are preserved.
+func RedactURL(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+	r := *u
+	if r.User != nil {
+		if _, ok := r.User.Password(); ok {
+			r.User = url.UserPassword(r.User.Username(), redacted)
+		}
+	}
+	if r.RawQuery != "" {
+		query := r.Query()
+		changed := false
+		for _, key := range sensitiveQueryKeys {
+			if values, ok := query[key]; ok {
+				for i := range values {
+					values[i] = redacted
+				}
+				changed = true
+			}
+		}
+		if changed {
+			r.RawQuery = query.Encode()
+		}
+	}
+	return &r
+}
+
+// redactHeader returns a copy of header with any credentials redacted.
+func redactHeader(header http.Header) http.Header {
+	r := header.Clone()
+	for _, key := range sensitiveHeaders {
+		if _, ok := r[key]; ok {
+			r.Set(key, redacted)
+		}
+	}
+	return r
+}
+
+// redactRequest returns a shallow copy of req with any credentials redacted.
+func redactRequest(req *http.Request) *http.Request {
+	r := *req
+	r.URL = RedactURL(req.URL)
+	r.Header = redactHeader(req.Header)
+	return &r
+}
+
+// redactResponse returns a shallow copy of resp whose request is req, which
+// should already be redacted.
+func redactResponse(resp *http.Response, req *http.Request) *http.Response {
+	r := *resp
+	r.Request = req
+	return &r
+}
+
+// redactError redacts any credentials from the URL in err.
+func redactError(err error) error {
+	var urlError *url.Error
+	if !errors.As(err, &urlError) {
+		return err
+	}
+	if u, parseErr := url.Parse(urlError.URL); parseErr == nil {
+		urlError.URL = RedactURL(u).String()
+	}
+	return err
+}
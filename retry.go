@@ -0,0 +1,171 @@
+package meteomatics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// A RetryPolicy determines how failed requests are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum delay between attempts, ignoring any
+	// Retry-After header.
+	MaxBackoff time.Duration
+	// Multiplier is the factor by which the delay grows after each attempt.
+	Multiplier float64
+	// Jitter is the fraction of each delay that is randomized, between 0 and
+	// 1.
+	Jitter float64
+	// Retryable returns whether a request that failed with an *Error should be
+	// retried. If nil, DefaultRetryable is used.
+	Retryable func(*Error) bool
+}
+
+// DefaultRetryPolicy is a retry policy suitable for most uses.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// WithRetryPolicy sets the retry policy. Only idempotent GET requests are
+// made, so all requests are retried according to p. Transport errors that are
+// timeouts, temporary, or closed connections are always considered retryable,
+// unless they are caused by the request's context. Other errors, for example
+// those returned by pre-request funcs such as token fetches or when decoding
+// responses, are never retried.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retryPolicy = &p
+	}
+}
+
+// DefaultRetryable returns true if e is the result of a transient error: too
+// many requests, a bad gateway, an unavailable service, or a gateway timeout.
+func DefaultRetryable(e *Error) bool {
+	switch e.Response.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// A permanentError is an error that is never retried. Client.do unwraps it
+// before returning it.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// retryable returns whether a request that failed with err should be retried.
+func (p *RetryPolicy) retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		return isTransportError(err)
+	}
+	if p.Retryable != nil {
+		return p.Retryable(e)
+	}
+	return DefaultRetryable(e)
+}
+
+// isTransportError returns whether err is a transient transport-level error:
+// a timeout, a temporary network error, or a connection reset or closed before
+// the response was complete. Permanent errors, such as unsupported URL schemes
+// or invalid certificates, are not transport errors.
+func isTransportError(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) {
+		return false
+	}
+	return netErr.Timeout() || netErr.Temporary() //nolint:staticcheck
+}
+
+// delay returns the delay before the next attempt after attempt attempts
+// have failed, the last with err.
+func (p *RetryPolicy) delay(attempt int, err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) {
+		if d, ok := parseRetryAfter(e.Response.Header.Get("Retry-After"), time.Now()); ok {
+			return d
+		}
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1) //nolint:gosec
+	}
+	return time.Duration(d)
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either
+// a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// sleep waits for d or until ctx is done. It returns false without waiting
+// if ctx's deadline would expire before d elapses.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,255 @@
+package meteomatics
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRequestRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		statusCodes      []int
+		retryPolicy      RetryPolicy
+		expectedAttempts int32
+		expectedErr      bool
+	}{
+		{
+			name:        "success_after_transient_errors",
+			statusCodes: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			retryPolicy: RetryPolicy{
+				MaxAttempts:    4,
+				InitialBackoff: time.Millisecond,
+			},
+			expectedAttempts: 3,
+		},
+		{
+			name:        "max_attempts",
+			statusCodes: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			retryPolicy: RetryPolicy{
+				MaxAttempts:    2,
+				InitialBackoff: time.Millisecond,
+			},
+			expectedAttempts: 2,
+			expectedErr:      true,
+		},
+		{
+			name:        "not_retryable",
+			statusCodes: []int{http.StatusNotFound, http.StatusOK},
+			retryPolicy: RetryPolicy{
+				MaxAttempts:    4,
+				InitialBackoff: time.Millisecond,
+			},
+			expectedAttempts: 1,
+			expectedErr:      true,
+		},
+		{
+			name:        "custom_retryable",
+			statusCodes: []int{http.StatusInternalServerError, http.StatusOK},
+			retryPolicy: RetryPolicy{
+				MaxAttempts:    4,
+				InitialBackoff: time.Millisecond,
+				Retryable: func(e *Error) bool {
+					return e.Response.StatusCode == http.StatusInternalServerError
+				},
+			},
+			expectedAttempts: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var attempts int32
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := atomic.AddInt32(&attempts, 1)
+				w.WriteHeader(tc.statusCodes[attempt-1])
+			}))
+			defer s.Close()
+
+			_, err := NewClient(
+				WithBaseURL(s.URL),
+				WithRetryPolicy(tc.retryPolicy),
+			).Request(context.Background(), TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+			if tc.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedAttempts, atomic.LoadInt32(&attempts))
+		})
+	}
+}
+
+func TestClientRequestRetryErrors(t *testing.T) {
+	retryPolicy := RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}
+
+	t.Run("transport_error", func(t *testing.T) {
+		var attempts int32
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				conn.Close()
+				return
+			}
+		}))
+		defer s.Close()
+
+		_, err := NewClient(
+			WithBaseURL(s.URL),
+			WithRetryPolicy(retryPolicy),
+		).Request(context.Background(), TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("pre_request_func_error", func(t *testing.T) {
+		var calls int32
+		errPreRequest := errors.New("pre-request")
+		c := NewClient(
+			WithBaseURL("http://127.0.0.1:0"),
+			WithRetryPolicy(retryPolicy),
+		)
+		c.preRequestFuncs = append(c.preRequestFuncs, func(*http.Request) error {
+			atomic.AddInt32(&calls, 1)
+			return errPreRequest
+		})
+		_, err := c.Request(context.Background(), TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+		assert.True(t, errors.Is(err, errPreRequest))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("token_error", func(t *testing.T) {
+		var tokenRequests int32
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&tokenRequests, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer s.Close()
+
+		_, err := NewClient(
+			WithBaseURL(s.URL),
+			WithTokenURL(s.URL+"/api/v1/token"),
+			WithTokenAuth("username", "password"),
+			WithRetryPolicy(retryPolicy),
+		).Request(context.Background(), TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+		e, ok := err.(*Error)
+		require.True(t, ok)
+		assert.Equal(t, http.StatusServiceUnavailable, e.Response.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&tokenRequests))
+	})
+}
+
+func TestIsTransportError(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "eof",
+			err:      &url.Error{Op: "Get", URL: "http://example.com", Err: io.EOF},
+			expected: true,
+		},
+		{
+			name:     "connection_reset",
+			err:      &url.Error{Op: "Get", URL: "http://example.com", Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}},
+			expected: true,
+		},
+		{
+			name:     "timeout",
+			err:      &url.Error{Op: "Get", URL: "http://example.com", Err: context.DeadlineExceeded},
+			expected: true,
+		},
+		{
+			name: "unsupported_scheme",
+			err:  &url.Error{Op: "Get", URL: "ftp://example.com", Err: errors.New(`unsupported protocol scheme "ftp"`)},
+		},
+		{
+			name: "certificate",
+			err:  &url.Error{Op: "Get", URL: "https://example.com", Err: x509.UnknownAuthorityError{}},
+		},
+		{
+			name: "other",
+			err:  errors.New("other"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isTransportError(tc.err))
+		})
+	}
+}
+
+func TestClientRequestRetryAfterDeadline(t *testing.T) {
+	var attempts int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := NewClient(
+		WithBaseURL(s.URL),
+		WithRetryPolicy(DefaultRetryPolicy),
+	).Request(ctx, TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+	require.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2019, 6, 10, 15, 55, 41, 0, time.UTC)
+	for _, tc := range []struct {
+		value      string
+		expectedD  time.Duration
+		expectedOK bool
+	}{
+		{value: ""},
+		{value: "invalid"},
+		{value: "-1"},
+		{value: "0", expectedOK: true},
+		{value: "120", expectedD: 2 * time.Minute, expectedOK: true},
+		{value: "Mon, 10 Jun 2019 15:56:11 GMT", expectedD: 30 * time.Second, expectedOK: true},
+		{value: "Mon, 10 Jun 2019 15:55:11 GMT", expectedOK: true},
+	} {
+		d, ok := parseRetryAfter(tc.value, now)
+		assert.Equal(t, tc.expectedD, d, tc.value)
+		assert.Equal(t, tc.expectedOK, ok, tc.value)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+	}
+	assert.Equal(t, 1*time.Second, p.delay(1, nil))
+	assert.Equal(t, 2*time.Second, p.delay(2, nil))
+	assert.Equal(t, 4*time.Second, p.delay(3, nil))
+	assert.Equal(t, 5*time.Second, p.delay(4, nil))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.delay(1, nil)
+		assert.True(t, 500*time.Millisecond <= d && d <= 1500*time.Millisecond)
+	}
+}
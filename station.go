@@ -0,0 +1,194 @@
+package meteomatics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errFindStationsParse = errors.New("find station parse error")
+
+// A Station is an observation station.
+type Station struct {
+	ID             string
+	WMOID          string
+	AlternativeIDs []string
+	Name           string
+	Lat            float64
+	Lon            float64
+	Elevation      float64
+	StartDate      time.Time
+	EndDate        time.Time
+}
+
+// FindStationsOptions are filters for FindStations. Zero values are not sent.
+type FindStationsOptions struct {
+	// Location is a Point, around which stations are searched, or a
+	// RectangleN or RectangleRes, within whose bounds stations are searched.
+	Location LocationStringer
+	// Radius is the maximum distance from Location, in meters.
+	Radius float64
+	// Elevation is the elevation of the station, in meters.
+	Elevation  *float64
+	WMOIDs     []string
+	METARIDs   []string
+	Parameters ParameterStringer
+	StartDate  time.Time
+	EndDate    time.Time
+}
+
+// FindStations returns the stations that match options.
+func (c *Client) FindStations(ctx context.Context, options *FindStationsOptions) ([]Station, error) {
+	urlStr := c.baseURL + "/find_station"
+	if values := options.Values(); values != nil {
+		urlStr += "?" + values.Encode()
+	}
+	data, err := c.get(ctx, urlStr, FormatCSV.ContentType())
+	if err != nil {
+		return nil, err
+	}
+	return parseStations(data)
+}
+
+// Values returns the url.Values that set the filters defined by o.
+func (o *FindStationsOptions) Values() url.Values {
+	if o == nil {
+		return nil
+	}
+	v := url.Values{}
+	switch l := o.Location.(type) {
+	case nil:
+	case RectangleN:
+		v.Set("location", boundingBoxString(l.Min, l.Max))
+	case RectangleRes:
+		v.Set("location", boundingBoxString(l.Min, l.Max))
+	default:
+		v.Set("location", string(l.LocationString()))
+	}
+	if o.Radius != 0 {
+		v.Set("radius", strconv.FormatFloat(o.Radius, 'f', -1, 64)+"m")
+	}
+	if o.Elevation != nil {
+		v.Set("elevation", strconv.FormatFloat(*o.Elevation, 'f', -1, 64)+"m")
+	}
+	if len(o.WMOIDs) != 0 {
+		v.Set("wmo_ids", strings.Join(o.WMOIDs, ","))
+	}
+	if len(o.METARIDs) != 0 {
+		v.Set("metar_ids", strings.Join(o.METARIDs, ","))
+	}
+	if o.Parameters != nil {
+		v.Set("parameters", string(o.Parameters.ParameterString()))
+	}
+	if !o.StartDate.IsZero() {
+		v.Set("startdate", formatTime(o.StartDate))
+	}
+	if !o.EndDate.IsZero() {
+		v.Set("enddate", formatTime(o.EndDate))
+	}
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+// boundingBoxString returns the bounding box of min and max as a string.
+func boundingBoxString(min, max Point) string {
+	return string(formatFloat(max.Lat) + "," + formatFloat(min.Lon) +
+		"_" + formatFloat(min.Lat) + "," + formatFloat(max.Lon))
+}
+
+// parseStations parses the stations in a find_station response, which is a
+// semicolon-separated table with a header. Errors are wrapped with the line
+// number at which they occur.
+func parseStations(data []byte) ([]Station, error) {
+	s := newCSVScanner(bytes.NewReader(data))
+	if !s.Scan() {
+		return nil, errFindStationsParse
+	}
+	header := strings.Split(s.Text(), ";")
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range []string{"ID Hash", "Name", "Location Lat,Lon", "Elevation"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("line 1: %w", errFindStationsParse)
+		}
+	}
+
+	var stations []Station
+	for line := 2; s.Scan(); line++ {
+		if s.Text() == "" {
+			continue
+		}
+		record := strings.Split(s.Text(), ";")
+		if len(record) != len(header) {
+			return nil, fmt.Errorf("line %d: %w", line, errFindStationsParse)
+		}
+		station, err := parseStation(columns, record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		stations = append(stations, station)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return stations, nil
+}
+
+// parseStation parses the station in record, whose fields are indexed by
+// columns.
+func parseStation(columns map[string]int, record []string) (Station, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	station := Station{
+		ID:    field("ID Hash"),
+		WMOID: field("WMO ID"),
+		Name:  field("Name"),
+	}
+	if alternativeIDs := field("Alternative IDs"); alternativeIDs != "" {
+		station.AlternativeIDs = strings.Split(alternativeIDs, ",")
+	}
+	latLon := strings.Split(field("Location Lat,Lon"), ",")
+	if len(latLon) != 2 {
+		return Station{}, errFindStationsParse
+	}
+	var err error
+	if station.Lat, err = strconv.ParseFloat(latLon[0], 64); err != nil {
+		return Station{}, err
+	}
+	if station.Lon, err = strconv.ParseFloat(latLon[1], 64); err != nil {
+		return Station{}, err
+	}
+	if station.Elevation, err = strconv.ParseFloat(strings.TrimSuffix(field("Elevation"), "m"), 64); err != nil {
+		return Station{}, err
+	}
+	if station.StartDate, err = parseStationDate(field("Start Date")); err != nil {
+		return Station{}, err
+	}
+	if station.EndDate, err = parseStationDate(field("End Date")); err != nil {
+		return Station{}, err
+	}
+	return station, nil
+}
+
+// parseStationDate parses a station date, returning the zero time if s is
+// empty.
+func parseStationDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,132 @@
+package meteomatics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientFindStations(t *testing.T) {
+	s := newTestServer(
+		t,
+		"/find_station?elevation=450m&location=47.423336%2C9.377225&parameters=t_2m%3AC&radius=20000m&wmo_ids=066810%2C066790",
+		"testdata/find_station.csv",
+	)
+	defer s.Close()
+
+	elevation := 450.0
+	stations, err := NewClient(WithBaseURL(s.URL)).FindStations(
+		context.Background(),
+		&FindStationsOptions{
+			Location: Point{
+				Lat: 47.423336,
+				Lon: 9.377225,
+			},
+			Radius:    20000,
+			Elevation: &elevation,
+			WMOIDs:    []string{"066810", "066790"},
+			Parameters: Parameter{
+				Name:  ParameterTemperature,
+				Level: LevelMeters(2),
+				Units: UnitsCelsius,
+			},
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []Station{
+		{
+			ID:             "1229428282",
+			WMOID:          "066810",
+			AlternativeIDs: []string{"LSZR", "STG"},
+			Name:           "St. Gallen",
+			Lat:            47.4254,
+			Lon:            9.39849,
+			Elevation:      776,
+			StartDate:      time.Date(1981, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:        "3149564154",
+			WMOID:     "066790",
+			Name:      "Altenrhein",
+			Lat:       47.4851,
+			Lon:       9.56031,
+			Elevation: 398,
+			StartDate: time.Date(1995, 6, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2019, 12, 31, 23, 0, 0, 0, time.UTC),
+		},
+	}, stations)
+}
+
+func TestParseStations(t *testing.T) {
+	stations, err := parseStations([]byte("ID Hash;Name;Location Lat,Lon;Elevation;Distance;Distance\n" +
+		"1229428282;St. Gallen;47.4254,9.39849;776m;1589.2;-1\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []Station{
+		{
+			ID:        "1229428282",
+			Name:      "St. Gallen",
+			Lat:       47.4254,
+			Lon:       9.39849,
+			Elevation: 776,
+		},
+	}, stations)
+
+	for _, tc := range []struct {
+		name          string
+		data          string
+		expectedErrRx string
+	}{
+		{
+			name:          "missing_column",
+			data:          "ID Hash;Name;Elevation\n",
+			expectedErrRx: `\Aline 1: `,
+		},
+		{
+			name: "wrong_number_of_fields",
+			data: "ID Hash;Name;Location Lat,Lon;Elevation\n" +
+				"1229428282;St. Gallen;47.4254,9.39849;776m\n" +
+				"3149564154;Altenrhein;47.4851,9.56031\n",
+			expectedErrRx: `\Aline 3: `,
+		},
+		{
+			name: "invalid_elevation",
+			data: "ID Hash;Name;Location Lat,Lon;Elevation\n" +
+				"1229428282;St. Gallen;47.4254,9.39849;high\n",
+			expectedErrRx: `\Aline 2: `,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseStations([]byte(tc.data))
+			require.Error(t, err)
+			assert.Regexp(t, tc.expectedErrRx, err.Error())
+		})
+	}
+
+	_, err = parseStations([]byte("ID Hash;Name;Location Lat,Lon;Elevation\n1;A;1\n"))
+	assert.True(t, errors.Is(err, errFindStationsParse))
+}
+
+func TestFindStationsOptionsValues(t *testing.T) {
+	assert.Nil(t, (*FindStationsOptions)(nil).Values())
+	assert.Nil(t, (&FindStationsOptions{}).Values())
+	assert.Equal(t, "location=47.8%2C5.9_45.8%2C10.5&metar_ids=LSZH&startdate=2019-01-01T00%3A00%3A00Z", (&FindStationsOptions{
+		Location: RectangleN{
+			Min: Point{
+				Lat: 45.8,
+				Lon: 5.9,
+			},
+			Max: Point{
+				Lat: 47.8,
+				Lon: 10.5,
+			},
+			NLon: 10,
+			NLat: 10,
+		},
+		METARIDs:  []string{"LSZH"},
+		StartDate: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+	}).Values().Encode())
+}
@@ -0,0 +1,153 @@
+package meteomatics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultTokenURL is the default URL from which access tokens are requested.
+const DefaultTokenURL = "https://login.meteomatics.com/api/v1/token"
+
+const (
+	// defaultTokenLifetime is the lifetime of an access token whose response
+	// does not include an expiry.
+	defaultTokenLifetime = 2 * time.Hour
+
+	// tokenRefreshMargin is how long before its expiry an access token is
+	// refreshed. It is capped at half of the token's lifetime, so that
+	// short-lived tokens are still reused.
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+var errNoAccessToken = errors.New("no access token")
+
+// A tokenSource fetches, caches, and refreshes access tokens. It is safe for
+// concurrent use.
+type tokenSource struct {
+	client      *Client
+	username    string
+	password    string
+	now         func() time.Time
+	mu          sync.Mutex
+	accessToken string
+	refreshAt   time.Time
+}
+
+// A tokenResponse is a response from the token URL.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// WithTokenAuth sets the username and password used to obtain access tokens.
+// Access tokens are requested from the token URL when first needed, cached,
+// and refreshed shortly before they expire, so the username and password are
+// not sent with data requests. An access token that is rejected with 401
+// Unauthorized is discarded.
+func WithTokenAuth(username, password string) ClientOption {
+	return func(c *Client) {
+		ts := &tokenSource{
+			client:   c,
+			username: username,
+			password: password,
+			now:      time.Now,
+		}
+		c.preRequestFuncs = append(c.preRequestFuncs, ts.authorize)
+		c.unauthorizedFuncs = append(c.unauthorizedFuncs, ts.invalidate)
+	}
+}
+
+// WithTokenURL sets the URL from which access tokens are requested.
+func WithTokenURL(tokenURL string) ClientOption {
+	return func(c *Client) {
+		c.tokenURL = tokenURL
+	}
+}
+
+// authorize adds an access token to req.
+func (ts *tokenSource) authorize(req *http.Request) error {
+	accessToken, err := ts.token(req.Context())
+	if err != nil {
+		return err
+	}
+	query := req.URL.Query()
+	query.Set("access_token", accessToken)
+	req.URL.RawQuery = query.Encode()
+	return nil
+}
+
+// invalidate discards the cached access token if it is the one used by req.
+func (ts *tokenSource) invalidate(req *http.Request) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.accessToken != "" && req.URL.Query().Get("access_token") == ts.accessToken {
+		ts.accessToken = ""
+	}
+}
+
+// token returns a valid access token, fetching a new one if needed.
+func (ts *tokenSource) token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	now := ts.now()
+	if ts.accessToken != "" && now.Before(ts.refreshAt) {
+		return ts.accessToken, nil
+	}
+
+	tr, err := ts.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	lifetime := defaultTokenLifetime
+	if tr.ExpiresIn > 0 {
+		lifetime = time.Duration(tr.ExpiresIn) * time.Second
+	}
+	margin := tokenRefreshMargin
+	if margin > lifetime/2 {
+		margin = lifetime / 2
+	}
+	ts.accessToken = tr.AccessToken
+	ts.refreshAt = now.Add(lifetime - margin)
+	return ts.accessToken, nil
+}
+
+// fetch requests a new access token from the token URL.
+func (ts *tokenSource) fetch(ctx context.Context) (*tokenResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, ts.client.tokenURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(ts.username, ts.password)
+
+	resp, err := ts.client.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < http.StatusOK || http.StatusMultipleChoices <= resp.StatusCode {
+		return nil, newError(req, resp, respBody)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tr := &tokenResponse{}
+	if err := json.Unmarshal(respBody, tr); err != nil {
+		return nil, err
+	}
+	if tr.AccessToken == "" {
+		return nil, errNoAccessToken
+	}
+	return tr, nil
+}
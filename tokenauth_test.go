@@ -0,0 +1,175 @@
+package meteomatics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTokenTestServer(t *testing.T, expiresIn int) (*httptest.Server, *int32) {
+	var tokens int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/token":
+			username, password, ok := r.BasicAuth()
+			if !ok || username != "username" || password != "password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			n := atomic.AddInt32(&tokens, 1)
+			w.Header().Set("Content-Type", "application/json")
+			if expiresIn == 0 {
+				fmt.Fprintf(w, `{"access_token":"token%d","token_type":"bearer"}`, n)
+			} else {
+				fmt.Fprintf(w, `{"access_token":"token%d","token_type":"bearer","expires_in":%d}`, n, expiresIn)
+			}
+		default:
+			_, _, ok := r.BasicAuth()
+			assert.False(t, ok)
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, r.URL.Query().Get("access_token"))
+		}
+	}))
+	return s, &tokens
+}
+
+func TestClientTokenAuth(t *testing.T) {
+	s, tokens := newTokenTestServer(t, 0)
+	defer s.Close()
+
+	c := NewClient(
+		WithBaseURL(s.URL),
+		WithTokenURL(s.URL+"/api/v1/token"),
+		WithTokenAuth("username", "password"),
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := c.Request(context.Background(), TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, "token1", string(data))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(tokens))
+}
+
+func TestClientTokenAuthShortLived(t *testing.T) {
+	s, tokens := newTokenTestServer(t, 60)
+	defer s.Close()
+
+	c := NewClient(
+		WithBaseURL(s.URL),
+		WithTokenURL(s.URL+"/api/v1/token"),
+		WithTokenAuth("username", "password"),
+	)
+	for i := 0; i < 3; i++ {
+		data, err := c.Request(context.Background(), TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, &RequestOptions{Source: "mix"})
+		require.NoError(t, err)
+		assert.Equal(t, "token1", string(data))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(tokens))
+}
+
+func TestTokenSourceRefresh(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		expiresIn       int
+		expectedRefresh time.Duration
+	}{
+		{
+			name:            "short_lived",
+			expiresIn:       60,
+			expectedRefresh: 30 * time.Second,
+		},
+		{
+			name:            "long_lived",
+			expiresIn:       3600,
+			expectedRefresh: 55 * time.Minute,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, tokens := newTokenTestServer(t, tc.expiresIn)
+			defer s.Close()
+
+			now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			ts := &tokenSource{
+				client:   NewClient(WithTokenURL(s.URL + "/api/v1/token")),
+				username: "username",
+				password: "password",
+				now: func() time.Time {
+					return now
+				},
+			}
+			for _, d := range []time.Duration{0, tc.expectedRefresh / 2, tc.expectedRefresh - time.Second} {
+				now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Add(d)
+				token, err := ts.token(context.Background())
+				require.NoError(t, err)
+				assert.Equal(t, "token1", token)
+			}
+			now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Add(tc.expectedRefresh)
+			token, err := ts.token(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "token2", token)
+			assert.Equal(t, int32(2), atomic.LoadInt32(tokens))
+		})
+	}
+}
+
+func TestClientTokenAuthUnauthorized(t *testing.T) {
+	var tokens int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/token":
+			n := atomic.AddInt32(&tokens, 1)
+			fmt.Fprintf(w, `{"access_token":"token%d","token_type":"bearer","expires_in":7200}`, n)
+		default:
+			accessToken := r.URL.Query().Get("access_token")
+			if accessToken == "token1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, accessToken)
+		}
+	}))
+	defer s.Close()
+
+	c := NewClient(
+		WithBaseURL(s.URL),
+		WithTokenURL(s.URL+"/api/v1/token"),
+		WithTokenAuth("username", "password"),
+	)
+	_, err := c.Request(context.Background(), TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+	data, err := c.Request(context.Background(), TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "token2", string(data))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&tokens))
+}
+
+func TestClientTokenAuthError(t *testing.T) {
+	s, tokens := newTokenTestServer(t, 0)
+	defer s.Close()
+
+	_, err := NewClient(
+		WithBaseURL(s.URL),
+		WithTokenURL(s.URL+"/api/v1/token"),
+		WithTokenAuth("username", "wrong"),
+	).Request(context.Background(), TimeNow, ParameterString("t_2m:C"), Point{}, FormatCSV, nil)
+	require.Error(t, err)
+	e, ok := err.(*Error)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusUnauthorized, e.Response.StatusCode)
+	assert.Equal(t, int32(0), atomic.LoadInt32(tokens))
+}
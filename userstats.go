@@ -0,0 +1,85 @@
+package meteomatics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var errUserStatsParse = errors.New("user stats parse error")
+
+// A UserStatsUsage is the number of requests used in a period and its limits.
+// A zero limit means that there is no limit.
+type UserStatsUsage struct {
+	Used      int `json:"used"`
+	SoftLimit int `json:"soft limit"`
+	HardLimit int `json:"hard limit"`
+}
+
+// UserStats are a user's account usage statistics.
+type UserStats struct {
+	Username        string
+	LastRequestTime time.Time
+	Total           UserStatsUsage
+	Day             UserStatsUsage
+	Hour            UserStatsUsage
+	Minute          UserStatsUsage
+	Parallel        UserStatsUsage
+}
+
+// userStatsJSONResponse is a JSON response to a user_stats_json request.
+type userStatsJSONResponse struct {
+	Status         string `json:"status"`
+	UserStatistics *struct {
+		Username        string         `json:"username"`
+		LastRequestTime time.Time      `json:"last request time"`
+		Total           UserStatsUsage `json:"requests total"`
+		Day             UserStatsUsage `json:"requests since last UTC midnight"`
+		Hour            UserStatsUsage `json:"requests since HH:00:00"`
+		Minute          UserStatsUsage `json:"requests in the last 60 seconds"`
+		Parallel        UserStatsUsage `json:"requests in parallel"`
+	} `json:"user statistics"`
+}
+
+// Remaining returns the number of requests remaining before u's hard limit is
+// reached, or -1 if u has no hard limit.
+func (u UserStatsUsage) Remaining() int {
+	switch {
+	case u.HardLimit == 0:
+		return -1
+	case u.Used >= u.HardLimit:
+		return 0
+	default:
+		return u.HardLimit - u.Used
+	}
+}
+
+// UserStats returns the account usage statistics of the authenticated user.
+func (c *Client) UserStats(ctx context.Context) (*UserStats, error) {
+	data, err := c.get(ctx, c.baseURL+"/user_stats_json", FormatJSON.ContentType())
+	if err != nil {
+		return nil, err
+	}
+
+	var usjr userStatsJSONResponse
+	if err := json.Unmarshal(data, &usjr); err != nil {
+		return nil, err
+	}
+	if usjr.Status != "" && usjr.Status != "OK" {
+		return nil, parseAPIStatus(usjr.Status)
+	}
+	if usjr.UserStatistics == nil {
+		return nil, errUserStatsParse
+	}
+	us := usjr.UserStatistics
+	return &UserStats{
+		Username:        us.Username,
+		LastRequestTime: us.LastRequestTime,
+		Total:           us.Total,
+		Day:             us.Day,
+		Hour:            us.Hour,
+		Minute:          us.Minute,
+		Parallel:        us.Parallel,
+	}, nil
+}
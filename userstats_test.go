@@ -0,0 +1,84 @@
+package meteomatics
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientUserStats(t *testing.T) {
+	body, err := ioutil.ReadFile("testdata/user_stats.json")
+	require.NoError(t, err)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/user_stats_json", r.URL.Path)
+		if username, password, ok := r.BasicAuth(); !ok || username != "username" || password != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer s.Close()
+
+	us, err := NewClient(WithBaseURL(s.URL), WithBasicAuth("username", "password")).UserStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &UserStats{
+		Username:        "foo_lambda",
+		LastRequestTime: time.Date(2019, 6, 10, 15, 55, 41, 0, time.UTC),
+		Total:           UserStatsUsage{Used: 12345},
+		Day:             UserStatsUsage{Used: 420, SoftLimit: 400, HardLimit: 500},
+		Hour:            UserStatsUsage{Used: 17},
+		Minute:          UserStatsUsage{Used: 3, HardLimit: 60},
+		Parallel:        UserStatsUsage{Used: 1, HardLimit: 4},
+	}, us)
+	assert.Equal(t, -1, us.Total.Remaining())
+	assert.Equal(t, 80, us.Day.Remaining())
+	assert.Equal(t, 0, UserStatsUsage{Used: 5, HardLimit: 4}.Remaining())
+
+	_, err = NewClient(WithBaseURL(s.URL), WithBasicAuth("username", "wrong")).UserStats(context.Background())
+	var e *Error
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusUnauthorized, e.Response.StatusCode)
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+}
+
+func TestClientUserStatsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name                    string
+		body                    string
+		expectedAPIErrorMessage string
+	}{
+		{
+			name: "missing_user_statistics",
+			body: `{}`,
+		},
+		{
+			name:                    "status",
+			body:                    `{"status":"No valid user or password given"}`,
+			expectedAPIErrorMessage: "No valid user or password given",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer s.Close()
+
+			us, err := NewClient(WithBaseURL(s.URL)).UserStats(context.Background())
+			assert.Nil(t, us)
+			if tc.expectedAPIErrorMessage == "" {
+				assert.True(t, errors.Is(err, errUserStatsParse))
+				return
+			}
+			var e *APIError
+			require.True(t, errors.As(err, &e))
+			assert.Equal(t, tc.expectedAPIErrorMessage, e.Message)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package meteomatics
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+// An xmlResponse is an XML response.
+type xmlResponse struct {
+	XMLName       xml.Name       `xml:"meteomatics-api-response"`
+	Version       string         `xml:"version,attr"`
+	User          string         `xml:"user"`
+	DateGenerated time.Time      `xml:"dateGenerated"`
+	Status        string         `xml:"status"`
+	Parameters    []xmlParameter `xml:"data>parameter"`
+}
+
+// An xmlParameter is a parameter measured at locations.
+type xmlParameter struct {
+	Name      ParameterString `xml:"name,attr"`
+	Locations []xmlLocation   `xml:"location"`
+}
+
+// An xmlLocation is a series of values at a location.
+type xmlLocation struct {
+	Lat       float64    `xml:"lat,attr"`
+	Lon       float64    `xml:"lon,attr"`
+	StationID string     `xml:"station_id,attr"`
+	Values    []xmlValue `xml:"value"`
+}
+
+// An xmlValue is a value at a date. An empty value is missing.
+type xmlValue struct {
+	Date  time.Time `xml:"date,attr"`
+	Value string    `xml:",chardata"`
+}
+
+// RequestXML requests a forecast in XML format. The response is returned in
+// the same structure as for RequestJSON.
+func (c *Client) RequestXML(ctx context.Context, ts TimeStringer, ps ParameterStringer, ls LocationStringer, options *RequestOptions) (*JSONResponse, error) {
+	data, err := c.Request(ctx, ts, ps, ls, FormatXML, options)
+	if err != nil {
+		return nil, err
+	}
+	xr := &xmlResponse{}
+	if err := xml.Unmarshal(data, xr); err != nil {
+		return nil, err
+	}
+	jr, err := xr.jsonResponse()
+	if err != nil {
+		return nil, err
+	}
+	if jr.Status != "OK" {
+		return nil, jr
+	}
+	c.missingValues.replaceJSONResponse(jr)
+	return jr, nil
+}
+
+// jsonResponse returns r as a *JSONResponse.
+func (r *xmlResponse) jsonResponse() (*JSONResponse, error) {
+	jr := &JSONResponse{
+		Version:       r.Version,
+		User:          r.User,
+		DateGenerated: r.DateGenerated,
+		Status:        r.Status,
+		Data:          make([]JSONData, 0, len(r.Parameters)),
+	}
+	for _, parameter := range r.Parameters {
+		data := JSONData{
+			Parameter:   parameter.Name,
+			Coordinates: make([]JSONCoordinates, 0, len(parameter.Locations)),
+		}
+		for _, location := range parameter.Locations {
+			coordinates := JSONCoordinates{
+				Lat:       location.Lat,
+				Lon:       location.Lon,
+				StationID: location.StationID,
+				Dates:     make([]JSONDate, 0, len(location.Values)),
+			}
+			for _, value := range location.Values {
+				x, err := parseValue(strings.TrimSpace(value.Value))
+				if err != nil {
+					return nil, err
+				}
+				coordinates.Dates = append(coordinates.Dates, JSONDate{
+					Date:  value.Date,
+					Value: x,
+				})
+			}
+			data.Coordinates = append(data.Coordinates, coordinates)
+		}
+		jr.Data = append(jr.Data, data)
+	}
+	return jr, nil
+}
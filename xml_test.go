@@ -0,0 +1,92 @@
+package meteomatics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRequestXML(t *testing.T) {
+	s := newTestServer(
+		t,
+		"/2016-12-20T00:00:00ZP2D:P1D/t_2m:C,relative_humidity_2m:p/50,10+40,20/xml",
+		"testdata/temperature_and_relative_humidity_between_two_times_at_two_locations.xml",
+	)
+	r, err := NewClient(WithBaseURL(s.URL)).RequestXML(
+		context.Background(),
+		TimePeriod{
+			Start:    time.Date(2016, 12, 20, 0, 0, 0, 0, time.UTC),
+			Duration: 2 * 24 * time.Hour,
+			Step:     24 * time.Hour,
+		},
+		ParameterSlice{
+			Parameter{
+				Name:  ParameterTemperature,
+				Level: LevelMeters(2),
+				Units: UnitsCelsius,
+			},
+			Parameter{
+				Name:  ParameterRelativeHumidity,
+				Level: LevelMeters(2),
+				Units: UnitsPercentage,
+			},
+		},
+		LocationSlice{
+			Point{
+				Lat: 50,
+				Lon: 10,
+			},
+			Point{
+				Lat: 40,
+				Lon: 20,
+			},
+		},
+		&RequestOptions{},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "3.0", r.Version)
+	assert.Equal(t, "internal-api-beta-user", r.User)
+	assert.Equal(t, time.Date(2016, 12, 23, 15, 24, 7, 0, time.UTC), r.DateGenerated)
+	assert.Equal(t, "OK", r.Status)
+	require.Len(t, r.Data, 2)
+	assert.Equal(t, ParameterString("t_2m:C"), r.Data[0].Parameter)
+	require.Len(t, r.Data[0].Coordinates, 2)
+	assert.Equal(t, 50.0, r.Data[0].Coordinates[0].Lat)
+	assert.Equal(t, 10.0, r.Data[0].Coordinates[0].Lon)
+	require.Len(t, r.Data[0].Coordinates[0].Dates, 3)
+	assert.Equal(t, time.Date(2016, 12, 20, 0, 0, 0, 0, time.UTC), r.Data[0].Coordinates[0].Dates[0].Date)
+	assert.Equal(t, -1.18699, r.Data[0].Coordinates[0].Dates[0].Value)
+	assert.Equal(t, ParameterString("relative_humidity_2m:p"), r.Data[1].Parameter)
+	require.Len(t, r.Data[1].Coordinates, 2)
+	assert.Equal(t, 40.0, r.Data[1].Coordinates[1].Lat)
+	assert.Equal(t, 20.0, r.Data[1].Coordinates[1].Lon)
+	require.Len(t, r.Data[1].Coordinates[1].Dates, 3)
+	assert.Equal(t, time.Date(2016, 12, 22, 0, 0, 0, 0, time.UTC), r.Data[1].Coordinates[1].Dates[2].Date)
+	assert.Equal(t, 64.9726, r.Data[1].Coordinates[1].Dates[2].Value)
+}
+
+func TestClientRequestXMLError(t *testing.T) {
+	s := newTestServer(t, "/now/t_2m:C/0,190/xml", "testdata/out_of_range_error.xml")
+	_, err := NewClient(WithBaseURL(s.URL)).RequestXML(
+		context.Background(),
+		TimeNow,
+		Parameter{
+			Name:  ParameterTemperature,
+			Level: LevelMeters(2),
+			Units: UnitsCelsius,
+		},
+		Point{
+			Lat: 0,
+			Lon: 190,
+		},
+		&RequestOptions{},
+	)
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "Not enough data outside temporal and/or spatial domain"))
+	assert.True(t, errors.Is(err, ErrOutOfDomain))
+}